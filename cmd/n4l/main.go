@@ -0,0 +1,162 @@
+// Commande n4l : formatage et vérification de fichiers N4L sans serveur.
+//
+//	n4l fmt [-w] [-l] [fichiers...]
+//	n4l lint [-fail warning] [-relations registre.json] [fichiers...]
+//	n4l lsp
+//
+// Sans fichier, l'entrée standard est lue. "n4l lsp" démarre un serveur
+// Language Server Protocol sur l'entrée et la sortie standard.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"n4l-editor/lsp"
+	"n4l-editor/services"
+)
+
+const usage = `usage :
+  n4l fmt [-w] [-l] [fichiers...]       normalise la mise en page
+  n4l lint [-fail niveau] [fichiers...] signale les problèmes
+  n4l lsp                               serveur LSP sur stdio
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var code int
+	switch os.Args[1] {
+	case "fmt":
+		code = runFmt(os.Args[2:])
+	case "lint":
+		code = runLint(os.Args[2:])
+	case "lsp":
+		if err := lsp.NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "commande inconnue : %s\n%s", os.Args[1], usage)
+		code = 2
+	}
+	os.Exit(code)
+}
+
+// runFmt reformate les fichiers ; -w réécrit les fichiers, -l liste ceux
+// dont la mise en page diffère et fait échouer la commande s'il y en a
+func runFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "réécrire les fichiers au lieu d'afficher le résultat")
+	list := flags.Bool("l", false, "lister les fichiers mal formatés")
+	flags.Parse(args)
+
+	formatter := services.NewN4LFormatter()
+	code := 0
+
+	err := eachInput(flags.Args(), func(name string, content []byte) error {
+		formatted, err := formatter.Format(string(content))
+		if err != nil {
+			return err
+		}
+		changed := !bytes.Equal(content, []byte(formatted))
+
+		switch {
+		case *list:
+			if changed {
+				fmt.Println(name)
+				code = 1
+			}
+		case *write && name != "":
+			if changed {
+				return os.WriteFile(name, []byte(formatted), 0644)
+			}
+		default:
+			fmt.Print(formatted)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return code
+}
+
+// runLint affiche les diagnostics au format "fichier:ligne:colonne" et échoue
+// si l'un d'eux atteint le niveau demandé
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	fail := flags.String("fail", services.SeverityWarning, "niveau à partir duquel la commande échoue (error, warning, info)")
+	relations := flags.String("relations", "", "registre de relations JSON remplaçant celui par défaut")
+	flags.Parse(args)
+
+	threshold, ok := severityRank[*fail]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "niveau inconnu : %s\n", *fail)
+		return 2
+	}
+	if *relations != "" {
+		registry, err := services.LoadRelationRegistry(*relations)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		services.SetDefaultRelationRegistry(registry)
+	}
+
+	linter := services.NewN4LLinter()
+	code := 0
+
+	err := eachInput(flags.Args(), func(name string, content []byte) error {
+		if name == "" {
+			name = "<stdin>"
+		}
+		for _, d := range linter.Lint(string(content)) {
+			fmt.Printf("%s:%d:%d: %s %s %s\n", name, d.Pos.Line, d.Pos.Column, d.Severity, d.Code, d.Message)
+			if severityRank[d.Severity] >= threshold {
+				code = 1
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return code
+}
+
+var severityRank = map[string]int{
+	services.SeverityInfo:    0,
+	services.SeverityWarning: 1,
+	services.SeverityError:   2,
+}
+
+// eachInput applique fn à chaque fichier, ou à l'entrée standard (nom vide)
+func eachInput(files []string, fn func(name string, content []byte) error) error {
+	if len(files) == 0 {
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		return fn("", content)
+	}
+
+	for _, name := range files {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if err := fn(name, content); err != nil {
+			return fmt.Errorf("%s : %w", name, err)
+		}
+	}
+	return nil
+}
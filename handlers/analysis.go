@@ -42,13 +42,13 @@ func (h *AnalysisHandler) AnalyzeGraph(w http.ResponseWriter, r *http.Request) {
 
 // DetectTemporalPatterns détecte les patterns temporels
 func (h *AnalysisHandler) DetectTemporalPatterns(w http.ResponseWriter, r *http.Request) {
-	var n4lNotes map[string][]string
-	if err := json.NewDecoder(r.Body).Decode(&n4lNotes); err != nil {
+	req, err := decodeNotesRequest(r)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	patterns := h.analyzer.DetectTemporalPatterns(n4lNotes)
+	patterns := h.analyzer.DetectTemporalPatterns(req.Notes, req.Spans)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(patterns)
@@ -81,3 +81,24 @@ func (h *AnalysisHandler) GenerateQuestions(w http.ResponseWriter, r *http.Reque
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(questions)
 }
+
+// GetCentrality mesure la centralité des nœuds ; ?direction=undirected ignore
+// le sens des relations, ?context= restreint le graphe à un contexte
+func (h *AnalysisHandler) GetCentrality(w http.ResponseWriter, r *http.Request) {
+	var graphData models.GraphData
+	if err := json.NewDecoder(r.Body).Decode(&graphData); err != nil {
+		http.Error(w, "Données invalides", http.StatusBadRequest)
+		return
+	}
+
+	direction := r.URL.Query().Get("direction")
+	if direction != "" && direction != services.PathDirected && direction != services.PathUndirected {
+		http.Error(w, "Sens de parcours inconnu : "+direction, http.StatusBadRequest)
+		return
+	}
+	graphData = services.FilterGraphByContext(graphData, r.URL.Query().Get("context"))
+	report := h.analyzer.Centrality(graphData, direction != services.PathUndirected)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
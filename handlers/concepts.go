@@ -89,6 +89,40 @@ func (h *ConceptsHandler) ParseN4L(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(parsedData)
 }
 
+// ProjectRequest décrit un projet N4L multi-fichiers : le fichier principal
+// et le contenu de chaque fichier, indexé par son chemin relatif
+type ProjectRequest struct {
+	Root  string            `json:"root"`
+	Files map[string]string `json:"files"`
+}
+
+// ParseProject parse un projet N4L dont les fichiers s'incluent par "@include"
+func (h *ConceptsHandler) ParseProject(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Données invalides", http.StatusBadRequest)
+		return
+	}
+	if req.Root == "" {
+		http.Error(w, "Fichier principal manquant", http.StatusBadRequest)
+		return
+	}
+
+	parsedData, err := h.parser.ParseProjectFiles(req.Files, req.Root)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Fichier principal introuvable : %s", req.Root), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(parsedData)
+}
+
 // GenerateN4LFromText utilise l'IA pour convertir un texte brut en format N4L.
 func (h *ConceptsHandler) GenerateN4LFromText(w http.ResponseWriter, r *http.Request) {
 	file, _, err := r.FormFile("textFile")
@@ -170,7 +204,7 @@ Exemples :
 - Équivalence simple : 'A <-> B'
 - Équivalence avec (=) : 'A (=) B'
 - Synonyme/alias : 'A (same as) B' ou 'A (alias) B'
-- Négation : 'A (!eq) B' pour bloquer une équivalence
+- Négation : 'A (!eq) B' pour bloquer une équivalence, 'A (!relation) B' pour nier une relation
 
 ## Groupements
 'Parent => { Enfant1; Enfant2; Enfant3 }'
@@ -179,6 +213,13 @@ Exemples :
 - 'alpha waves (freq) 5-15 Hz'
 - 'Indices => { Tasse; Livre; Boue }'
 
+## Événements à plusieurs participants
+Un fait qui implique plus de deux éléments s'écrit en un seul groupe dont chaque membre porte son rôle :
+'Événement => { rôle: participant; rôle: participant }'
+Exemple :
+- 'Remise de la lettre => { agent: Jean; objet: lettre; destinataire: Élodie; lieu: manoir; date: 27/08/2025 }'
+Rôles usuels : agent, objet, destinataire, victime, lieu, date. Ne découpe pas un tel fait en relations séparées.
+
 # 3. CONTINUITÉ ET RÉFÉRENCES
 
 ## Guillemets pour continuation
@@ -192,18 +233,18 @@ Victor Moreau (âge) 67 ans
 
 ## Variables et références
 - Définir une variable : '@nom variable_content'
-- Référencer : '$nom.1' (première occurrence)
-- Référence au précédent : '$PREV.1' (élément précédent)
+- Référencer : '$nom.1' (premier élément de la ligne définie par @nom)
+- Référence au précédent : '$PREV.1' (premier élément de la ligne précédente)
 - Exemples :
   '@goal "améliorer l'enquête"'
   'N4L (used for) $goal.1'
 
 ## Annotations spéciales
 - Concepts importants : '>"concept"'
-- Symboles personnalisés : '%terme' pour marquer l'importance
+- Symboles personnalisés : '%%terme' pour marquer l'importance
 - Exemples :
   '>"inverse path tracing problem"'
-  '%reasoning goes back to 350 BC'
+  '%%reasoning goes back to 350 BC'
 
 # 4. MÉTADONNÉES ET ANNOTATIONS
 
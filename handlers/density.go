@@ -6,6 +6,7 @@ import (
 	"math"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 
 	"n4l-editor/models"
@@ -32,7 +33,13 @@ func (h *DensityHandler) GetDensityMap(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	densityMap := h.calculateDensityMap(graphData)
+	graphData = services.FilterGraphByContext(graphData, r.URL.Query().Get("context"))
+	graph, communities, err := h.communities(r, graphData)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	densityMap := h.calculateDensityMap(graph, communities)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(densityMap)
@@ -46,7 +53,13 @@ func (h *DensityHandler) GetConceptualTerritories(w http.ResponseWriter, r *http
 		return
 	}
 
-	territories := h.identifyTerritories(graphData)
+	graphData = services.FilterGraphByContext(graphData, r.URL.Query().Get("context"))
+	graph, communities, err := h.communities(r, graphData)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	territories := h.identifyTerritories(graph, communities)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(territories)
@@ -60,7 +73,13 @@ func (h *DensityHandler) GetExplorationSuggestions(w http.ResponseWriter, r *htt
 		return
 	}
 
-	suggestions := h.generateExplorationSuggestions(graphData)
+	graphData = services.FilterGraphByContext(graphData, r.URL.Query().Get("context"))
+	graph, communities, err := h.communities(r, graphData)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	suggestions := h.generateExplorationSuggestions(graph, communities)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(suggestions)
@@ -74,7 +93,13 @@ func (h *DensityHandler) GetDensityMetrics(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	metrics := h.calculateDensityMetrics(graphData)
+	graphData = services.FilterGraphByContext(graphData, r.URL.Query().Get("context"))
+	graph, communities, err := h.communities(r, graphData)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	metrics := h.calculateDensityMetrics(graph, communities)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(metrics)
@@ -82,23 +107,38 @@ func (h *DensityHandler) GetDensityMetrics(w http.ResponseWriter, r *http.Reques
 
 // Méthodes privées
 
-func (h *DensityHandler) calculateDensityMap(graph models.GraphData) models.DensityMap {
+// communities découpe le graphe filtré en communautés, à la résolution
+// donnée par ?resolution= (services.DefaultResolution par défaut)
+func (h *DensityHandler) communities(r *http.Request, graphData models.GraphData) (*services.Graph, models.CommunityReport, error) {
+	resolution := services.DefaultResolution
+	if value := r.URL.Query().Get("resolution"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed <= 0 {
+			return nil, models.CommunityReport{}, fmt.Errorf("résolution invalide : %s", value)
+		}
+		resolution = parsed
+	}
+	graph := services.NewGraph(graphData)
+	return graph, h.analyzer.Communities(graphData, resolution), nil
+}
+
+func (h *DensityHandler) calculateDensityMap(graph *services.Graph, communities models.CommunityReport) models.DensityMap {
 	densityMap := models.DensityMap{
 		Zones:         []models.DensityZone{},
 		HeatmapData:   []models.HeatmapPoint{},
 		GlobalDensity: 0.0,
 	}
 
-	if len(graph.Nodes) == 0 {
+	if len(graph.Nodes()) == 0 {
 		return densityMap
 	}
 
 	// Utiliser les positions réelles fournies, sinon assigner des positions par défaut
-	nodePositions := graph.Positions
+	nodePositions := graph.Data().Positions
 	if nodePositions == nil || len(nodePositions) == 0 {
 		nodePositions = make(map[string]models.Position)
-		gridSize := int(math.Ceil(math.Sqrt(float64(len(graph.Nodes)))))
-		for i, node := range graph.Nodes {
+		gridSize := int(math.Ceil(math.Sqrt(float64(len(graph.Nodes())))))
+		for i, node := range graph.Nodes() {
 			x := float64((i % gridSize) * 100)
 			y := float64((i / gridSize) * 100)
 			nodePositions[node.ID] = models.Position{X: x, Y: y}
@@ -107,24 +147,24 @@ func (h *DensityHandler) calculateDensityMap(graph models.GraphData) models.Dens
 
 	// Calculer le degré de chaque nœud
 	nodeDegrees := make(map[string]int)
-	for _, edge := range graph.Edges {
+	for _, edge := range graph.Edges() {
 		nodeDegrees[edge.From]++
 		nodeDegrees[edge.To]++
 	}
 
-	// Créer les zones de densité basées sur les clusters
-	clusters := h.identifyClusters(graph)
-	avgDensity := h.calculateAverageDensity(graph)
+	// Créer les zones de densité basées sur les communautés
+	avgDensity := h.calculateAverageDensity(communities, graph)
 
-	for _, cluster := range clusters {
-		if len(cluster) > 0 {
-			zone := h.createDensityZone(cluster, graph, nodeDegrees, nodePositions, avgDensity)
+	for _, community := range communities.Communities {
+		if len(community.Nodes) > 0 {
+			zone := h.createDensityZone(community.Nodes, graph, nodeDegrees, nodePositions, avgDensity)
 			densityMap.Zones = append(densityMap.Zones, zone)
 		}
 	}
+	densityMap.Modularity = communities.Modularity
 
 	// Créer les points de heatmap
-	for _, node := range graph.Nodes {
+	for _, node := range graph.Nodes() {
 		pos, exists := nodePositions[node.ID]
 		if !exists {
 			continue // Skip node if position is unknown
@@ -152,21 +192,23 @@ func (h *DensityHandler) calculateDensityMap(graph models.GraphData) models.Dens
 	return densityMap
 }
 
-func (h *DensityHandler) identifyTerritories(graph models.GraphData) models.ConceptualTerritories {
+// identifyTerritories classe les communautés du graphe selon leur densité
+func (h *DensityHandler) identifyTerritories(graph *services.Graph, communities models.CommunityReport) models.ConceptualTerritories {
 	territories := models.ConceptualTerritories{
 		Explored:   []models.Territory{},
 		Unexplored: []models.Territory{},
 		Frontier:   []models.Territory{},
+		Modularity: communities.Modularity,
 	}
 
-	clusters := h.identifyClusters(graph)
-	avgDensity := h.calculateAverageDensity(graph)
+	avgDensity := h.calculateAverageDensity(communities, graph)
 
 	// Définir des seuils dynamiques
 	exploredThreshold := math.Max(avgDensity*1.5, 0.2)
 	unexploredThreshold := math.Min(avgDensity*0.7, 0.1)
 
-	for i, cluster := range clusters {
+	for i, community := range communities.Communities {
+		cluster := community.Nodes
 		density := h.calculateClusterDensity(cluster, graph)
 		connections := h.countExternalConnections(cluster, graph)
 
@@ -176,6 +218,7 @@ func (h *DensityHandler) identifyTerritories(graph models.GraphData) models.Conc
 			Density:     density,
 			Size:        len(cluster),
 			CentralNode: h.findCentralNode(cluster, graph),
+			Modularity:  community.Modularity,
 		}
 
 		// Classifier le territoire avec des seuils dynamiques
@@ -218,15 +261,15 @@ func (h *DensityHandler) identifyTerritories(graph models.GraphData) models.Conc
 	return territories
 }
 
-func (h *DensityHandler) generateExplorationSuggestions(graph models.GraphData) models.ExplorationSuggestions {
+func (h *DensityHandler) generateExplorationSuggestions(graph *services.Graph, communities models.CommunityReport) models.ExplorationSuggestions {
 	suggestions := models.ExplorationSuggestions{
 		PriorityConnections: []models.ConnectionSuggestion{},
 		BridgeOpportunities: []models.BridgeSuggestion{},
 		DensityBalancing:    []models.BalancingSuggestion{},
 	}
 
-	territories := h.identifyTerritories(graph)
-	avgDensity := h.calculateAverageDensity(graph)
+	territories := h.identifyTerritories(graph, communities)
+	avgDensity := h.calculateAverageDensity(communities, graph)
 
 	// 1. Suggérer des connexions pour les zones peu denses
 	for _, territory := range territories.Unexplored {
@@ -296,10 +339,10 @@ func (h *DensityHandler) generateExplorationSuggestions(graph models.GraphData)
 	return suggestions
 }
 
-func (h *DensityHandler) calculateDensityMetrics(graph models.GraphData) models.DensityMetrics {
+func (h *DensityHandler) calculateDensityMetrics(graph *services.Graph, communities models.CommunityReport) models.DensityMetrics {
 	metrics := models.DensityMetrics{}
 
-	if len(graph.Nodes) == 0 {
+	if len(graph.Nodes()) == 0 {
 		return metrics
 	}
 
@@ -308,8 +351,8 @@ func (h *DensityHandler) calculateDensityMetrics(graph models.GraphData) models.
 	metrics.ClusteringCoefficient = h.calculateGlobalClusteringCoefficient(graph)
 
 	degreeDist := make(map[int]int)
-	for _, node := range graph.Nodes {
-		degree := h.getNodeDegree(node.ID, graph)
+	for _, node := range graph.Nodes() {
+		degree := graph.Degree(node.ID)
 		degreeDist[degree]++
 	}
 	metrics.DegreeDistribution = degreeDist
@@ -317,12 +360,13 @@ func (h *DensityHandler) calculateDensityMetrics(graph models.GraphData) models.
 	metrics.Hubs = h.identifyHubs(graph)
 	metrics.Peripherals = h.identifyPeripherals(graph)
 
-	territories := h.identifyTerritories(graph)
+	territories := h.identifyTerritories(graph, communities)
 	metrics.HighDensityZones = len(territories.Explored)
 	metrics.LowDensityZones = len(territories.Unexplored)
 	metrics.FrontierZones = len(territories.Frontier)
 
 	metrics.BalanceScore = h.calculateBalanceScore(graph, territories)
+	metrics.AverageConfidence, metrics.WeakEdges = h.calculateEvidenceQuality(graph)
 	metrics.Recommendations = h.generateMetricRecommendations(metrics, graph)
 
 	return metrics
@@ -330,37 +374,7 @@ func (h *DensityHandler) calculateDensityMetrics(graph models.GraphData) models.
 
 // Méthodes auxiliaires
 
-func (h *DensityHandler) identifyClusters(graph models.GraphData) [][]string {
-	visited := make(map[string]bool)
-	var clusters [][]string
-
-	for _, node := range graph.Nodes {
-		if !visited[node.ID] {
-			cluster := []string{}
-			h.dfs(node.ID, graph, visited, &cluster)
-			if len(cluster) > 0 {
-				clusters = append(clusters, cluster)
-			}
-		}
-	}
-	return clusters
-}
-
-func (h *DensityHandler) dfs(nodeID string, graph models.GraphData, visited map[string]bool, cluster *[]string) {
-	visited[nodeID] = true
-	*cluster = append(*cluster, nodeID)
-
-	for _, edge := range graph.Edges {
-		if edge.From == nodeID && !visited[edge.To] {
-			h.dfs(edge.To, graph, visited, cluster)
-		}
-		if edge.To == nodeID && !visited[edge.From] {
-			h.dfs(edge.From, graph, visited, cluster)
-		}
-	}
-}
-
-func (h *DensityHandler) createDensityZone(cluster []string, graph models.GraphData, degrees map[string]int, positions map[string]models.Position, avgDensity float64) models.DensityZone {
+func (h *DensityHandler) createDensityZone(cluster []string, graph *services.Graph, degrees map[string]int, positions map[string]models.Position, avgDensity float64) models.DensityZone {
 	zone := models.DensityZone{
 		Nodes: cluster,
 	}
@@ -409,10 +423,9 @@ func (h *DensityHandler) createDensityZone(cluster []string, graph models.GraphD
 	return zone
 }
 
-func (h *DensityHandler) calculateNodeIntensity(nodeID string, graph models.GraphData, degrees map[string]int, positions map[string]models.Position) float64 {
+func (h *DensityHandler) calculateNodeIntensity(nodeID string, graph *services.Graph, degrees map[string]int, positions map[string]models.Position) float64 {
 	intensity := float64(degrees[nodeID]) * 0.5
-	neighbors := h.getNeighbors(nodeID, graph)
-	for _, neighbor := range neighbors {
+	for _, neighbor := range graph.Neighbors(nodeID) {
 		intensity += float64(degrees[neighbor]) * 0.2
 	}
 
@@ -427,10 +440,15 @@ func (h *DensityHandler) calculateNodeIntensity(nodeID string, graph models.Grap
 		intensity = intensity / float64(maxDegree*2)
 	}
 
+	// Une zone dense d'hypothèses est moins « explorée » qu'une zone de faits
+	if support, count := graph.Support(nodeID); count > 0 {
+		intensity *= support
+	}
+
 	return math.Min(intensity, 1.0)
 }
 
-func (h *DensityHandler) findEmptyZones(graph models.GraphData, positions map[string]models.Position) []models.EmptyZone {
+func (h *DensityHandler) findEmptyZones(graph *services.Graph, positions map[string]models.Position) []models.EmptyZone {
 	// Cette fonction reste une simplification et pourrait être améliorée
 	var emptyZones []models.EmptyZone
 	gridSize := 200.0
@@ -450,7 +468,7 @@ func (h *DensityHandler) findEmptyZones(graph models.GraphData, positions map[st
 	for _, pos := range positions {
 		gridX := int(math.Floor(pos.X / gridSize))
 		gridY := int(math.Floor(pos.Y / gridSize))
-		key := string(gridX) + "," + string(gridY)
+		key := fmt.Sprintf("%d,%d", gridX, gridY)
 		occupied[key] = true
 	}
 
@@ -459,7 +477,7 @@ func (h *DensityHandler) findEmptyZones(graph models.GraphData, positions map[st
 
 	for x := startX; x <= endX; x++ {
 		for y := startY; y <= endY; y++ {
-			key := string(x) + "," + string(y)
+			key := fmt.Sprintf("%d,%d", x, y)
 			if !occupied[key] {
 				emptyZones = append(emptyZones, models.EmptyZone{
 					X:      float64(x)*gridSize + gridSize/2,
@@ -472,7 +490,7 @@ func (h *DensityHandler) findEmptyZones(graph models.GraphData, positions map[st
 	return emptyZones
 }
 
-func (h *DensityHandler) calculateClusterDensity(cluster []string, graph models.GraphData) float64 {
+func (h *DensityHandler) calculateClusterDensity(cluster []string, graph *services.Graph) float64 {
 	if len(cluster) <= 1 {
 		return 0.0
 	}
@@ -484,61 +502,31 @@ func (h *DensityHandler) calculateClusterDensity(cluster []string, graph models.
 	return float64(internalEdges) / float64(maxPossible)
 }
 
-func (h *DensityHandler) getNeighbors(nodeID string, graph models.GraphData) []string {
-	neighborMap := make(map[string]bool)
-	for _, edge := range graph.Edges {
-		if edge.From == nodeID {
-			neighborMap[edge.To] = true
-		}
-		if edge.To == nodeID {
-			neighborMap[edge.From] = true
-		}
-	}
-	neighbors := []string{}
-	for neighbor := range neighborMap {
-		neighbors = append(neighbors, neighbor)
-	}
-	return neighbors
-}
-
-func (h *DensityHandler) countExternalConnections(cluster []string, graph models.GraphData) int {
+func (h *DensityHandler) countExternalConnections(cluster []string, graph *services.Graph) int {
 	clusterSet := make(map[string]bool)
 	for _, node := range cluster {
 		clusterSet[node] = true
 	}
 
 	count := 0
-	for _, edge := range graph.Edges {
-		fromInCluster := clusterSet[edge.From]
-		toInCluster := clusterSet[edge.To]
-		if fromInCluster != toInCluster {
-			count++
+	for _, node := range cluster {
+		for _, edge := range graph.IncidentEdges(node) {
+			if clusterSet[edge.From] != clusterSet[edge.To] {
+				count++
+			}
 		}
 	}
 	return count
 }
 
-func (h *DensityHandler) findCentralNode(cluster []string, graph models.GraphData) string {
+func (h *DensityHandler) findCentralNode(cluster []string, graph *services.Graph) string {
 	if len(cluster) == 0 {
 		return ""
 	}
 	maxDegree := -1
 	centralNode := cluster[0]
 	for _, node := range cluster {
-		degree := 0
-		// On ne compte que les liens internes au cluster pour la centralité
-		for _, edge := range graph.Edges {
-			isInternal := false
-			for _, n := range cluster {
-				if edge.From == n || edge.To == n {
-					isInternal = true
-					break
-				}
-			}
-			if isInternal && (edge.From == node || edge.To == node) {
-				degree++
-			}
-		}
+		degree := graph.Degree(node)
 		if degree > maxDegree {
 			maxDegree = degree
 			centralNode = node
@@ -547,25 +535,10 @@ func (h *DensityHandler) findCentralNode(cluster []string, graph models.GraphDat
 	return centralNode
 }
 
-func (h *DensityHandler) getNodeDegree(nodeID string, graph models.GraphData) int {
-	degree := 0
-	for _, edge := range graph.Edges {
-		if edge.From == nodeID || edge.To == nodeID {
-			degree++
-		}
-	}
-	return degree
-}
-
-func (h *DensityHandler) findOrphans(graph models.GraphData) []string {
-	connected := make(map[string]bool)
-	for _, edge := range graph.Edges {
-		connected[edge.From] = true
-		connected[edge.To] = true
-	}
+func (h *DensityHandler) findOrphans(graph *services.Graph) []string {
 	orphans := []string{}
-	for _, node := range graph.Nodes {
-		if !connected[node.ID] {
+	for _, node := range graph.Nodes() {
+		if graph.Degree(node.ID) == 0 {
 			orphans = append(orphans, node.ID)
 		}
 	}
@@ -583,8 +556,25 @@ func (h *DensityHandler) sortSuggestions(suggestions *models.ExplorationSuggesti
 
 // Méthodes additionnelles pour les métriques
 
-func (h *DensityHandler) calculateGlobalDensity(graph models.GraphData) float64 {
-	n := len(graph.Nodes)
+// calculateEvidenceQuality retourne la certitude moyenne des arêtes et le
+// nombre d'arêtes faibles (certitude < 0.5)
+func (h *DensityHandler) calculateEvidenceQuality(graph *services.Graph) (float64, int) {
+	if len(graph.Edges()) == 0 {
+		return 0, 0
+	}
+	total, weak := 0.0, 0
+	for _, edge := range graph.Edges() {
+		confidence := services.EdgeConfidence(edge)
+		total += confidence
+		if confidence < 0.5 {
+			weak++
+		}
+	}
+	return total / float64(len(graph.Edges())), weak
+}
+
+func (h *DensityHandler) calculateGlobalDensity(graph *services.Graph) float64 {
+	n := len(graph.Nodes())
 	if n <= 1 {
 		return 0.0
 	}
@@ -592,33 +582,33 @@ func (h *DensityHandler) calculateGlobalDensity(graph models.GraphData) float64
 	if maxEdges == 0 {
 		return 0.0
 	}
-	return float64(len(graph.Edges)) / float64(maxEdges)
+	return float64(len(graph.Edges())) / float64(maxEdges)
 }
 
-func (h *DensityHandler) calculateGlobalAverageDegree(graph models.GraphData) float64 {
-	if len(graph.Nodes) == 0 {
+func (h *DensityHandler) calculateGlobalAverageDegree(graph *services.Graph) float64 {
+	if len(graph.Nodes()) == 0 {
 		return 0.0
 	}
-	return float64(len(graph.Edges)*2) / float64(len(graph.Nodes))
+	return float64(len(graph.Edges())*2) / float64(len(graph.Nodes()))
 }
 
-func (h *DensityHandler) calculateGlobalClusteringCoefficient(graph models.GraphData) float64 {
+func (h *DensityHandler) calculateGlobalClusteringCoefficient(graph *services.Graph) float64 {
 	// **IMPLÉMENTATION CORRIGÉE**
 	totalCoeff := 0.0
 	nodesWithDegreeTwoOrMore := 0
 
 	// Créer une structure de données pour un accès rapide aux voisins
 	adj := make(map[string]map[string]bool)
-	for _, node := range graph.Nodes {
+	for _, node := range graph.Nodes() {
 		adj[node.ID] = make(map[string]bool)
 	}
-	for _, edge := range graph.Edges {
+	for _, edge := range graph.Edges() {
 		adj[edge.From][edge.To] = true
 		adj[edge.To][edge.From] = true
 	}
 
-	for _, node := range graph.Nodes {
-		neighbors := h.getNeighbors(node.ID, graph)
+	for _, node := range graph.Nodes() {
+		neighbors := graph.Neighbors(node.ID)
 		degree := len(neighbors)
 
 		if degree < 2 {
@@ -649,28 +639,31 @@ func (h *DensityHandler) calculateGlobalClusteringCoefficient(graph models.Graph
 	return totalCoeff / float64(nodesWithDegreeTwoOrMore)
 }
 
-func (h *DensityHandler) identifyHubs(graph models.GraphData) []string {
+// identifyHubs retient les nœuds très connectés et les intermédiaires obligés,
+// du plus central au moins central
+func (h *DensityHandler) identifyHubs(graph *services.Graph) []string {
 	avgDegree := h.calculateGlobalAverageDegree(graph)
 	hubs := []string{}
-	for _, node := range graph.Nodes {
-		if float64(h.getNodeDegree(node.ID, graph)) > avgDegree*1.5+1 { // Seuil un peu plus strict
-			hubs = append(hubs, node.ID)
+	for _, c := range h.analyzer.Centrality(graph.Data(), true).Nodes {
+		if float64(c.Degree) > avgDegree*1.5+1 || // Seuil un peu plus strict
+			c.Betweenness >= services.KeyPlayerBetweenness {
+			hubs = append(hubs, c.Node)
 		}
 	}
 	return hubs
 }
 
-func (h *DensityHandler) identifyPeripherals(graph models.GraphData) []string {
+func (h *DensityHandler) identifyPeripherals(graph *services.Graph) []string {
 	peripherals := []string{}
-	for _, node := range graph.Nodes {
-		if h.getNodeDegree(node.ID, graph) <= 1 {
+	for _, node := range graph.Nodes() {
+		if graph.Degree(node.ID) <= 1 {
 			peripherals = append(peripherals, node.ID)
 		}
 	}
 	return peripherals
 }
 
-func (h *DensityHandler) calculateBalanceScore(graph models.GraphData, territories models.ConceptualTerritories) float64 {
+func (h *DensityHandler) calculateBalanceScore(graph *services.Graph, territories models.ConceptualTerritories) float64 {
 	sizes := []float64{}
 
 	// On ne prend en compte que les vrais clusters (taille > 1)
@@ -715,9 +708,9 @@ func (h *DensityHandler) calculateBalanceScore(graph models.GraphData, territori
 	return score
 }
 
-func (h *DensityHandler) generateMetricRecommendations(metrics models.DensityMetrics, graph models.GraphData) []string {
+func (h *DensityHandler) generateMetricRecommendations(metrics models.DensityMetrics, graph *services.Graph) []string {
 	recommendations := []string{}
-	nodeCount := len(graph.Nodes)
+	nodeCount := len(graph.Nodes())
 
 	if nodeCount == 0 {
 		return recommendations
@@ -737,6 +730,10 @@ func (h *DensityHandler) generateMetricRecommendations(metrics models.DensityMet
 		recommendations = append(recommendations, "Le graphe est déséquilibré, avec des zones de tailles très différentes. Essayez d'équilibrer les territoires.")
 	}
 
+	if metrics.WeakEdges > 0 && metrics.WeakEdges*3 >= len(graph.Edges()) {
+		recommendations = append(recommendations, fmt.Sprintf("%d relations reposent sur des hypothèses ou des rumeurs (certitude < 0.5). Cherchez des preuves pour les confirmer ou les écarter.", metrics.WeakEdges))
+	}
+
 	if metrics.LowDensityZones > metrics.HighDensityZones+metrics.FrontierZones {
 		recommendations = append(recommendations, "Beaucoup de territoires sont inexplorés. Concentrez-vous sur le développement de ces zones.")
 	}
@@ -746,32 +743,34 @@ func (h *DensityHandler) generateMetricRecommendations(metrics models.DensityMet
 
 // Méthodes helper additionnelles
 
-func (h *DensityHandler) countInternalEdges(cluster []string, graph models.GraphData) int {
+func (h *DensityHandler) countInternalEdges(cluster []string, graph *services.Graph) int {
 	clusterSet := make(map[string]bool)
 	for _, node := range cluster {
 		clusterSet[node] = true
 	}
 	count := 0
-	for _, edge := range graph.Edges {
-		if clusterSet[edge.From] && clusterSet[edge.To] {
-			count++
+	for _, node := range cluster {
+		for _, edge := range graph.OutEdges(node) {
+			if clusterSet[edge.To] {
+				count++
+			}
 		}
 	}
 	return count
 }
 
-func (h *DensityHandler) calculateAverageDegree(cluster []string, graph models.GraphData) float64 {
+func (h *DensityHandler) calculateAverageDegree(cluster []string, graph *services.Graph) float64 {
 	if len(cluster) == 0 {
 		return 0.0
 	}
 	totalDegree := 0
 	for _, node := range cluster {
-		totalDegree += h.getNodeDegree(node, graph)
+		totalDegree += graph.Degree(node)
 	}
 	return float64(totalDegree) / float64(len(cluster))
 }
 
-func (h *DensityHandler) calculateCentrality(cluster []string, graph models.GraphData) float64 {
+func (h *DensityHandler) calculateCentrality(cluster []string, graph *services.Graph) float64 {
 	external := h.countExternalConnections(cluster, graph)
 	internal := h.countInternalEdges(cluster, graph)
 	if internal+external == 0 {
@@ -780,12 +779,12 @@ func (h *DensityHandler) calculateCentrality(cluster []string, graph models.Grap
 	return float64(external) / float64(internal+external)
 }
 
-func (h *DensityHandler) findNearbyHighDensityNodes(nodeID string, graph models.GraphData) []string {
+func (h *DensityHandler) findNearbyHighDensityNodes(nodeID string, graph *services.Graph) []string {
 	candidates := []string{}
 	avgDegree := h.calculateGlobalAverageDegree(graph)
 
-	for _, node := range graph.Nodes {
-		if node.ID != nodeID && float64(h.getNodeDegree(node.ID, graph)) > avgDegree*1.2 {
+	for _, node := range graph.Nodes() {
+		if node.ID != nodeID && float64(graph.Degree(node.ID)) > avgDegree*1.2 {
 			candidates = append(candidates, node.ID)
 			if len(candidates) >= 3 {
 				break
@@ -795,18 +794,19 @@ func (h *DensityHandler) findNearbyHighDensityNodes(nodeID string, graph models.
 	return candidates
 }
 
-func (h *DensityHandler) clustersConnected(cluster1, cluster2 []string, graph models.GraphData) bool {
-	set1 := make(map[string]bool)
-	for _, n := range cluster1 {
-		set1[n] = true
+func (h *DensityHandler) clustersConnected(cluster1, cluster2 []string, graph *services.Graph) bool {
+	if len(cluster2) < len(cluster1) {
+		cluster1, cluster2 = cluster2, cluster1
 	}
 	set2 := make(map[string]bool)
 	for _, n := range cluster2 {
 		set2[n] = true
 	}
-	for _, edge := range graph.Edges {
-		if (set1[edge.From] && set2[edge.To]) || (set2[edge.From] && set1[edge.To]) {
-			return true
+	for _, n := range cluster1 {
+		for _, neighbor := range graph.Neighbors(n) {
+			if set2[neighbor] {
+				return true
+			}
 		}
 	}
 	return false
@@ -819,18 +819,17 @@ func (h *DensityHandler) calculateBridgeImpact(t1, t2 models.Territory) float64
 	return math.Min(impact, 1.0)
 }
 
-func (h *DensityHandler) calculateAverageDensity(graph models.GraphData) float64 {
-	clusters := h.identifyClusters(graph)
-	if len(clusters) == 0 {
+func (h *DensityHandler) calculateAverageDensity(communities models.CommunityReport, graph *services.Graph) float64 {
+	if len(communities.Communities) == 0 {
 		return 0.0
 	}
 	totalDensity := 0.0
-	for _, cluster := range clusters {
-		if len(cluster) > 1 {
-			totalDensity += h.calculateClusterDensity(cluster, graph)
+	for _, community := range communities.Communities {
+		if len(community.Nodes) > 1 {
+			totalDensity += h.calculateClusterDensity(community.Nodes, graph)
 		}
 	}
-	return totalDensity / float64(len(clusters))
+	return totalDensity / float64(len(communities.Communities))
 }
 
 // SuggestClustersWithAI utilise l'IA pour suggérer des termes de clustering
@@ -847,7 +846,7 @@ func (h *DensityHandler) SuggestClustersWithAI(w http.ResponseWriter, r *http.Re
 	}
 
 	// Préparer le contexte pour l'IA
-	graphContext := h.prepareGraphContextForAI(req)
+	graphContext := h.prepareGraphContextForAI(services.NewGraph(req))
 
 	// Créer le service Ollama
 	ollamaService := services.NewOllamaService("http://localhost:11434/api/generate")
@@ -886,13 +885,13 @@ Limite-toi à 5-7 termes maximum.`, graphContext)
 }
 
 // prepareGraphContextForAI prépare un résumé du graphe pour l'IA
-func (h *DensityHandler) prepareGraphContextForAI(graph models.GraphData) string {
+func (h *DensityHandler) prepareGraphContextForAI(graph *services.Graph) string {
 	var context strings.Builder
 
 	// Lister les nœuds principaux
 	context.WriteString("Nœuds principaux:\n")
 	maxNodes := 30
-	for i, node := range graph.Nodes {
+	for i, node := range graph.Nodes() {
 		if i >= maxNodes {
 			break
 		}
@@ -904,18 +903,15 @@ func (h *DensityHandler) prepareGraphContextForAI(graph models.GraphData) string
 	if len(hubs) > 0 {
 		context.WriteString("\nNœuds centraux (hubs):\n")
 		for _, hubID := range hubs {
-			for _, node := range graph.Nodes {
-				if node.ID == hubID {
-					context.WriteString(fmt.Sprintf("- %s\n", node.Label))
-					break
-				}
+			if node, ok := graph.Node(hubID); ok {
+				context.WriteString(fmt.Sprintf("- %s\n", node.Label))
 			}
 		}
 	}
 
 	// Types de relations
 	edgeTypes := make(map[string]int)
-	for _, edge := range graph.Edges {
+	for _, edge := range graph.Edges() {
 		edgeTypes[edge.Type]++
 	}
 	context.WriteString("\nTypes de relations:\n")
@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 
@@ -10,31 +11,71 @@ import (
 	"n4l-editor/services"
 )
 
-// Définition des structures pour la requête et la réponse
+// ExpansionConeRequest demande le cône d'expansion d'un nœud
 type ExpansionConeRequest struct {
-	NodeID    string           `json:"nodeId"`
-	Depth     int              `json:"depth"`
+	services.ConeQuery
 	GraphData models.GraphData `json:"graphData"`
 }
 
+// ExpansionConeResponse garde la liste nodeIds des clients plus anciens
 type ExpansionConeResponse struct {
-	NodeIDs []string      `json:"nodeIds"`
-	Edges   []models.Edge `json:"edges"`
+	models.ExpansionCone
+	NodeIDs []string `json:"nodeIds"`
 }
 
-type FindClustersRequest struct {
-	Terms     []string         `json:"terms"`
+// FindPathsRequest demande les chemins entre deux nœuds d'un graphe
+type FindPathsRequest struct {
+	services.PathQuery
 	GraphData models.GraphData `json:"graphData"`
 }
 
+// FindClustersRequest cherche les nœuds correspondant à des termes ; sans
+// terme, le graphe est découpé en communautés à la résolution demandée
+type FindClustersRequest struct {
+	Terms      []string         `json:"terms"`
+	Resolution float64          `json:"resolution,omitempty"`
+	GraphData  models.GraphData `json:"graphData"`
+}
+
 type FindClustersResponse struct {
-	Clusters map[string][]string `json:"clusters"`
-	Paths    [][]string          `json:"paths"`
+	Clusters    map[string][]string `json:"clusters"`
+	Paths       [][]string          `json:"paths"`
+	Communities []models.Community  `json:"communities,omitempty"`
+	Modularity  float64             `json:"modularity,omitempty"`
+}
+
+// NotesRequest transmet les notes accompagnées de leurs lignes source
+// (ParsedN4L.Spans). Les clients plus anciens envoient la map des notes seule.
+type NotesRequest struct {
+	Notes map[string][]string `json:"notes"`
+	Spans models.NoteSpans    `json:"spans,omitempty"`
+}
+
+// decodeNotesRequest accepte {"notes": {...}, "spans": {...}} ou la map des
+// notes seule ; "notes" est un objet dans le premier cas, un tableau si c'est
+// le nom d'un contexte
+func decodeNotesRequest(r *http.Request) (NotesRequest, error) {
+	var req NotesRequest
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return req, err
+	}
+
+	var probe struct {
+		Notes json.RawMessage `json:"notes"`
+	}
+	if json.Unmarshal(body, &probe) == nil && strings.HasPrefix(strings.TrimSpace(string(probe.Notes)), "{") {
+		err = json.Unmarshal(body, &req)
+		return req, err
+	}
+	err = json.Unmarshal(body, &req.Notes)
+	return req, err
 }
 
 // GraphHandler gère les requêtes liées au graphe
 type GraphHandler struct {
 	parser        *services.N4LParser
+	serializer    *services.N4LSerializer
 	analyzer      *services.GraphAnalyzer
 	ollamaService *services.OllamaService
 }
@@ -48,6 +89,7 @@ type AnalyzeClustersRequest struct {
 func NewGraphHandler(ollamaService *services.OllamaService) *GraphHandler {
 	return &GraphHandler{
 		parser:        services.NewN4LParser(),
+		serializer:    services.NewN4LSerializer(),
 		analyzer:      services.NewGraphAnalyzer(),
 		ollamaService: ollamaService,
 	}
@@ -55,18 +97,35 @@ func NewGraphHandler(ollamaService *services.OllamaService) *GraphHandler {
 
 // GetGraphData convertit les notes N4L en données de graphe
 func (h *GraphHandler) GetGraphData(w http.ResponseWriter, r *http.Request) {
-	var n4lNotes map[string][]string
-	if err := json.NewDecoder(r.Body).Decode(&n4lNotes); err != nil {
+	req, err := decodeNotesRequest(r)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	graphData := h.parser.ParseN4LToGraph(n4lNotes)
+	// ?merge=aliases fusionne les équivalences, ?fold=accents ignore les accents
+	policy := services.DefaultIdentityPolicy
+	policy.MergeAliases = r.URL.Query().Get("merge") == "aliases"
+	policy.FoldAccents = r.URL.Query().Get("fold") == "accents"
+
+	graphData := h.parser.WithIdentityPolicy(policy).ParseN4LToGraphWithSpans(req.Notes, req.Spans)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(graphData)
 }
 
+// GraphToN4L reconstruit le texte N4L d'un graphe
+func (h *GraphHandler) GraphToN4L(w http.ResponseWriter, r *http.Request) {
+	var graphData models.GraphData
+	if err := json.NewDecoder(r.Body).Decode(&graphData); err != nil {
+		http.Error(w, "Données invalides", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(h.serializer.Serialize(graphData)))
+}
+
 // GetExpansionCone gère la requête pour le cône d'expansion
 func (h *GraphHandler) GetExpansionCone(w http.ResponseWriter, r *http.Request) {
 	var req ExpansionConeRequest
@@ -75,16 +134,15 @@ func (h *GraphHandler) GetExpansionCone(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	nodeIDs, coneEdges := h.analyzer.GetExpansionCone(req.NodeID, req.Depth, req.GraphData)
-
-	nodeIDSlice := make([]string, 0, len(nodeIDs))
-	for id := range nodeIDs {
-		nodeIDSlice = append(nodeIDSlice, id)
+	cone, err := h.analyzer.ExpansionCone(req.ConeQuery, req.GraphData)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	resp := ExpansionConeResponse{
-		NodeIDs: nodeIDSlice,
-		Edges:   coneEdges,
+	resp := ExpansionConeResponse{ExpansionCone: cone, NodeIDs: make([]string, len(cone.Nodes))}
+	for i, node := range cone.Nodes {
+		resp.NodeIDs[i] = node.ID
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -107,8 +165,24 @@ func (h *GraphHandler) FindClusters(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if req.Resolution < 0 {
+		http.Error(w, "Résolution négative", http.StatusBadRequest)
+		return
+	}
+
 	if len(cleanTerms) == 0 {
-		http.Error(w, "Aucun terme de recherche fourni", http.StatusBadRequest)
+		report := h.analyzer.Communities(req.GraphData, req.Resolution)
+		clusters := make(map[string][]string, len(report.Communities))
+		for _, community := range report.Communities {
+			clusters[fmt.Sprintf("community-%d", community.ID)] = community.Nodes
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(FindClustersResponse{
+			Clusters:    clusters,
+			Paths:       [][]string{},
+			Communities: report.Communities,
+			Modularity:  report.Modularity,
+		})
 		return
 	}
 
@@ -122,6 +196,8 @@ func (h *GraphHandler) FindClusters(w http.ResponseWriter, r *http.Request) {
 }
 
 // FindAllPaths trouve tous les chemins dans le graphe
+//
+// Deprecated: conservé pour les clients existants, voir FindPaths.
 func (h *GraphHandler) FindAllPaths(w http.ResponseWriter, r *http.Request) {
 	var notes map[string][]string
 	if err := json.NewDecoder(r.Body).Decode(&notes); err != nil {
@@ -135,6 +211,24 @@ func (h *GraphHandler) FindAllPaths(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(paths)
 }
 
+// FindPaths retourne les chemins les plus sûrs entre deux nœuds
+func (h *GraphHandler) FindPaths(w http.ResponseWriter, r *http.Request) {
+	var req FindPathsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Données invalides: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	paths, err := h.analyzer.FindPaths(req.PathQuery, req.GraphData)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(paths)
+}
+
 // GetLayeredGraph retourne le graphe organisé en couches
 func (h *GraphHandler) GetLayeredGraph(w http.ResponseWriter, r *http.Request) {
 	var graphData models.GraphData
@@ -143,6 +237,7 @@ func (h *GraphHandler) GetLayeredGraph(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	graphData = services.FilterGraphByContext(graphData, r.URL.Query().Get("context"))
 	layeredGraph := h.analyzer.GetLayeredGraph(graphData)
 
 	w.Header().Set("Content-Type", "application/json")
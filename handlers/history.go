@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -288,28 +289,54 @@ func (h *HistoryHandler) detectSemanticChanges(current, previous models.GraphDat
 		}
 	}
 
-	// Détecter les nouvelles relations
-	prevEdgeMap := make(map[string]bool)
-	for _, e := range previous.Edges {
-		key := fmt.Sprintf("%s->%s", e.From, e.To)
-		prevEdgeMap[key] = true
+	// Détecter les relations ajoutées, supprimées ou renommées ; une relation
+	// renommée garde ses extrémités, son type et son contexte
+	added := h.findAddedEdges(previous, current)
+	removed := h.findRemovedEdges(previous, current)
+	candidates := make(map[string][]models.Edge)
+	for _, e := range removed {
+		candidates[relabelKey(e)] = append(candidates[relabelKey(e)], e)
 	}
+	relabeled := make(map[string]bool)
+	graph := services.NewGraph(current)
 
-	for _, e := range current.Edges {
-		key := fmt.Sprintf("%s->%s", e.From, e.To)
-		if !prevEdgeMap[key] {
-			impact := "medium"
-			if h.isCriticalConnection(e, current) {
-				impact = "high"
-			}
-
+	for _, e := range added {
+		key := relabelKey(e)
+		if old := candidates[key]; len(old) > 0 {
+			candidates[key] = old[1:]
+			relabeled[old[0].ID] = true
 			changes = append(changes, models.SemanticChange{
-				Type:        "edge_added",
-				ElementID:   key,
-				Description: fmt.Sprintf("Nouvelle relation: %s -> %s -> %s", e.From, e.Label, e.To),
-				Impact:      impact,
+				Type:        "edge_relabeled",
+				ElementID:   e.ID,
+				Description: fmt.Sprintf("Relation renommée: %s -> %s -> %s devient %s", e.From, old[0].Label, e.To, e.Label),
+				Impact:      "medium",
 			})
+			continue
+		}
+
+		impact := "medium"
+		if h.isCriticalConnection(e, graph) {
+			impact = "high"
+		}
+
+		changes = append(changes, models.SemanticChange{
+			Type:        "edge_added",
+			ElementID:   e.ID,
+			Description: fmt.Sprintf("Nouvelle relation: %s -> %s -> %s", e.From, e.Label, e.To),
+			Impact:      impact,
+		})
+	}
+
+	for _, e := range removed {
+		if relabeled[e.ID] {
+			continue
 		}
+		changes = append(changes, models.SemanticChange{
+			Type:        "edge_removed",
+			ElementID:   e.ID,
+			Description: fmt.Sprintf("Relation supprimée: %s -> %s -> %s", e.From, e.Label, e.To),
+			Impact:      "medium",
+		})
 	}
 
 	// Détecter les changements structurels majeurs
@@ -350,9 +377,21 @@ func (h *HistoryHandler) calculateConfidence(graph models.GraphData) float64 {
 	}
 	orphanPenalty := float64(orphans) / nodeCount
 
+	// Certitude moyenne des relations : des hypothèses non vérifiées
+	// rendent la version moins fiable
+	evidence := 1.0
+	if len(graph.Edges) > 0 {
+		total := 0.0
+		for _, e := range graph.Edges {
+			total += services.EdgeConfidence(e)
+		}
+		evidence = total / edgeCount
+	}
+
 	// Calculer la confiance (0-1)
 	confidence := (connectivityRatio / 3.0) // Normaliser sur une échelle
 	confidence *= (1.0 - orphanPenalty*0.5)
+	confidence *= evidence
 
 	if confidence > 1.0 {
 		confidence = 1.0
@@ -461,7 +500,8 @@ func (h *HistoryHandler) calculateMetrics(graph models.GraphData) models.GraphMe
 	}
 
 	// Calculer les composantes connexes
-	components := h.countConnectedComponents(graph)
+	indexed := services.NewGraph(graph)
+	components := h.countConnectedComponents(indexed)
 
 	// Calculer le degré moyen
 	avgDegree := 0.0
@@ -475,45 +515,34 @@ func (h *HistoryHandler) calculateMetrics(graph models.GraphData) models.GraphMe
 		Density:         density,
 		Components:      components,
 		AverageDegree:   avgDegree,
-		OrphanNodes:     h.countOrphans(graph),
+		OrphanNodes:     h.countOrphans(indexed),
 		MaxPathLength:   h.findMaxPathLength(graph),
 		ClusteringCoeff: h.calculateClusteringCoefficient(graph),
 	}
 }
 
-func (h *HistoryHandler) isCriticalConnection(edge models.Edge, graph models.GraphData) bool {
+func (h *HistoryHandler) isCriticalConnection(edge models.Edge, graph *services.Graph) bool {
 	// Une connexion est critique si elle connecte deux clusters précédemment séparés
 	// ou si elle crée un cycle important
 
 	// Simplification : vérifier si l'edge connecte des nœuds de contextes différents
-	var fromContext, toContext string
-	for _, n := range graph.Nodes {
-		if n.ID == edge.From {
-			fromContext = n.Context
-		}
-		if n.ID == edge.To {
-			toContext = n.Context
-		}
-	}
+	from, _ := graph.Node(edge.From)
+	to, _ := graph.Node(edge.To)
 
-	return fromContext != toContext && fromContext != "" && toContext != ""
+	return from.Context != to.Context && from.Context != "" && to.Context != ""
 }
 
 func (h *HistoryHandler) detectStructuralChange(current, previous models.GraphData) bool {
 	// Détecter si la structure globale a changé significativement
-
-	prevComponents := h.countConnectedComponents(previous)
-	currComponents := h.countConnectedComponents(current)
+	prevMetrics := h.calculateMetrics(previous)
+	currMetrics := h.calculateMetrics(current)
 
 	// Changement majeur si le nombre de composantes change significativement
-	if prevComponents-currComponents >= 2 || currComponents-prevComponents >= 2 {
+	if prevMetrics.Components-currMetrics.Components >= 2 || currMetrics.Components-prevMetrics.Components >= 2 {
 		return true
 	}
 
 	// Ou si la densité change significativement
-	prevMetrics := h.calculateMetrics(previous)
-	currMetrics := h.calculateMetrics(current)
-
 	densityChange := currMetrics.Density - prevMetrics.Density
 	if densityChange > 0.3 || densityChange < -0.3 {
 		return true
@@ -528,8 +557,8 @@ func (h *HistoryHandler) isBridgeConnection(edgeID string, graph models.GraphDat
 	return len(graph.Edges) > 10 && len(edgeID) > 0
 }
 
-func (h *HistoryHandler) countConnectedComponents(graph models.GraphData) int {
-	if len(graph.Nodes) == 0 {
+func (h *HistoryHandler) countConnectedComponents(graph *services.Graph) int {
+	if len(graph.Nodes()) == 0 {
 		return 0
 	}
 
@@ -539,17 +568,14 @@ func (h *HistoryHandler) countConnectedComponents(graph models.GraphData) int {
 	var dfs func(nodeID string)
 	dfs = func(nodeID string) {
 		visited[nodeID] = true
-		for _, edge := range graph.Edges {
-			if edge.From == nodeID && !visited[edge.To] {
-				dfs(edge.To)
-			}
-			if edge.To == nodeID && !visited[edge.From] {
-				dfs(edge.From)
+		for _, neighbor := range graph.Neighbors(nodeID) {
+			if !visited[neighbor] {
+				dfs(neighbor)
 			}
 		}
 	}
 
-	for _, node := range graph.Nodes {
+	for _, node := range graph.Nodes() {
 		if !visited[node.ID] {
 			components++
 			dfs(node.ID)
@@ -559,16 +585,10 @@ func (h *HistoryHandler) countConnectedComponents(graph models.GraphData) int {
 	return components
 }
 
-func (h *HistoryHandler) countOrphans(graph models.GraphData) int {
-	connected := make(map[string]bool)
-	for _, edge := range graph.Edges {
-		connected[edge.From] = true
-		connected[edge.To] = true
-	}
-
+func (h *HistoryHandler) countOrphans(graph *services.Graph) int {
 	orphans := 0
-	for _, node := range graph.Nodes {
-		if !connected[node.ID] {
+	for _, node := range graph.Nodes() {
+		if graph.Degree(node.ID) == 0 {
 			orphans++
 		}
 	}
@@ -623,17 +643,18 @@ func (h *HistoryHandler) findRemovedNodes(v1, v2 models.GraphData) []models.Node
 	return h.findAddedNodes(v2, v1)
 }
 
+// findAddedEdges compare les arêtes par identifiant. Les identifiants sont
+// recalculés pour que les versions enregistrées avant leur introduction, ou
+// modifiées côté client, restent comparables.
 func (h *HistoryHandler) findAddedEdges(v1, v2 models.GraphData) []models.Edge {
 	v1Map := make(map[string]bool)
-	for _, e := range v1.Edges {
-		key := fmt.Sprintf("%s->%s", e.From, e.To)
-		v1Map[key] = true
+	for _, e := range services.AssignEdgeIDs(v1.Edges) {
+		v1Map[e.ID] = true
 	}
 
 	added := []models.Edge{}
-	for _, e := range v2.Edges {
-		key := fmt.Sprintf("%s->%s", e.From, e.To)
-		if !v1Map[key] {
+	for _, e := range services.AssignEdgeIDs(v2.Edges) {
+		if !v1Map[e.ID] {
 			added = append(added, e)
 		}
 	}
@@ -641,6 +662,11 @@ func (h *HistoryHandler) findAddedEdges(v1, v2 models.GraphData) []models.Edge {
 	return added
 }
 
+// relabelKey identifie une relation indépendamment de son libellé
+func relabelKey(e models.Edge) string {
+	return strings.Join([]string{e.From, e.To, e.Type, e.Context}, "\x00")
+}
+
 func (h *HistoryHandler) findRemovedEdges(v1, v2 models.GraphData) []models.Edge {
 	return h.findAddedEdges(v2, v1)
 }
@@ -0,0 +1,251 @@
+package lsp
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"n4l-editor/models"
+	"n4l-editor/services"
+)
+
+// span repère un fragment du source (ligne et colonne à partir de 1, en caractères)
+type span struct {
+	line   int
+	column int
+	length int
+}
+
+func (s span) contains(line, column int) bool {
+	return s.line == line && column >= s.column && column <= s.column+s.length
+}
+
+// symbol est un élément nommé trouvé sous le curseur
+type symbol struct {
+	kind string // "subject", "variable"
+	name string
+	span span
+}
+
+// document est un fichier ouvert, analysé à chaque modification
+type document struct {
+	uri         string
+	lines       []string
+	parsed      models.ParsedN4L
+	graph       *services.Graph
+	diagnostics []models.Diagnostic
+
+	subjects   map[string][]span // occurrences de chaque sujet par nœud, dans l'ordre du document
+	nodes      map[string]string // nœud désigné par chaque clé d'identité
+	policy     services.IdentityPolicy
+	variables  map[string]span // définition "@nom" de chaque variable
+	references []symbol        // références "$nom.N"
+	labels     []string        // libellés de relation existants
+}
+
+func newDocument(uri, text string, parser *services.N4LParser, linter *services.N4LLinter) *document {
+	d := &document{
+		uri:       uri,
+		lines:     strings.Split(text, "\n"),
+		subjects:  make(map[string][]span),
+		variables: make(map[string]span),
+		nodes:     make(map[string]string),
+		policy:    parser.IdentityPolicy(),
+	}
+	d.parsed = parser.ParseN4L(text)
+	d.graph = services.NewGraph(parser.ParseN4LToGraph(d.parsed.Notes))
+	for _, node := range d.graph.Nodes() {
+		d.nodes[d.policy.Key(node.ID)] = node.ID
+		for _, alias := range node.Aliases {
+			d.nodes[d.policy.Key(alias)] = node.ID
+		}
+	}
+	d.diagnostics = linter.Lint(text)
+	d.index()
+	return d
+}
+
+// index recense sujets, variables et références à partir de l'AST
+func (d *document) index() {
+	seen := make(map[span]bool)
+	addSubject := func(item models.N4LItem) {
+		s := span{line: item.Pos.Line, column: item.Pos.Column, length: utf8.RuneCountInString(item.Text)}
+		// Ignorer les continuations '"' et les positions approximatives
+		if seen[s] || d.textAt(s) != item.Text {
+			return
+		}
+		seen[s] = true
+		name := d.nodeID(item.Text)
+		d.subjects[name] = append(d.subjects[name], s)
+	}
+
+	for _, context := range d.parsed.Document.Contexts {
+		for _, stmt := range context.Statements {
+			for _, rel := range stmt.Relations {
+				addSubject(rel.Source)
+				addSubject(rel.Target)
+			}
+			if stmt.Group != nil {
+				addSubject(stmt.Group.Parent)
+				for _, member := range stmt.Group.Members {
+					addSubject(member)
+				}
+			}
+			for _, ref := range stmt.References {
+				length := utf8.RuneCountInString("$" + ref.Name + "." + strconv.Itoa(ref.Index))
+				d.references = append(d.references, symbol{
+					kind: "variable",
+					name: ref.Name,
+					span: span{line: ref.Pos.Line, column: ref.Pos.Column, length: length},
+				})
+			}
+		}
+	}
+
+	for _, variable := range d.parsed.Document.Variables {
+		d.variables[variable.Name] = span{
+			line:   variable.Pos.Line,
+			column: variable.Pos.Column,
+			length: utf8.RuneCountInString(variable.Name) + 1,
+		}
+	}
+
+	labels := make(map[string]bool)
+	for _, edge := range d.graph.Edges() {
+		if edge.Type == "relation" && edge.Label != "" && !labels[edge.Label] {
+			labels[edge.Label] = true
+			d.labels = append(d.labels, edge.Label)
+		}
+	}
+	sort.Strings(d.labels)
+}
+
+// nodeID retourne l'identifiant du nœud qu'un nom désigne selon la politique
+// du parser, qui rapproche "Jean" et "jean"
+func (d *document) nodeID(name string) string {
+	if id, ok := d.nodes[d.policy.Key(name)]; ok {
+		return id
+	}
+	return services.NormalizeNodeID(name)
+}
+
+// symbolAt retourne l'élément nommé sous le curseur
+func (d *document) symbolAt(pos Position) (symbol, bool) {
+	line, column := d.fromLSP(pos)
+
+	for _, ref := range d.references {
+		if ref.span.contains(line, column) {
+			return ref, true
+		}
+	}
+	for name, s := range d.variables {
+		if s.contains(line, column) {
+			return symbol{kind: "variable", name: name, span: s}, true
+		}
+	}
+	for name, spans := range d.subjects {
+		for _, s := range spans {
+			if s.contains(line, column) {
+				return symbol{kind: "subject", name: name, span: s}, true
+			}
+		}
+	}
+	return symbol{}, false
+}
+
+// definition retourne l'emplacement où un élément est introduit
+func (d *document) definition(sym symbol) (span, bool) {
+	if sym.kind == "variable" {
+		s, ok := d.variables[sym.name]
+		return s, ok
+	}
+	spans := d.subjects[sym.name]
+	if len(spans) == 0 {
+		return span{}, false
+	}
+	return spans[0], true
+}
+
+// occurrences retourne toutes les mentions d'un élément, tous contextes confondus
+func (d *document) occurrences(sym symbol, includeDeclaration bool) []span {
+	var spans []span
+	if sym.kind == "variable" {
+		if def, ok := d.variables[sym.name]; ok && includeDeclaration {
+			spans = append(spans, def)
+		}
+		for _, ref := range d.references {
+			if ref.name == sym.name {
+				spans = append(spans, ref.span)
+			}
+		}
+		return spans
+	}
+
+	for i, s := range d.subjects[sym.name] {
+		if i > 0 || includeDeclaration {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+// ========== CONVERSION DES POSITIONS ==========
+
+func (d *document) lineText(line int) string {
+	if line < 1 || line > len(d.lines) {
+		return ""
+	}
+	return strings.TrimRight(d.lines[line-1], "\r")
+}
+
+// textAt retourne le texte couvert par un fragment
+func (d *document) textAt(s span) string {
+	runes := []rune(d.lineText(s.line))
+	start := s.column - 1
+	if start < 0 || start+s.length > len(runes) {
+		return ""
+	}
+	return string(runes[start : start+s.length])
+}
+
+// toLSP convertit une position en caractères (à partir de 1) en position LSP
+func (d *document) toLSP(line, column int) Position {
+	runes := []rune(d.lineText(line))
+	if column-1 > len(runes) {
+		column = len(runes) + 1
+	}
+	if column < 1 {
+		column = 1
+	}
+	return Position{
+		Line:      line - 1,
+		Character: len(utf16.Encode(runes[:column-1])),
+	}
+}
+
+// fromLSP convertit une position LSP en ligne et colonne en caractères (à partir de 1)
+func (d *document) fromLSP(pos Position) (int, int) {
+	line := pos.Line + 1
+	units := 0
+	column := 1
+	for _, r := range d.lineText(line) {
+		if units >= pos.Character {
+			break
+		}
+		units++
+		if r >= 0x10000 {
+			units++ // paire de substitution UTF-16
+		}
+		column++
+	}
+	return line, column
+}
+
+func (d *document) rangeOf(s span) Range {
+	return Range{
+		Start: d.toLSP(s.line, s.column),
+		End:   d.toLSP(s.line, s.column+s.length),
+	}
+}
@@ -0,0 +1,352 @@
+// Package lsp implémente un serveur Language Server Protocol pour les
+// fichiers N4L, au-dessus du parser et de l'analyseur de graphe.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"n4l-editor/services"
+)
+
+// Server dialogue en JSON-RPC avec un éditeur sur un flux (stdio en pratique)
+type Server struct {
+	in        *bufio.Reader
+	out       io.Writer
+	parser    *services.N4LParser
+	linter    *services.N4LLinter
+	documents map[string]*document
+	shutdown  bool
+	writeErr  error // première erreur d'écriture d'une notification
+}
+
+// NewServer crée un serveur lisant les requêtes sur in et répondant sur out
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		parser:    services.NewN4LParser(),
+		linter:    services.NewN4LLinter(),
+		documents: make(map[string]*document),
+	}
+}
+
+// Run traite les messages jusqu'à la notification "exit" ou la fin du flux.
+// Une erreur est renvoyée si le client quitte sans avoir demandé "shutdown".
+func (s *Server) Run() error {
+	for {
+		body, err := readMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := s.reply(nil, nil, &responseError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("arrêt sans requête shutdown")
+			}
+			return nil
+		}
+
+		result, rpcErr := s.handle(req)
+		if s.writeErr != nil {
+			return s.writeErr
+		}
+		if len(req.ID) == 0 {
+			continue // notification : pas de réponse
+		}
+		if err := s.reply(req.ID, result, rpcErr); err != nil {
+			return err
+		}
+	}
+}
+
+// handle aiguille une requête vers sa méthode
+func (s *Server) handle(req request) (interface{}, *responseError) {
+	if s.shutdown && req.Method != "shutdown" {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "serveur en cours d'arrêt"}
+	}
+
+	switch req.Method {
+	case "initialize":
+		return s.initialize(), nil
+	case "initialized", "$/cancelRequest", "$/setTrace":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		s.update(params.TextDocument.URI, params.TextDocument.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		// Synchronisation complète : le dernier changement contient tout le texte
+		if n := len(params.ContentChanges); n > 0 {
+			s.update(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		delete(s.documents, params.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []Diagnostic{}})
+		return nil, nil
+
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.definition(params), nil
+	case "textDocument/references":
+		var params ReferenceParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.references(params), nil
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.completion(params), nil
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.hover(params), nil
+	}
+
+	if len(req.ID) == 0 {
+		return nil, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "méthode inconnue : " + req.Method}
+}
+
+func (s *Server) initialize() interface{} {
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync":   1, // texte complet à chaque modification
+			"definitionProvider": true,
+			"referencesProvider": true,
+			"hoverProvider":      true,
+			"completionProvider": map[string]interface{}{
+				"triggerCharacters": []string{"(", "$", ">"},
+			},
+		},
+		"serverInfo": map[string]string{"name": "n4l"},
+	}
+}
+
+// update réanalyse un document et publie ses diagnostics
+func (s *Server) update(uri, text string) {
+	doc := newDocument(uri, text, s.parser, s.linter)
+	s.documents[uri] = doc
+
+	diagnostics := make([]Diagnostic, 0, len(doc.diagnostics))
+	for _, d := range doc.diagnostics {
+		length := d.Length
+		if length <= 0 {
+			length = len([]rune(doc.lineText(d.Pos.Line))) - d.Pos.Column + 1
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    doc.rangeOf(span{line: d.Pos.Line, column: d.Pos.Column, length: length}),
+			Severity: lspSeverity(d.Severity),
+			Code:     d.Code,
+			Source:   "n4l",
+			Message:  d.Message,
+		})
+	}
+	s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+}
+
+// definition renvoie la première mention d'un sujet ou la définition "@nom"
+func (s *Server) definition(params TextDocumentPositionParams) interface{} {
+	doc, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return nil
+	}
+	sym, ok := doc.symbolAt(params.Position)
+	if !ok {
+		return nil
+	}
+	def, ok := doc.definition(sym)
+	if !ok {
+		return nil
+	}
+	return Location{URI: doc.uri, Range: doc.rangeOf(def)}
+}
+
+// references renvoie toutes les mentions d'un élément, tous contextes confondus
+func (s *Server) references(params ReferenceParams) interface{} {
+	locations := []Location{}
+	doc, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return locations
+	}
+	sym, ok := doc.symbolAt(params.Position)
+	if !ok {
+		return locations
+	}
+	for _, occurrence := range doc.occurrences(sym, params.Context.IncludeDeclaration) {
+		locations = append(locations, Location{URI: doc.uri, Range: doc.rangeOf(occurrence)})
+	}
+	return locations
+}
+
+// completion propose les libellés de relation dans une parenthèse ouverte,
+// les variables après "$" et les sujets existants ailleurs
+func (s *Server) completion(params TextDocumentPositionParams) interface{} {
+	items := []CompletionItem{}
+	doc, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return items
+	}
+
+	line, column := doc.fromLSP(params.Position)
+	before := string([]rune(doc.lineText(line))[:column-1])
+	word := before[strings.LastIndexAny(before, " \t(;{")+1:]
+
+	switch {
+	case strings.LastIndex(before, "(") > strings.LastIndex(before, ")"):
+		seen := make(map[string]bool)
+		for _, label := range doc.labels {
+			seen[label] = true
+			items = append(items, CompletionItem{Label: label, Kind: completionKeyword, Detail: "relation"})
+		}
+		// Relations déclarées dans le registre mais pas encore utilisées
+		for _, t := range services.DefaultRelationRegistry().Types() {
+			if !seen[t.Name] {
+				items = append(items, CompletionItem{Label: t.Name, Kind: completionKeyword, Detail: "relation " + t.Class})
+			}
+		}
+	case strings.HasPrefix(word, "$"):
+		for _, variable := range doc.parsed.Document.Variables {
+			for i, item := range variable.Items {
+				items = append(items, CompletionItem{
+					Label:  fmt.Sprintf("$%s.%d", variable.Name, i+1),
+					Kind:   completionVariable,
+					Detail: item,
+				})
+			}
+		}
+	default:
+		for _, node := range doc.graph.Nodes() {
+			items = append(items, CompletionItem{Label: node.ID, Kind: completionValue, Detail: node.Context})
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+	}
+	return items
+}
+
+// hover affiche le degré d'un nœud et la liste de ses arêtes
+func (s *Server) hover(params TextDocumentPositionParams) interface{} {
+	doc, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return nil
+	}
+	sym, ok := doc.symbolAt(params.Position)
+	if !ok {
+		return nil
+	}
+
+	var b strings.Builder
+	if sym.kind == "variable" {
+		fmt.Fprintf(&b, "**@%s**\n", sym.name)
+		for _, variable := range doc.parsed.Document.Variables {
+			if variable.Name == sym.name {
+				for i, item := range variable.Items {
+					fmt.Fprintf(&b, "\n- `$%s.%d` : %s", sym.name, i+1, item)
+				}
+			}
+		}
+	} else {
+		incoming, outgoing := doc.graph.InEdges(sym.name), doc.graph.OutEdges(sym.name)
+		fmt.Fprintf(&b, "**%s** : degré %d (%d entrante(s), %d sortante(s))\n",
+			sym.name, doc.graph.Degree(sym.name), len(incoming), len(outgoing))
+		if node, ok := doc.graph.Node(sym.name); ok && len(node.Contexts) > 0 {
+			fmt.Fprintf(&b, "\nContextes : %s\n", strings.Join(node.Contexts, ", "))
+		}
+		for _, edge := range outgoing {
+			fmt.Fprintf(&b, "\n- → %s → %s", edgeLabel(edge.Label, edge.Type), edge.To)
+		}
+		for _, edge := range incoming {
+			fmt.Fprintf(&b, "\n- %s → %s →", edge.From, edgeLabel(edge.Label, edge.Type))
+		}
+	}
+
+	r := doc.rangeOf(sym.span)
+	return Hover{Contents: MarkupContent{Kind: "markdown", Value: b.String()}, Range: &r}
+}
+
+func edgeLabel(label, edgeType string) string {
+	if label == "" {
+		return edgeType
+	}
+	return label
+}
+
+func (s *Server) reply(id json.RawMessage, result interface{}, rpcErr *responseError) error {
+	resp := response{JSONRPC: "2.0", ID: id}
+	if resp.ID == nil {
+		resp.ID = json.RawMessage("null")
+	}
+	if rpcErr != nil {
+		resp.Error = rpcErr
+	} else {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		resp.Result = data
+	}
+	return writeMessage(s.out, resp)
+}
+
+// notify envoie une notification ; une erreur d'écriture est conservée pour
+// que Run s'arrête, le client n'étant plus joignable
+func (s *Server) notify(method string, params interface{}) {
+	if s.writeErr != nil {
+		return
+	}
+	s.writeErr = writeMessage(s.out, notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func invalidParams(err error) *responseError {
+	return &responseError{Code: codeInvalidParams, Message: err.Error()}
+}
+
+func lspSeverity(severity string) int {
+	switch severity {
+	case services.SeverityError:
+		return severityError
+	case services.SeverityWarning:
+		return severityWarning
+	}
+	return severityInformation
+}
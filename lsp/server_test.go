@@ -0,0 +1,223 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const testURI = "file:///enquete.n4l"
+
+const testDocument = `:: Preuves | Chronologie ::
+@suspect Dupont (était à) Gare
+Gare (proche de) Hôtel
+:: Témoins ::
+$suspect.1 (a vu) Martin
+X -> r -> $inconnu.1
+`
+
+// session envoie une suite de messages au serveur et retourne ses réponses
+func session(t *testing.T, messages ...interface{}) []map[string]json.RawMessage {
+	t.Helper()
+	var in, out bytes.Buffer
+	for _, m := range messages {
+		if err := writeMessage(&in, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := NewServer(&in, &out).Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	var replies []map[string]json.RawMessage
+	r := bufio.NewReader(&out)
+	for {
+		body, err := readMessage(r)
+		if err != nil {
+			break
+		}
+		var msg map[string]json.RawMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatal(err)
+		}
+		replies = append(replies, msg)
+	}
+	return replies
+}
+
+func call(id int, method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+}
+
+func notify(method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+}
+
+func at(line, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]string{"uri": testURI},
+		"position":     Position{Line: line, Character: character},
+		"context":      map[string]bool{"includeDeclaration": true},
+	}
+}
+
+// result retourne le résultat de la réponse à une requête
+func result(t *testing.T, replies []map[string]json.RawMessage, id int, v interface{}) {
+	t.Helper()
+	for _, msg := range replies {
+		if string(msg["id"]) == strconv.Itoa(id) {
+			if msg["error"] != nil {
+				t.Fatalf("requête %d en erreur : %s", id, msg["error"])
+			}
+			if err := json.Unmarshal(msg["result"], v); err != nil {
+				t.Fatal(err)
+			}
+			return
+		}
+	}
+	t.Fatalf("pas de réponse à la requête %d", id)
+}
+
+func TestServerSession(t *testing.T) {
+	replies := session(t,
+		call(1, "initialize", map[string]interface{}{}),
+		notify("initialized", map[string]interface{}{}),
+		notify("textDocument/didOpen", map[string]interface{}{
+			"textDocument": map[string]string{"uri": testURI, "text": testDocument},
+		}),
+		call(2, "textDocument/definition", at(4, 3)),  // $suspect.1
+		call(3, "textDocument/references", at(2, 1)),  // Gare
+		call(4, "textDocument/hover", at(1, 10)),      // Dupont
+		call(5, "textDocument/completion", at(2, 6)),  // dans "(proche de)"
+		call(6, "textDocument/definition", at(4, 18)), // Martin
+		call(7, "shutdown", nil),
+		notify("exit", nil),
+	)
+
+	// Diagnostics publiés à l'ouverture, dont la référence non résolue
+	var published PublishDiagnosticsParams
+	for _, msg := range replies {
+		if string(msg["method"]) == `"textDocument/publishDiagnostics"` {
+			json.Unmarshal(msg["params"], &published)
+		}
+	}
+	found := false
+	for _, d := range published.Diagnostics {
+		if d.Code == "N4L004" && d.Range.Start.Line == 5 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("diagnostic N4L004 attendu ligne 6 : %+v", published.Diagnostics)
+	}
+
+	var def Location
+	result(t, replies, 2, &def)
+	if def.Range.Start != (Position{Line: 1, Character: 0}) {
+		t.Errorf("définition de $suspect.1 : %+v", def.Range)
+	}
+
+	var refs []Location
+	result(t, replies, 3, &refs)
+	if len(refs) != 2 {
+		t.Errorf("2 mentions de Gare attendues : %+v", refs)
+	}
+
+	var hover Hover
+	result(t, replies, 4, &hover)
+	if !strings.Contains(hover.Contents.Value, "degré 2") {
+		t.Errorf("survol de Dupont : %q", hover.Contents.Value)
+	}
+
+	var items []CompletionItem
+	result(t, replies, 5, &items)
+	labels := make(map[string]bool)
+	for _, item := range items {
+		labels[item.Label] = true
+	}
+	if !labels["était à"] || !labels["a vu"] || labels["Gare"] {
+		t.Errorf("complétion des relations : %+v", items)
+	}
+
+	result(t, replies, 6, &def)
+	if def.Range.Start != (Position{Line: 4, Character: 18}) {
+		t.Errorf("définition de Martin : %+v", def.Range)
+	}
+}
+
+// hoverAt ouvre un document et retourne le survol d'une position
+func hoverAt(t *testing.T, text string, line, character int) string {
+	t.Helper()
+	replies := session(t,
+		call(1, "initialize", map[string]interface{}{}),
+		notify("textDocument/didOpen", map[string]interface{}{
+			"textDocument": map[string]string{"uri": testURI, "text": text},
+		}),
+		call(2, "textDocument/hover", at(line, character)),
+		call(3, "shutdown", nil),
+		notify("exit", nil),
+	)
+	var hover Hover
+	result(t, replies, 2, &hover)
+	return hover.Contents.Value
+}
+
+func TestHoverDegree(t *testing.T) {
+	// Une boucle compte une fois, comme dans Graph.Degree
+	if got := hoverAt(t, "Jean (se cite) Jean\nJean (aime) Marie\n", 1, 1); !strings.Contains(got, "degré 2 ") {
+		t.Errorf("survol de Jean : %q", got)
+	}
+}
+
+func TestSymbolsFollowIdentityPolicy(t *testing.T) {
+	const text = "Jean (aime) Marie\njean (voit) Paul\n"
+	if got := hoverAt(t, text, 1, 1); !strings.Contains(got, "**Jean** : degré 2 ") {
+		t.Errorf("survol de jean : %q", got)
+	}
+
+	replies := session(t,
+		call(1, "initialize", map[string]interface{}{}),
+		notify("textDocument/didOpen", map[string]interface{}{
+			"textDocument": map[string]string{"uri": testURI, "text": text},
+		}),
+		call(2, "textDocument/references", at(1, 1)),
+		call(3, "shutdown", nil),
+		notify("exit", nil),
+	)
+	var refs []Location
+	result(t, replies, 2, &refs)
+	if len(refs) != 2 {
+		t.Errorf("Jean et jean attendus : %+v", refs)
+	}
+}
+
+func TestReadMessageRejectsHugeLength(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("Content-Length: 99999999999999\r\n\r\n{}"))
+	if _, err := readMessage(r); err == nil {
+		t.Fatal("Content-Length démesuré accepté")
+	}
+}
+
+// failingWriter simule un client dont le tube est fermé
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("tube fermé") }
+
+func TestRunStopsWhenNotificationFails(t *testing.T) {
+	var in bytes.Buffer
+	open := notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]string{"uri": testURI, "text": testDocument},
+	})
+	for _, m := range []interface{}{open, open} {
+		if err := writeMessage(&in, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := NewServer(&in, failingWriter{}).Run(); err == nil {
+		t.Fatal("Run continue après l'échec d'une notification")
+	}
+}
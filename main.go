@@ -1,7 +1,9 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
 
@@ -13,9 +15,35 @@ const (
 	// Configuration
 	port         = ":8080"
 	ollamaAPIURL = "http://localhost:11434/api/generate"
+
+	// Registre de relations personnalisé (facultatif, voir services/relations.json)
+	relationsConfig = "relations.json"
+
+	// Schéma du projet : types de nœuds et contraintes (facultatif)
+	schemaConfig = "schema.json"
 )
 
 func main() {
+	// Charger le registre de relations avant de créer parsers et analyseurs
+	registry, err := services.LoadRelationRegistry(relationsConfig)
+	switch {
+	case err == nil:
+		services.SetDefaultRelationRegistry(registry)
+		fmt.Printf("Registre de relations chargé depuis %s\n", relationsConfig)
+	case !errors.Is(err, fs.ErrNotExist):
+		log.Fatal(err)
+	}
+
+	// Le schéma désigne les relations par le registre : le charger ensuite
+	schema, err := services.LoadSchema(schemaConfig)
+	switch {
+	case err == nil:
+		services.SetDefaultSchema(schema)
+		fmt.Printf("Schéma chargé depuis %s\n", schemaConfig)
+	case !errors.Is(err, fs.ErrNotExist):
+		log.Fatal(err)
+	}
+
 	// Initialiser les services
 	ollamaService := services.NewOllamaService(ollamaAPIURL)
 
@@ -55,11 +83,14 @@ func setupAPIRoutes(
 	http.HandleFunc("/api/extract-concepts", concepts.ExtractConcepts)
 	http.HandleFunc("/api/auto-extract-subjects", concepts.AutoExtractSubjects)
 	http.HandleFunc("/api/parse-n4l", concepts.ParseN4L)
+	http.HandleFunc("/api/parse-project", concepts.ParseProject)
 	http.HandleFunc("/api/generate-n4l-from-text", concepts.GenerateN4LFromText)
 
 	// Graphe
 	http.HandleFunc("/api/graph-data", graph.GetGraphData)
+	http.HandleFunc("/api/graph-to-n4l", graph.GraphToN4L)
 	http.HandleFunc("/api/find-all-paths", graph.FindAllPaths)
+	http.HandleFunc("/api/find-paths", graph.FindPaths)
 	http.HandleFunc("/api/layered-graph", graph.GetLayeredGraph)
 	http.HandleFunc("/api/analyze-path", graph.AnalyzePath)
 	http.HandleFunc("/api/graph/expansion-cone", graph.GetExpansionCone)
@@ -73,6 +104,7 @@ func setupAPIRoutes(
 	http.HandleFunc("/api/detect-temporal-patterns", analysis.DetectTemporalPatterns)
 	http.HandleFunc("/api/check-consistency", analysis.CheckConsistency)
 	http.HandleFunc("/api/generate-questions", analysis.GenerateQuestions)
+	http.HandleFunc("/api/centrality", analysis.GetCentrality)
 
 	// Timeline
 	http.HandleFunc("/api/timeline-data", timeline.GetTimelineData)
@@ -20,29 +20,207 @@ type GraphData struct {
 	Nodes     []Node              `json:"nodes"`
 	Edges     []Edge              `json:"edges"`
 	Positions map[string]Position `json:"positions,omitempty"`
+
+	Hyperedges []Hyperedge `json:"hyperedges,omitempty"` // événements à plusieurs participants
+}
+
+// Hyperedge est un fait à plusieurs participants, déclaré par un groupe dont
+// chaque membre porte un rôle : "Remise => { agent: Jean; objet: lettre }".
+// Dans Nodes et Edges, il est représenté par un nœud événement relié à chaque
+// participant par une arête de rôle.
+type Hyperedge struct {
+	ID           string        `json:"id"` // identifiant du nœud événement
+	Label        string        `json:"label"`
+	Context      string        `json:"context"`
+	Participants []Participant `json:"participants"`
+	Date         *time.Time    `json:"date,omitempty"` // attribut "date" de l'événement
+	Spans        []SourceSpan  `json:"spans,omitempty"`
+}
+
+// Participant est un nœud impliqué dans un événement avec un rôle donné
+type Participant struct {
+	Role string `json:"role"` // "agent", "objet", "destinataire", "lieu", ...
+	Node string `json:"node"`
+	Edge string `json:"edge"` // identifiant de l'arête de rôle
 }
 
 // Node représente un nœud dans le graphe
 type Node struct {
-	ID      string `json:"id"`
-	Label   string `json:"label"`
-	Context string `json:"context"`
+	ID         string         `json:"id"`
+	Label      string         `json:"label"`
+	Context    string         `json:"context"`
+	Contexts   []string       `json:"contexts,omitempty"`   // chemins de tous les contextes du nœud, ancêtres compris
+	Spans      []SourceSpan   `json:"spans,omitempty"`      // lignes du source qui mentionnent le nœud
+	Aliases    []string       `json:"aliases,omitempty"`    // autres noms fusionnés dans ce nœud
+	Properties []NodeProperty `json:"properties,omitempty"` // attributs typés ("âge", "date", ...)
+	Kind       string         `json:"kind,omitempty"`       // "event" pour le nœud d'un événement n-aire
+}
+
+// NodeProperty est un attribut typé d'un nœud, déclaré par une relation de
+// propriété du registre : "Victor Moreau (âge) 67 ans"
+type NodeProperty struct {
+	Name    string       `json:"name"`
+	Type    string       `json:"type"`  // "string", "number", "date", "quantity"
+	Value   string       `json:"value"` // texte du source
+	Number  *float64     `json:"number,omitempty"`
+	Unit    string       `json:"unit,omitempty"`
+	Date    *time.Time   `json:"date,omitempty"`
+	Context string       `json:"context"`
+	Spans   []SourceSpan `json:"spans,omitempty"`
+
+	Confidence float64 `json:"confidence,omitempty"`
+	Source     string  `json:"source,omitempty"`
 }
 
 // Edge représente une arête dans le graphe
 type Edge struct {
-	ID      string `json:"id"`
-	From    string `json:"from"`
-	To      string `json:"to"`
-	Label   string `json:"label"`
-	Type    string `json:"type"` // "relation", "equivalence", "group"
-	Context string `json:"context"`
+	ID       string       `json:"id"`
+	From     string       `json:"from"`
+	To       string       `json:"to"`
+	Label    string       `json:"label"`
+	Type     string       `json:"type"` // "relation", "equivalence", "group", "role", "sequence", "negation", "non_equivalence"
+	Context  string       `json:"context"`
+	Contexts []string     `json:"contexts,omitempty"`
+	Spans    []SourceSpan `json:"spans,omitempty"` // lignes du source qui ont produit l'arête
+
+	// Qualificatifs "[certitude=…; source=…; poids=…]" : 0 ou "" si non précisés
+	Confidence float64 `json:"confidence,omitempty"` // certitude dans ]0, 1], 0 si non précisée
+	Source     string  `json:"source,omitempty"`     // origine de l'information
+	Weight     float64 `json:"weight,omitempty"`     // importance relative
 }
 
 // ParsedN4L contient les données parsées d'un fichier N4L
 type ParsedN4L struct {
-	Subjects []string            `json:"subjects"`
-	Notes    map[string][]string `json:"notes"`
+	Subjects    []string            `json:"subjects"`
+	Notes       map[string][]string `json:"notes"`
+	Spans       NoteSpans           `json:"spans,omitempty"`
+	Contexts    []ContextNode       `json:"contexts"`
+	Document    *N4LDocument        `json:"document,omitempty"`
+	Diagnostics []Diagnostic        `json:"diagnostics"`
+	Files       []string            `json:"files,omitempty"` // fichiers d'un projet, dans l'ordre de fusion
+}
+
+// ========== TYPES POUR L'AST N4L ==========
+
+// SourcePos repère une position dans le source N4L (ligne et colonne à partir de 1)
+type SourcePos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// SourceSpan relie un élément du graphe ou un résultat d'analyse aux lignes
+// du source qui l'ont produit
+type SourceSpan struct {
+	File      string `json:"file,omitempty"`
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	Text      string `json:"text"`
+}
+
+// NoteSpans associe à chaque note l'emplacement de sa ligne source, par
+// contexte et dans le même ordre que ParsedN4L.Notes
+type NoteSpans map[string][]SourceSpan
+
+// N4LDocument est la racine de l'arbre syntaxique d'un fichier N4L
+type N4LDocument struct {
+	Contexts  []N4LContext  `json:"contexts"`
+	Variables []N4LVariable `json:"variables,omitempty"`
+	Includes  []N4LInclude  `json:"includes,omitempty"`
+}
+
+// N4LInclude est une directive "@include chemin", résolue par le chargeur de
+// projet relativement au fichier qui la contient
+type N4LInclude struct {
+	Path string    `json:"path"`
+	Pos  SourcePos `json:"pos"`
+}
+
+// N4LContext regroupe les instructions d'une section ":: contexte ::"
+type N4LContext struct {
+	Name       string         `json:"name"`  // clé du contexte, ex. "Enquête > Témoins | Preuves"
+	Paths      []string       `json:"paths"` // chemins désignés, ancêtres compris
+	Depth      int            `json:"depth"` // 1 pour "::", 2 pour ":::", ...
+	Pos        SourcePos      `json:"pos"`
+	Statements []N4LStatement `json:"statements"`
+}
+
+// N4LStatement représente une ligne significative du document
+type N4LStatement struct {
+	Kind       string         `json:"kind"` // "relation", "equivalence", "group", "event", "definition", "note"
+	Pos        SourcePos      `json:"pos"`
+	Raw        string         `json:"raw"`
+	Alias      string         `json:"alias,omitempty"` // nom défini par "@nom" en tête de ligne
+	Note       string         `json:"note"`            // forme normalisée transmise au graphe
+	Subjects   []string       `json:"subjects,omitempty"`
+	Relations  []N4LRelation  `json:"relations,omitempty"`
+	Group      *N4LGroup      `json:"group,omitempty"`
+	References []N4LReference `json:"references,omitempty"`
+	Qualifier  *N4LQualifier  `json:"qualifier,omitempty"`
+}
+
+// N4LQualifier porte les qualificatifs "[certitude=…; source=…; poids=…]"
+// d'une instruction, appliqués à toutes ses arêtes
+type N4LQualifier struct {
+	Confidence float64   `json:"confidence,omitempty"`
+	Source     string    `json:"source,omitempty"`
+	Weight     float64   `json:"weight,omitempty"`
+	Pos        SourcePos `json:"pos"`
+}
+
+// N4LItem est un élément positionné (sujet, cible, membre de groupe)
+type N4LItem struct {
+	Text string    `json:"text"`
+	Role string    `json:"role,omitempty"` // rôle d'un participant d'événement
+	Pos  SourcePos `json:"pos"`
+}
+
+// N4LRelation représente une relation binaire entre deux éléments
+type N4LRelation struct {
+	Kind     string    `json:"kind"` // "relation", "negation", "equivalence", "sequence"
+	Source   N4LItem   `json:"source"`
+	Label    string    `json:"label,omitempty"`
+	LabelPos SourcePos `json:"labelPos"`
+	Target   N4LItem   `json:"target"`
+}
+
+// N4LGroup représente un groupement "Parent => { a; b; c }", ou un événement
+// "Événement => { rôle: a; rôle: b }" dont les membres portent un rôle
+type N4LGroup struct {
+	Parent  N4LItem   `json:"parent"`
+	Members []N4LItem `json:"members"`
+}
+
+// N4LReference représente une référence "$nom.N" rencontrée dans une ligne
+type N4LReference struct {
+	Name     string    `json:"name"`
+	Index    int       `json:"index"`
+	Pos      SourcePos `json:"pos"`
+	Resolved string    `json:"resolved,omitempty"`
+}
+
+// N4LVariable est une entrée de la table des symboles : "@nom" et les éléments
+// de la ligne qu'il désigne, numérotés à partir de 1 par "$nom.N"
+type N4LVariable struct {
+	Name  string    `json:"name"`
+	Pos   SourcePos `json:"pos"`
+	Items []string  `json:"items"`
+}
+
+// ContextNode est un nœud de la hiérarchie des contextes
+type ContextNode struct {
+	Name     string        `json:"name"`
+	Path     string        `json:"path"`
+	Children []ContextNode `json:"children,omitempty"`
+}
+
+// Diagnostic signale un problème détecté lors du parsing
+type Diagnostic struct {
+	Severity string    `json:"severity"` // "error", "warning", "info"
+	Code     string    `json:"code"`
+	Message  string    `json:"message"`
+	File     string    `json:"file,omitempty"` // fichier concerné, dans un projet
+	Pos      SourcePos `json:"pos"`
+	Length   int       `json:"length,omitempty"`
 }
 
 // TimelineEvent représente un événement chronologique enrichi
@@ -81,6 +259,70 @@ type TimelineEvent struct {
 	Importance string `json:"importance,omitempty"`
 }
 
+// Path est un chemin entre deux nœuds : ses nœuds, les arêtes parcourues dans
+// l'ordre et son coût, plus faible pour un chemin de faits établis
+type Path struct {
+	Nodes []string `json:"nodes"`
+	Edges []Edge   `json:"edges"`
+	Cost  float64  `json:"cost"`
+}
+
+// NodeCentrality regroupe les mesures de centralité d'un nœud, chacune entre
+// 0 et 1
+type NodeCentrality struct {
+	Node        string  `json:"node"`
+	Degree      int     `json:"degree"`
+	Betweenness float64 `json:"betweenness"` // part des plus courts chemins qui passent par le nœud
+	Closeness   float64 `json:"closeness"`   // proximité harmonique depuis les autres nœuds
+	PageRank    float64 `json:"pageRank"`
+	Eigenvector float64 `json:"eigenvector"` // rapportée au nœud le plus influent
+	Score       float64 `json:"score"`       // moyenne des quatre mesures rapportées à leur maximum
+}
+
+// CentralityReport classe les nœuds d'un graphe par centralité décroissante.
+// Sources est le nombre de nœuds d'où partent les plus courts chemins :
+// inférieur au nombre de nœuds, intermédiarité et proximité sont estimées.
+type CentralityReport struct {
+	Directed bool             `json:"directed"`
+	Sources  int              `json:"sources"`
+	Nodes    []NodeCentrality `json:"nodes"`
+}
+
+// ConeNode est un nœud atteint par un cône d'expansion
+type ConeNode struct {
+	ID    string   `json:"id"`
+	Depth int      `json:"depth"`
+	Path  []string `json:"path"` // nœuds depuis la racine, racine et nœud compris
+}
+
+// ExpansionCone est le voisinage d'un nœud, du plus proche au plus éloigné.
+// Truncated indique que le nombre maximal de nœuds a été atteint.
+type ExpansionCone struct {
+	Root      string     `json:"root"`
+	Nodes     []ConeNode `json:"nodes"`
+	Edges     []Edge     `json:"edges"`
+	Truncated bool       `json:"truncated"`
+}
+
+// Community est un groupe de nœuds plus liés entre eux qu'avec le reste du
+// graphe
+type Community struct {
+	ID             int      `json:"id"`
+	Nodes          []string `json:"nodes"`
+	Size           int      `json:"size"`
+	InternalWeight float64  `json:"internalWeight"` // force cumulée des arêtes internes
+	Modularity     float64  `json:"modularity"`     // contribution à la modularité du découpage
+}
+
+// CommunityReport est un découpage du graphe en communautés, de la plus
+// grande à la plus petite. Une résolution plus élevée donne des communautés
+// plus petites.
+type CommunityReport struct {
+	Resolution  float64     `json:"resolution"`
+	Modularity  float64     `json:"modularity"`
+	Communities []Community `json:"communities"`
+}
+
 // AnalyzePathRequest pour l'analyse de chemins
 type AnalyzePathRequest struct {
 	Path  []string            `json:"path"`
@@ -89,12 +331,13 @@ type AnalyzePathRequest struct {
 
 // InvestigationQuestion représente une question d'investigation
 type InvestigationQuestion struct {
-	Question string   `json:"question"`
-	Type     string   `json:"type"`
-	Priority string   `json:"priority"`
-	Context  string   `json:"context"`
-	Nodes    []string `json:"nodes"`
-	Hint     string   `json:"hint"`
+	Question string       `json:"question"`
+	Type     string       `json:"type"`
+	Priority string       `json:"priority"`
+	Context  string       `json:"context"`
+	Nodes    []string     `json:"nodes"`
+	Hint     string       `json:"hint"`
+	Spans    []SourceSpan `json:"spans,omitempty"`
 }
 
 // InvestigationStep représente une étape du mode enquête
@@ -117,18 +360,20 @@ type InvestigationProgress struct {
 
 // TemporalPattern représente un pattern temporel détecté
 type TemporalPattern struct {
-	Pattern     string   `json:"pattern"`
-	Occurrences []string `json:"occurrences"`
-	Suggestions []string `json:"suggestions"`
+	Pattern     string       `json:"pattern"`
+	Occurrences []string     `json:"occurrences"`
+	Suggestions []string     `json:"suggestions"`
+	Spans       []SourceSpan `json:"spans,omitempty"`
 }
 
 // Inconsistency représente une incohérence détectée
 type Inconsistency struct {
-	Type        string   `json:"type"`
-	Description string   `json:"description"`
-	Nodes       []string `json:"nodes"`
-	Severity    string   `json:"severity"` // "error", "warning", "info"
-	Suggestion  string   `json:"suggestion"`
+	Type        string       `json:"type"`
+	Description string       `json:"description"`
+	Nodes       []string     `json:"nodes"`
+	Severity    string       `json:"severity"` // "error", "warning", "info"
+	Suggestion  string       `json:"suggestion"`
+	Spans       []SourceSpan `json:"spans,omitempty"`
 }
 
 // LayeredNode pour la vue en couches
@@ -179,7 +424,7 @@ type SemanticVersion struct {
 
 // SemanticChange représente un changement sémantique
 type SemanticChange struct {
-	Type        string `json:"type"` // node_added, edge_added, node_removed, edge_removed, structural_change
+	Type        string `json:"type"` // node_added, edge_added, node_removed, edge_removed, edge_relabeled, structural_change
 	ElementID   string `json:"elementId,omitempty"`
 	Description string `json:"description"`
 	Impact      string `json:"impact"` // low, medium, high
@@ -264,6 +509,7 @@ type DensityMap struct {
 	HeatmapData   []HeatmapPoint `json:"heatmapData"`
 	GlobalDensity float64        `json:"globalDensity"`
 	EmptyZones    []EmptyZone    `json:"emptyZones"`
+	Modularity    float64        `json:"modularity"` // modularité du découpage en zones
 }
 
 // DensityZone représente une zone de densité
@@ -299,6 +545,7 @@ type ConceptualTerritories struct {
 	Explored   []Territory `json:"explored"`
 	Unexplored []Territory `json:"unexplored"`
 	Frontier   []Territory `json:"frontier"`
+	Modularity float64     `json:"modularity"` // modularité du découpage en territoires
 }
 
 // Territory représente un territoire conceptuel
@@ -310,6 +557,7 @@ type Territory struct {
 	Size        int              `json:"size"`
 	Description string           `json:"description"`
 	CentralNode string           `json:"centralNode"`
+	Modularity  float64          `json:"modularity"` // contribution de la communauté
 	Metrics     TerritoryMetrics `json:"metrics"`
 }
 
@@ -369,6 +617,8 @@ type DensityMetrics struct {
 	LowDensityZones       int         `json:"lowDensityZones"`
 	FrontierZones         int         `json:"frontierZones"`
 	BalanceScore          float64     `json:"balanceScore"`
+	AverageConfidence     float64     `json:"averageConfidence"` // certitude moyenne des arêtes
+	WeakEdges             int         `json:"weakEdges"`         // arêtes dont la certitude est inférieure à 0.5
 	Recommendations       []string    `json:"recommendations"`
 }
 
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+
+	"n4l-editor/models"
+)
+
+const (
+	// maxCentralitySources borne le nombre de plus courts chemins calculés :
+	// au-delà, intermédiarité et proximité sont estimées depuis des nœuds
+	// sources répartis régulièrement
+	maxCentralitySources = 256
+	pageRankDamping      = 0.85
+	centralityIterations = 200
+	centralityTolerance  = 1e-9
+	maxKeyPlayers        = 3
+)
+
+// KeyPlayerBetweenness est l'intermédiarité à partir de laquelle un nœud est
+// un acteur clé : une part notable des plus courts chemins passe par lui
+const KeyPlayerBetweenness = 0.1
+
+// Centrality mesure l'importance structurelle de chaque nœud : intermédiarité
+// (Brandes), proximité harmonique, PageRank et centralité de vecteur propre.
+// Les distances sont les coûts de findPath, PageRank suit la force des
+// arêtes ; en mode orienté, équivalences, rôles et relations symétriques se
+// parcourent dans les deux sens, comme pour FindPaths. La centralité de
+// vecteur propre ignore le sens : dans un graphe orienté sans cycle, elle
+// serait nulle partout.
+func (ga *GraphAnalyzer) Centrality(graphData models.GraphData, directed bool) models.CentralityReport {
+	return ga.centrality(NewGraph(graphData), directed)
+}
+
+// centralityArc est une arête vue depuis son origine, les nœuds étant numérotés
+type centralityArc struct {
+	to       int
+	cost     float64 // distance, l'arête parallèle la plus sûre l'emporte
+	strength float64 // force cumulée des arêtes parallèles
+}
+
+// centralityArcs sont les arêtes sortantes de chaque nœud, une seule par
+// voisin
+type centralityArcs struct {
+	arcs     [][]centralityArc
+	position map[[2]int]int
+}
+
+func newArcs(n int) *centralityArcs {
+	return &centralityArcs{arcs: make([][]centralityArc, n), position: make(map[[2]int]int)}
+}
+
+func (a *centralityArcs) add(from, to int, cost, strength float64) {
+	if i, ok := a.position[[2]int{from, to}]; ok {
+		arc := &a.arcs[from][i]
+		arc.cost = math.Min(arc.cost, cost)
+		arc.strength += strength
+		return
+	}
+	a.position[[2]int{from, to}] = len(a.arcs[from])
+	a.arcs[from] = append(a.arcs[from], centralityArc{to, cost, strength})
+}
+
+func (ga *GraphAnalyzer) centrality(graph *Graph, directed bool) models.CentralityReport {
+	nodes := graph.Nodes()
+	index := make(map[string]int, len(nodes))
+	var ids []string
+	for _, node := range nodes {
+		if _, seen := index[node.ID]; !seen {
+			index[node.ID] = len(ids)
+			ids = append(ids, node.ID)
+		}
+	}
+	n := len(ids)
+	report := models.CentralityReport{Directed: directed, Nodes: make([]models.NodeCentrality, n)}
+	if n == 0 {
+		return report
+	}
+
+	out, undirected := newArcs(n), newArcs(n)
+	for _, edge := range graph.Edges() {
+		from, okFrom := index[edge.From]
+		to, okTo := index[edge.To]
+		if !okFrom || !okTo || from == to {
+			continue
+		}
+		cost, strength := edgeCost(edge), EdgeStrength(edge)
+		out.add(from, to, cost, strength)
+		if !directed || ga.bidirectional(edge) {
+			out.add(to, from, cost, strength)
+		}
+		undirected.add(from, to, cost, strength)
+		undirected.add(to, from, cost, strength)
+	}
+
+	betweenness, closeness, sources := shortestPathCentrality(out.arcs)
+	pageRank := pageRank(out.arcs)
+	eigenvector := eigenvectorCentrality(undirected.arcs)
+	report.Sources = sources
+
+	for i, id := range ids {
+		report.Nodes[i] = models.NodeCentrality{
+			Node:        id,
+			Degree:      graph.Degree(id),
+			Betweenness: betweenness[i],
+			Closeness:   closeness[i],
+			PageRank:    pageRank[i],
+			Eigenvector: eigenvector[i],
+		}
+	}
+	maxOf := func(measure func(models.NodeCentrality) float64) float64 {
+		max := 0.0
+		for _, c := range report.Nodes {
+			max = math.Max(max, measure(c))
+		}
+		return max
+	}
+	measures := []func(models.NodeCentrality) float64{
+		func(c models.NodeCentrality) float64 { return c.Betweenness },
+		func(c models.NodeCentrality) float64 { return c.Closeness },
+		func(c models.NodeCentrality) float64 { return c.PageRank },
+		func(c models.NodeCentrality) float64 { return c.Eigenvector },
+	}
+	for _, measure := range measures {
+		if max := maxOf(measure); max > 0 {
+			for i := range report.Nodes {
+				report.Nodes[i].Score += measure(report.Nodes[i]) / max / float64(len(measures))
+			}
+		}
+	}
+
+	sort.SliceStable(report.Nodes, func(i, j int) bool { return report.Nodes[i].Score > report.Nodes[j].Score })
+	return report
+}
+
+// shortestPathCentrality calcule intermédiarité (Brandes pondéré) et
+// proximité harmonique entrante, normalisées, depuis au plus
+// maxCentralitySources nœuds sources ; elle retourne aussi ce nombre
+func shortestPathCentrality(out [][]centralityArc) (betweenness, closeness []float64, sources int) {
+	n := len(out)
+	betweenness = make([]float64, n)
+	closeness = make([]float64, n)
+	reached := make([]int, n) // sources, autres que le nœud, prises en compte
+
+	stride := 1
+	if n > maxCentralitySources {
+		stride = (n + maxCentralitySources - 1) / maxCentralitySources
+	}
+
+	dist := make([]float64, n)
+	sigma := make([]float64, n)
+	delta := make([]float64, n)
+	done := make([]bool, n)
+	predecessors := make([][]int, n)
+	var order []int
+
+	for s := 0; s < n; s += stride {
+		sources++
+		for i := range dist {
+			dist[i], sigma[i], delta[i], done[i] = -1, 0, 0, false
+			predecessors[i] = predecessors[i][:0]
+		}
+		order = order[:0]
+		dist[s], sigma[s] = 0, 1
+		queue := &distanceQueue{{node: s}}
+
+		for queue.Len() > 0 {
+			current := heap.Pop(queue).(queuedDistance)
+			v := current.node
+			if done[v] {
+				continue
+			}
+			done[v] = true
+			order = append(order, v)
+			for _, arc := range out[v] {
+				w, d := arc.to, dist[v]+arc.cost
+				switch {
+				case dist[w] < 0 || d < dist[w]-centralityTolerance*d:
+					dist[w], sigma[w] = d, sigma[v]
+					predecessors[w] = append(predecessors[w][:0], v)
+					heap.Push(queue, queuedDistance{node: w, dist: d})
+				case !done[w] && math.Abs(d-dist[w]) <= centralityTolerance*d:
+					sigma[w] += sigma[v]
+					predecessors[w] = append(predecessors[w], v)
+				}
+			}
+		}
+
+		for i := len(order) - 1; i >= 0; i-- {
+			w := order[i]
+			for _, v := range predecessors[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			if w != s {
+				betweenness[w] += delta[w]
+				closeness[w] += 1 / dist[w]
+			}
+		}
+		for v := range reached {
+			if v != s {
+				reached[v]++
+			}
+		}
+	}
+
+	if n > 2 {
+		scale := float64(n) / float64(sources) / float64((n-1)*(n-2))
+		for v := range betweenness {
+			betweenness[v] = math.Min(betweenness[v]*scale, 1)
+		}
+	} else {
+		clear(betweenness)
+	}
+	for v := range closeness {
+		if reached[v] > 0 {
+			closeness[v] /= float64(reached[v])
+		}
+	}
+	return betweenness, closeness, sources
+}
+
+// pageRank suit les arêtes en proportion de leur force ; la part des nœuds
+// sans successeur est redistribuée à tous
+func pageRank(out [][]centralityArc) []float64 {
+	n := len(out)
+	rank := make([]float64, n)
+	next := make([]float64, n)
+	total := make([]float64, n)
+	for v, arcs := range out {
+		for _, arc := range arcs {
+			total[v] += arc.strength
+		}
+	}
+	for v := range rank {
+		rank[v] = 1 / float64(n)
+	}
+
+	for iteration := 0; iteration < centralityIterations; iteration++ {
+		dangling := 0.0
+		for v := range next {
+			next[v] = 0
+			if total[v] == 0 {
+				dangling += rank[v]
+			}
+		}
+		for v, arcs := range out {
+			for _, arc := range arcs {
+				next[arc.to] += rank[v] * arc.strength / total[v]
+			}
+		}
+		change := 0.0
+		for v := range next {
+			next[v] = (1-pageRankDamping)/float64(n) + pageRankDamping*(next[v]+dangling/float64(n))
+			change += math.Abs(next[v] - rank[v])
+		}
+		rank, next = next, rank
+		if change < centralityTolerance {
+			break
+		}
+	}
+	return rank
+}
+
+// eigenvectorCentrality est la méthode de la puissance sur A + I (le décalage
+// assure la convergence des graphes bipartis), rapportée au maximum
+func eigenvectorCentrality(arcs [][]centralityArc) []float64 {
+	n := len(arcs)
+	x := make([]float64, n)
+	next := make([]float64, n)
+	for v := range x {
+		if len(arcs[v]) > 0 {
+			x[v] = 1
+		}
+	}
+
+	for iteration := 0; iteration < centralityIterations; iteration++ {
+		max := 0.0
+		for v := range next {
+			next[v] = x[v]
+			for _, arc := range arcs[v] {
+				next[v] += arc.strength * x[arc.to]
+			}
+			max = math.Max(max, next[v])
+		}
+		if max == 0 {
+			return next
+		}
+		change := 0.0
+		for v := range next {
+			next[v] /= max
+			change += math.Abs(next[v] - x[v])
+		}
+		x, next = next, x
+		if change < centralityTolerance*float64(n) {
+			break
+		}
+	}
+	return x
+}
+
+// queuedDistance est un nœud en attente dans le Dijkstra de Brandes
+type queuedDistance struct {
+	node int
+	dist float64
+}
+
+type distanceQueue []queuedDistance
+
+func (q distanceQueue) Len() int            { return len(q) }
+func (q distanceQueue) Less(i, j int) bool  { return q[i].dist < q[j].dist }
+func (q distanceQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *distanceQueue) Push(x interface{}) { *q = append(*q, x.(queuedDistance)) }
+func (q *distanceQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
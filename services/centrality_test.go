@@ -0,0 +1,121 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"n4l-editor/models"
+)
+
+func centralityOf(t *testing.T, source string, directed bool) (models.CentralityReport, map[string]models.NodeCentrality) {
+	t.Helper()
+	parser := NewN4LParser()
+	graph := parser.ParseN4LToGraph(parser.ParseN4L(source).Notes)
+	report := NewGraphAnalyzer().Centrality(graph, directed)
+	byNode := make(map[string]models.NodeCentrality)
+	for _, c := range report.Nodes {
+		byNode[c.Node] = c
+	}
+	return report, byNode
+}
+
+func TestCentralityChain(t *testing.T) {
+	chain := "A (mène à) B\nB (mène à) C\nC (mène à) D\nD (mène à) E"
+
+	// Chemins orientés passant par C : A→D, A→E, B→D, B→E sur 4×3 paires
+	report, byNode := centralityOf(t, chain, true)
+	for node, want := range map[string]float64{"A": 0, "B": 3.0 / 12, "C": 4.0 / 12, "E": 0} {
+		if got := byNode[node].Betweenness; math.Abs(got-want) > 1e-9 {
+			t.Errorf("orienté : intermédiarité de %s %.3f, attendu %.3f", node, got, want)
+		}
+	}
+	// Personne n'atteint A ; E est atteint depuis tous les autres
+	if byNode["A"].Closeness != 0 || byNode["E"].Closeness <= byNode["B"].Closeness {
+		t.Errorf("proximité : A %.3f, B %.3f, E %.3f", byNode["A"].Closeness, byNode["B"].Closeness, byNode["E"].Closeness)
+	}
+	if byNode["E"].PageRank <= byNode["A"].PageRank {
+		t.Errorf("PageRank : A %.3f, E %.3f", byNode["A"].PageRank, byNode["E"].PageRank)
+	}
+	sum := 0.0
+	for _, c := range report.Nodes {
+		sum += c.PageRank
+	}
+	if math.Abs(sum-1) > 1e-6 {
+		t.Errorf("somme des PageRank %.6f", sum)
+	}
+
+	// Sans orientation, C est au milieu de la chaîne
+	report, byNode = centralityOf(t, chain, false)
+	if got := byNode["C"].Betweenness; math.Abs(got-8.0/12) > 1e-9 {
+		t.Errorf("non orienté : intermédiarité de C %.3f", got)
+	}
+	if report.Nodes[0].Node != "C" || report.Sources != 5 {
+		t.Errorf("classement %+v (%d sources)", report.Nodes, report.Sources)
+	}
+}
+
+func TestCentralityWeights(t *testing.T) {
+	// Le raccourci par une rumeur coûte plus que le détour par deux faits
+	source := strings.Join([]string{
+		"Suspect (a vu) Témoin [certitude=rumeur]",
+		"Suspect (connaît) Complice",
+		"Complice (connaît) Témoin",
+		"Témoin (connaît) Voisin",
+	}, "\n")
+	_, byNode := centralityOf(t, source, false)
+	if byNode["Complice"].Betweenness <= 0 {
+		t.Errorf("Complice devrait relier Suspect et Témoin : %+v", byNode["Complice"])
+	}
+	if byNode["Témoin"].Eigenvector != 1 {
+		t.Errorf("Témoin devrait être le plus influent : %+v", byNode)
+	}
+}
+
+func TestCentralitySampled(t *testing.T) {
+	// Sur un anneau, tous les nœuds se valent : l'estimation doit le refléter
+	var lines []string
+	n := 600
+	for i := 0; i < n; i++ {
+		lines = append(lines, fmt.Sprintf("N%d (connaît) N%d", i, (i+1)%n))
+	}
+	report, _ := centralityOf(t, strings.Join(lines, "\n"), false)
+	if report.Sources >= n || report.Sources < maxCentralitySources/2 {
+		t.Fatalf("%d sources pour %d nœuds", report.Sources, n)
+	}
+	// Intermédiarité exacte d'un nœud d'un anneau pair : (n/2-1)²/2 paires sur (n-1)(n-2)/2
+	want := float64((n/2-1)*(n/2-1)) / float64((n-1)*(n-2))
+	for _, c := range report.Nodes {
+		if math.Abs(c.Betweenness-want) > want*0.1 {
+			t.Fatalf("%s : intermédiarité %.4f, attendu ≈ %.4f", c.Node, c.Betweenness, want)
+		}
+	}
+}
+
+func BenchmarkCentrality(b *testing.B) {
+	graph := benchmarkGraph(b)
+	ga := NewGraphAnalyzer()
+	for i := 0; i < b.N; i++ {
+		ga.Centrality(graph, true)
+	}
+}
+
+func TestKeyPlayerQuestions(t *testing.T) {
+	source := "Alice (connaît) Bob\nBob (connaît) Claire\nBob (connaît) David\nDavid (connaît) Eve"
+	parser := NewN4LParser()
+	graph := parser.ParseN4LToGraph(parser.ParseN4L(source).Notes)
+
+	var players []string
+	for _, q := range NewGraphAnalyzer().GenerateInvestigationQuestions(graph) {
+		if q.Type == "key_player" {
+			players = append(players, q.Nodes...)
+			if q.Priority != "high" {
+				t.Errorf("question %+v", q)
+			}
+		}
+	}
+	if len(players) == 0 || players[0] != "Bob" {
+		t.Errorf("acteurs clés %v, attendu Bob en premier", players)
+	}
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"sort"
+
+	"n4l-editor/models"
+)
+
+// DefaultResolution est la résolution de la modularité classique
+const DefaultResolution = 1.0
+
+// maxLouvainPasses borne les passes de déplacement d'un niveau de Louvain
+const maxLouvainPasses = 100
+
+// Communities découpe le graphe en communautés par la méthode de Louvain :
+// chaque nœud rejoint la communauté voisine qui augmente le plus la
+// modularité, puis les communautés sont fusionnées en nœuds et l'on
+// recommence. Les arêtes comptent pour leur force, sans tenir compte du
+// sens ; une résolution nulle ou négative vaut DefaultResolution.
+func (ga *GraphAnalyzer) Communities(graphData models.GraphData, resolution float64) models.CommunityReport {
+	return ga.communities(NewGraph(graphData), resolution)
+}
+
+// louvainArc relie un nœud d'un niveau à un voisin
+type louvainArc struct {
+	to     int
+	weight float64
+}
+
+// louvainLevel est le graphe d'un niveau : les nœuds du premier niveau sont
+// ceux du graphe, ceux des suivants les communautés du niveau précédent
+type louvainLevel struct {
+	arcs [][]louvainArc
+	self []float64 // poids des boucles, chaque arête interne comptée deux fois
+}
+
+// link ajoute un arc de from vers to, ou ajoute son poids à l'arc existant ;
+// position retient l'indice de chaque arc déjà créé
+func (l *louvainLevel) link(position map[[2]int]int, from, to int, weight float64) {
+	if i, ok := position[[2]int{from, to}]; ok {
+		l.arcs[from][i].weight += weight
+		return
+	}
+	position[[2]int{from, to}] = len(l.arcs[from])
+	l.arcs[from] = append(l.arcs[from], louvainArc{to, weight})
+}
+
+func (l *louvainLevel) degree(v int) float64 {
+	k := l.self[v]
+	for _, arc := range l.arcs[v] {
+		k += arc.weight
+	}
+	return k
+}
+
+func (ga *GraphAnalyzer) communities(graph *Graph, resolution float64) models.CommunityReport {
+	if resolution <= 0 {
+		resolution = DefaultResolution
+	}
+	report := models.CommunityReport{Resolution: resolution, Communities: []models.Community{}}
+
+	index := make(map[string]int)
+	var ids []string
+	for _, node := range graph.Nodes() {
+		if _, seen := index[node.ID]; !seen {
+			index[node.ID] = len(ids)
+			ids = append(ids, node.ID)
+		}
+	}
+	n := len(ids)
+	if n == 0 {
+		return report
+	}
+
+	// Arêtes non orientées, les arêtes parallèles additionnant leur force
+	level := &louvainLevel{arcs: make([][]louvainArc, n), self: make([]float64, n)}
+	position := make(map[[2]int]int)
+	for _, edge := range graph.Edges() {
+		from, okFrom := index[edge.From]
+		to, okTo := index[edge.To]
+		if !okFrom || !okTo || from == to {
+			continue
+		}
+		strength := EdgeStrength(edge)
+		level.link(position, from, to, strength)
+		level.link(position, to, from, strength)
+	}
+
+	// membership[v] est la communauté du nœud v du graphe au niveau courant
+	membership := make([]int, n)
+	for v := range membership {
+		membership[v] = v
+	}
+	for {
+		community, moved := level.moveNodes(resolution)
+		count := renumber(community)
+		for v := range membership {
+			membership[v] = community[membership[v]]
+		}
+		size := len(level.arcs)
+		level = level.aggregate(community, count)
+		if !moved || count == size {
+			break
+		}
+	}
+
+	report.Communities, report.Modularity = summarize(level, membership, ids, resolution)
+	return report
+}
+
+// moveNodes déplace chaque nœud vers la communauté voisine qui augmente le
+// plus la modularité, jusqu'à stabilité ; elle indique si un nœud a bougé
+func (l *louvainLevel) moveNodes(resolution float64) ([]int, bool) {
+	n := len(l.arcs)
+	community := make([]int, n)
+	degree := make([]float64, n)
+	total := make([]float64, n) // somme des degrés de chaque communauté
+	m2 := 0.0
+	for v := range community {
+		community[v] = v
+		degree[v] = l.degree(v)
+		total[v] = degree[v]
+		m2 += degree[v]
+	}
+	if m2 == 0 {
+		return community, false
+	}
+
+	links := make([]float64, n) // poids des arêtes du nœud vers chaque communauté
+	var touched []int
+	moved := false
+	for pass := 0; pass < maxLouvainPasses; pass++ {
+		changed := false
+		for v := 0; v < n; v++ {
+			current := community[v]
+			touched = touched[:0]
+			for _, arc := range l.arcs[v] {
+				c := community[arc.to]
+				if links[c] == 0 {
+					touched = append(touched, c)
+				}
+				links[c] += arc.weight
+			}
+
+			// Gain de modularité à rejoindre c, le nœud étant retiré de sa
+			// communauté
+			total[current] -= degree[v]
+			gain := func(c int) float64 {
+				return links[c] - resolution*total[c]*degree[v]/m2
+			}
+			best, bestGain := current, gain(current)
+			for _, c := range touched {
+				if g := gain(c); g > bestGain+1e-12 {
+					best, bestGain = c, g
+				}
+			}
+			total[best] += degree[v]
+			community[v] = best
+			if best != current {
+				changed, moved = true, true
+			}
+
+			for _, c := range touched {
+				links[c] = 0
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	return community, moved
+}
+
+// renumber numérote les communautés de 0 à count-1 dans l'ordre des nœuds
+func renumber(community []int) (count int) {
+	number := make(map[int]int)
+	for v, c := range community {
+		if _, ok := number[c]; !ok {
+			number[c] = len(number)
+		}
+		community[v] = number[c]
+	}
+	return len(number)
+}
+
+// aggregate fait de chaque communauté un nœud du niveau suivant
+func (l *louvainLevel) aggregate(community []int, count int) *louvainLevel {
+	next := &louvainLevel{arcs: make([][]louvainArc, count), self: make([]float64, count)}
+	position := make(map[[2]int]int)
+	for v, out := range l.arcs {
+		from := community[v]
+		next.self[from] += l.self[v]
+		for _, arc := range out {
+			to := community[arc.to]
+			if to == from {
+				next.self[from] += arc.weight
+				continue
+			}
+			next.link(position, from, to, arc.weight)
+		}
+	}
+	return next
+}
+
+// summarize construit les communautés du dernier niveau, de la plus grande à
+// la plus petite, et la modularité du découpage
+func summarize(level *louvainLevel, membership []int, ids []string, resolution float64) ([]models.Community, float64) {
+	communities := make([]models.Community, len(level.arcs))
+	for v, c := range membership {
+		communities[c].Nodes = append(communities[c].Nodes, ids[v])
+	}
+
+	m2 := 0.0
+	for c := range level.arcs {
+		m2 += level.degree(c)
+	}
+	modularity := 0.0
+	for c := range communities {
+		communities[c].Size = len(communities[c].Nodes)
+		communities[c].InternalWeight = level.self[c] / 2
+		if m2 > 0 {
+			share := level.degree(c) / m2
+			communities[c].Modularity = level.self[c]/m2 - resolution*share*share
+			modularity += communities[c].Modularity
+		}
+	}
+
+	sort.SliceStable(communities, func(i, j int) bool { return communities[i].Size > communities[j].Size })
+	for i := range communities {
+		communities[i].ID = i
+	}
+	return communities, modularity
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+
+	"n4l-editor/models"
+)
+
+func communitiesOf(source string, resolution float64) models.CommunityReport {
+	parser := NewN4LParser()
+	graph := parser.ParseN4LToGraph(parser.ParseN4L(source).Notes)
+	return NewGraphAnalyzer().Communities(graph, resolution)
+}
+
+func groups(report models.CommunityReport) []string {
+	var got []string
+	for _, c := range report.Communities {
+		nodes := append([]string{}, c.Nodes...)
+		sort.Strings(nodes)
+		got = append(got, strings.Join(nodes, ","))
+	}
+	sort.Strings(got)
+	return got
+}
+
+func TestCommunities(t *testing.T) {
+	// Deux triangles reliés par un seul pont forment une seule composante
+	triangles := strings.Join([]string{
+		"A (connaît) B", "B (connaît) C", "C (connaît) A",
+		"D (connaît) E", "E (connaît) F", "F (connaît) D",
+		"C (connaît) D",
+	}, "\n")
+
+	report := communitiesOf(triangles, 0)
+	if got := groups(report); strings.Join(got, " ") != "A,B,C D,E,F" {
+		t.Fatalf("communautés %v", got)
+	}
+	// Q = 2 × (3/7 − (7/14)²)
+	if want := 6.0/7 - 0.5; math.Abs(report.Modularity-want) > 1e-9 {
+		t.Errorf("modularité %.4f, attendu %.4f", report.Modularity, want)
+	}
+	if report.Resolution != DefaultResolution || report.Communities[0].InternalWeight != 3 {
+		t.Errorf("rapport %+v", report)
+	}
+
+	// Une faible résolution fusionne, une forte sépare
+	if got := groups(communitiesOf(triangles, 0.05)); len(got) != 1 {
+		t.Errorf("résolution 0.05 : %v", got)
+	}
+	if got := groups(communitiesOf(triangles, 10)); len(got) < 3 {
+		t.Errorf("résolution 10 : %v", got)
+	}
+
+	// Le pont incertain ne suffit pas à fusionner ; l'isolé reste seul
+	parser := NewN4LParser()
+	graph := parser.ParseN4LToGraph(parser.ParseN4L(triangles + "\nB (a croisé) E [certitude=rumeur]").Notes)
+	graph.Nodes = append(graph.Nodes, models.Node{ID: "Seul", Label: "Seul"})
+	report = NewGraphAnalyzer().Communities(graph, 0)
+	if got := groups(report); strings.Join(got, " ") != "A,B,C D,E,F Seul" {
+		t.Errorf("communautés %v", got)
+	}
+
+	if report := communitiesOf("", 0); len(report.Communities) != 0 || report.Modularity != 0 {
+		t.Errorf("graphe vide : %+v", report)
+	}
+}
+
+func TestCommunitiesRingOfCliques(t *testing.T) {
+	// Huit cliques de cinq nœuds en anneau : chaque clique est une communauté
+	var lines []string
+	for c := 0; c < 8; c++ {
+		for i := 0; i < 5; i++ {
+			for j := i + 1; j < 5; j++ {
+				lines = append(lines, fmt.Sprintf("K%d.%d (connaît) K%d.%d", c, i, c, j))
+			}
+		}
+		lines = append(lines, fmt.Sprintf("K%d.0 (connaît) K%d.4", c, (c+1)%8))
+	}
+	report := communitiesOf(strings.Join(lines, "\n"), 0)
+	if len(report.Communities) != 8 {
+		t.Fatalf("%d communautés : %v", len(report.Communities), groups(report))
+	}
+	sum := 0.0
+	for _, c := range report.Communities {
+		prefix := strings.SplitN(c.Nodes[0], ".", 2)[0]
+		for _, node := range c.Nodes {
+			if !strings.HasPrefix(node, prefix+".") {
+				t.Errorf("communauté mêlée : %v", c.Nodes)
+			}
+		}
+		sum += c.Modularity
+	}
+	if math.Abs(sum-report.Modularity) > 1e-9 || report.Modularity < 0.7 {
+		t.Errorf("modularité %.3f (somme des contributions %.3f)", report.Modularity, sum)
+	}
+}
+
+func BenchmarkCommunities(b *testing.B) {
+	graph := benchmarkGraph(b)
+	ga := NewGraphAnalyzer()
+	for i := 0; i < b.N; i++ {
+		ga.Communities(graph, DefaultResolution)
+	}
+}
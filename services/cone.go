@@ -0,0 +1,153 @@
+package services
+
+import (
+	"fmt"
+
+	"n4l-editor/models"
+)
+
+// Sens de parcours d'un cône d'expansion
+const (
+	ConeOutgoing = "outgoing" // ce que le nœud entraîne
+	ConeIncoming = "incoming" // ce qui mène au nœud
+	ConeBoth     = "both"
+)
+
+// ConeQuery décrit un cône d'expansion autour d'un nœud
+type ConeQuery struct {
+	NodeID    string   `json:"nodeId"`
+	Depth     int      `json:"depth"`
+	Direction string   `json:"direction,omitempty"` // ConeBoth par défaut
+	Types     []string `json:"types,omitempty"`     // types d'arêtes admis, tous si vide
+	Labels    []string `json:"labels,omitempty"`    // relations admises, toutes si vide
+	Contexts  []string `json:"contexts,omitempty"`  // contextes admis, tous si vide
+	MaxNodes  int      `json:"maxNodes,omitempty"`  // nombre maximal de nœuds, 0 : sans limite
+}
+
+// ExpansionCone retourne les nœuds à au plus Depth arêtes de la racine, du
+// plus proche au plus éloigné, avec le chemin qui y mène, et les arêtes
+// admises qui les relient. Un événement n'ajoute pas de niveau : ses
+// participants sont voisins les uns des autres, comme pour une arête
+// binaire. Comme pour FindPaths, équivalences, rôles et relations
+// symétriques se parcourent dans les deux sens quel que soit Direction.
+func (ga *GraphAnalyzer) ExpansionCone(query ConeQuery, graphData models.GraphData) (models.ExpansionCone, error) {
+	if query.Depth < 0 || query.MaxNodes < 0 {
+		return models.ExpansionCone{}, fmt.Errorf("profondeur ou nombre de nœuds négatif : %d, %d", query.Depth, query.MaxNodes)
+	}
+	switch query.Direction {
+	case "":
+		query.Direction = ConeBoth
+	case ConeOutgoing, ConeIncoming, ConeBoth:
+	default:
+		return models.ExpansionCone{}, fmt.Errorf("sens de parcours inconnu %q (%s, %s ou %s)", query.Direction, ConeOutgoing, ConeIncoming, ConeBoth)
+	}
+	graph := NewGraph(graphData)
+	if !graph.HasNode(query.NodeID) {
+		return models.ExpansionCone{}, fmt.Errorf("nœud inconnu %q", query.NodeID)
+	}
+
+	admitted := ga.coneFilter(query)
+	// next retourne le voisin atteint depuis id par une arête admise
+	next := func(id string, edge models.Edge) (string, bool) {
+		if edge.From == edge.To || !admitted(edge) {
+			return "", false
+		}
+		both := query.Direction == ConeBoth || ga.bidirectional(edge)
+		switch {
+		case edge.From == id && (both || query.Direction == ConeOutgoing):
+			return edge.To, true
+		case edge.To == id && (both || query.Direction == ConeIncoming):
+			return edge.From, true
+		}
+		return "", false
+	}
+
+	cone := models.ExpansionCone{Root: query.NodeID, Nodes: []models.ConeNode{}, Edges: []models.Edge{}}
+	inCone := make(map[string]bool)
+	level := map[string]int{query.NodeID: 0}
+	parent := make(map[string]string)
+	// File à double entrée : un événement, atteint sans changer de niveau,
+	// passe devant les nœuds du niveau suivant
+	queue := []string{query.NodeID}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if inCone[current] {
+			continue
+		}
+		if query.MaxNodes > 0 && len(cone.Nodes) == query.MaxNodes {
+			cone.Truncated = true
+			break
+		}
+		inCone[current] = true
+		cone.Nodes = append(cone.Nodes, models.ConeNode{ID: current, Depth: level[current], Path: conePath(current, parent)})
+
+		if level[current] >= query.Depth {
+			continue
+		}
+		for _, edge := range graph.IncidentEdges(current) {
+			neighbor, ok := next(current, edge)
+			if !ok || inCone[neighbor] {
+				continue
+			}
+			l := level[current] + 1
+			if graph.IsEvent(neighbor) {
+				l = level[current]
+			}
+			if seen, ok := level[neighbor]; ok && seen <= l {
+				continue
+			}
+			level[neighbor] = l
+			parent[neighbor] = current
+			if l == level[current] {
+				queue = append([]string{neighbor}, queue...)
+			} else {
+				queue = append(queue, neighbor)
+			}
+		}
+	}
+
+	for _, edge := range graph.Edges() {
+		if inCone[edge.From] && inCone[edge.To] && admitted(edge) {
+			cone.Edges = append(cone.Edges, edge)
+		}
+	}
+	return cone, nil
+}
+
+// coneFilter retourne le filtre des arêtes admises par une requête
+func (ga *GraphAnalyzer) coneFilter(query ConeQuery) func(models.Edge) bool {
+	types := make(map[string]bool)
+	for _, t := range query.Types {
+		types[t] = true
+	}
+	labels := make(map[string]bool)
+	for _, label := range query.Labels {
+		labels[ga.relations.Canonical(label)] = true
+	}
+	contexts := make(map[string]bool)
+	for _, context := range query.Contexts {
+		contexts[context] = true
+	}
+	return func(edge models.Edge) bool {
+		return (len(types) == 0 || types[edge.Type]) &&
+			(len(labels) == 0 || labels[ga.relations.Canonical(edge.Label)]) &&
+			(len(contexts) == 0 || edgeInContexts(edge, contexts))
+	}
+}
+
+// conePath remonte de id à la racine du cône
+func conePath(id string, parent map[string]string) []string {
+	path := []string{id}
+	for {
+		p, ok := parent[id]
+		if !ok {
+			break
+		}
+		path = append(path, p)
+		id = p
+	}
+	reverseStrings(path)
+	return path
+}
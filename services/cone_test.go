@@ -0,0 +1,92 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpansionCone(t *testing.T) {
+	source := strings.Join([]string{
+		":: météo ::",
+		"Orage (mène à) Pluie",
+		"Pluie (mène à) Inondation",
+		"Inondation (mène à) Évacuation",
+		"Pluie (=) Averse",
+		"Préfet (ordonne) Évacuation",
+		"",
+		":: rumeurs ::",
+		"Voisin (mène à) Orage",
+	}, "\n")
+	parser := NewN4LParser()
+	graph := parser.ParseN4LToGraph(parser.ParseN4L(source).Notes)
+	ga := NewGraphAnalyzer()
+
+	for _, tt := range []struct {
+		name  string
+		query ConeQuery
+		want  string // nœuds:profondeur dans l'ordre du cône
+	}{
+		{"ce que la pluie entraîne", ConeQuery{NodeID: "Pluie", Depth: 5, Direction: ConeOutgoing},
+			"Pluie:0 Inondation:1 Averse:1 Évacuation:2"},
+		{"ce qui mène à la pluie", ConeQuery{NodeID: "Pluie", Depth: 5, Direction: ConeIncoming},
+			"Pluie:0 Orage:1 Averse:1 Voisin:2"},
+		{"les deux sens", ConeQuery{NodeID: "Pluie", Depth: 1},
+			"Pluie:0 Orage:1 Inondation:1 Averse:1"},
+		{"contextes", ConeQuery{NodeID: "Pluie", Depth: 5, Direction: ConeIncoming, Contexts: []string{"météo"}},
+			"Pluie:0 Orage:1 Averse:1"},
+		{"relations", ConeQuery{NodeID: "Évacuation", Depth: 5, Labels: []string{"mène à"}},
+			"Évacuation:0 Inondation:1 Pluie:2 Orage:3 Voisin:4"},
+		{"types", ConeQuery{NodeID: "Pluie", Depth: 5, Direction: ConeOutgoing, Types: []string{"equivalence"}},
+			"Pluie:0 Averse:1"},
+		{"budget", ConeQuery{NodeID: "Pluie", Depth: 5, Direction: ConeOutgoing, MaxNodes: 2},
+			"Pluie:0 Inondation:1"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cone, err := ga.ExpansionCone(tt.query, graph)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			inCone := make(map[string]bool)
+			for _, node := range cone.Nodes {
+				got = append(got, node.ID+":"+string(rune('0'+node.Depth)))
+				inCone[node.ID] = true
+				if len(node.Path) != node.Depth+1 || node.Path[0] != tt.query.NodeID || node.Path[len(node.Path)-1] != node.ID {
+					t.Errorf("chemin de %s : %v", node.ID, node.Path)
+				}
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("cône %v, attendu %s", got, tt.want)
+			}
+			for _, edge := range cone.Edges {
+				if !inCone[edge.From] || !inCone[edge.To] {
+					t.Errorf("arête hors du cône : %+v", edge)
+				}
+			}
+			if cone.Truncated != (tt.query.MaxNodes > 0) {
+				t.Errorf("tronqué : %v", cone.Truncated)
+			}
+		})
+	}
+
+	cone, _ := ga.ExpansionCone(ConeQuery{NodeID: "Pluie", Depth: 5, Direction: ConeOutgoing}, graph)
+	if path := cone.Nodes[3].Path; !reflect.DeepEqual(path, []string{"Pluie", "Inondation", "Évacuation"}) {
+		t.Errorf("chemin de Évacuation : %v", path)
+	}
+	// Préfet n'est pas dans le cône : son arête n'y est pas
+	if len(cone.Edges) != 3 {
+		t.Errorf("%d arêtes, attendu 3 : %+v", len(cone.Edges), cone.Edges)
+	}
+
+	for _, query := range []ConeQuery{
+		{NodeID: "Inconnu", Depth: 1},
+		{NodeID: "Pluie", Depth: 1, Direction: "up"},
+		{NodeID: "Pluie", Depth: -1},
+		{NodeID: "Pluie", Depth: 1, MaxNodes: -1},
+	} {
+		if _, err := ga.ExpansionCone(query, graph); err == nil {
+			t.Errorf("%+v : erreur attendue", query)
+		}
+	}
+}
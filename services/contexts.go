@@ -0,0 +1,150 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"n4l-editor/models"
+)
+
+// Séparateurs utilisés dans les clés de contexte : "Enquête > Témoins | Preuves"
+// désigne le sous-contexte "Témoins" de "Enquête" et le contexte "Preuves"
+const (
+	ContextPathSeparator = " > "
+	ContextListSeparator = " | "
+)
+
+// ContextPaths retourne les chemins désignés par une clé de contexte,
+// ancêtres compris, dans l'ordre d'apparition
+func ContextPaths(key string) []string {
+	var paths []string
+	seen := make(map[string]bool)
+
+	for _, entry := range strings.Split(key, "|") {
+		var path []string
+		for _, segment := range strings.Split(entry, ">") {
+			if segment = strings.TrimSpace(segment); segment == "" {
+				continue
+			}
+			path = append(path, segment)
+			full := strings.Join(path, ContextPathSeparator)
+			if !seen[full] {
+				seen[full] = true
+				paths = append(paths, full)
+			}
+		}
+	}
+	return paths
+}
+
+// NodeContexts retourne les chemins de contexte d'un nœud, en se rabattant
+// sur son contexte principal pour les graphes produits avant la hiérarchie
+func NodeContexts(node models.Node) []string {
+	if len(node.Contexts) > 0 {
+		return node.Contexts
+	}
+	return ContextPaths(node.Context)
+}
+
+// EdgeContexts retourne les chemins de contexte d'une arête
+func EdgeContexts(edge models.Edge) []string {
+	if len(edge.Contexts) > 0 {
+		return edge.Contexts
+	}
+	return ContextPaths(edge.Context)
+}
+
+// mergeContexts ajoute à une liste de chemins ceux qu'elle ne contient pas encore
+func mergeContexts(current, paths []string) []string {
+	for _, path := range paths {
+		if !containsString(current, path) {
+			current = append(current, path)
+		}
+	}
+	return current
+}
+
+// BuildContextTree construit la hiérarchie des contextes à partir de leurs clés
+func BuildContextTree(keys []string) []models.ContextNode {
+	var roots []models.ContextNode
+
+	var paths []string
+	for _, key := range keys {
+		paths = mergeContexts(paths, ContextPaths(key))
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		segments := strings.Split(path, ContextPathSeparator)
+		level := &roots
+		for i, segment := range segments {
+			idx := -1
+			for j := range *level {
+				if (*level)[j].Name == segment {
+					idx = j
+					break
+				}
+			}
+			if idx < 0 {
+				*level = append(*level, models.ContextNode{
+					Name: segment,
+					Path: strings.Join(segments[:i+1], ContextPathSeparator),
+				})
+				idx = len(*level) - 1
+			}
+			level = &(*level)[idx].Children
+		}
+	}
+	return roots
+}
+
+// FilterGraphByContext restreint un graphe aux nœuds et arêtes d'un contexte
+// (sous-contextes inclus). Un contexte vide laisse le graphe inchangé.
+func FilterGraphByContext(graph models.GraphData, context string) models.GraphData {
+	paths := ContextPaths(context)
+	if len(paths) == 0 {
+		return graph
+	}
+	context = paths[len(paths)-1]
+
+	filtered := models.GraphData{
+		Nodes: []models.Node{},
+		Edges: []models.Edge{},
+	}
+	kept := make(map[string]bool)
+
+	for _, node := range graph.Nodes {
+		if containsString(NodeContexts(node), context) {
+			kept[node.ID] = true
+			filtered.Nodes = append(filtered.Nodes, node)
+		}
+	}
+
+	for _, edge := range graph.Edges {
+		if kept[edge.From] && kept[edge.To] && containsString(EdgeContexts(edge), context) {
+			filtered.Edges = append(filtered.Edges, edge)
+		}
+	}
+
+	filtered.Hyperedges = buildHyperedges(filtered)
+
+	if graph.Positions != nil {
+		filtered.Positions = make(map[string]models.Position)
+		for id, pos := range graph.Positions {
+			if kept[id] {
+				filtered.Positions[id] = pos
+			}
+		}
+	}
+
+	return filtered
+}
+
+func containsString(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
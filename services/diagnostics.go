@@ -0,0 +1,102 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"n4l-editor/models"
+)
+
+// Codes des diagnostics émis par le parser N4L
+const (
+	DiagUnstructuredNote      = "N4L001" // ligne sans relation reconnue
+	DiagIncompleteRelation    = "N4L002" // relation à laquelle il manque une source ou une cible
+	DiagDanglingContinuation  = "N4L003" // guillemet de continuation sans sujet précédent
+	DiagUnresolvedReference   = "N4L004" // référence $nom.N impossible à résoudre
+	DiagMalformedGroup        = "N4L005" // groupe "Parent => { ... }" mal formé
+	DiagEmptyContext          = "N4L006" // en-tête de contexte sans nom
+	DiagUnbalancedParentheses = "N4L007" // parenthèses non appariées
+	DiagDuplicateVariable     = "N4L008" // variable @nom définie plusieurs fois
+	DiagEmptyVariable         = "N4L009" // variable @nom sans contenu
+	DiagEmptyInclude          = "N4L010" // directive @include sans chemin
+	DiagMissingInclude        = "N4L011" // fichier inclus introuvable
+	DiagIncludeCycle          = "N4L012" // inclusion circulaire
+	DiagInvalidQualifier      = "N4L013" // qualificatif [clé=valeur] invalide
+	DiagIgnoredInclude        = "N4L014" // @include dans un fichier parsé seul
+)
+
+// Niveaux de sévérité des diagnostics
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// HasErrors indique si une liste de diagnostics contient au moins une erreur
+func HasErrors(diagnostics []models.Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// newDiagnostic construit un diagnostic positionné
+func newDiagnostic(severity, code string, pos models.SourcePos, length int, format string, args ...interface{}) models.Diagnostic {
+	return models.Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+		Pos:      pos,
+		Length:   length,
+	}
+}
+
+// lineLocator retrouve la position des fragments d'une ligne dans le source brut.
+// Les recherches avancent dans la ligne pour que source, relation et cible
+// soient localisées dans l'ordre où elles apparaissent.
+type lineLocator struct {
+	line   int
+	raw    string
+	offset int
+}
+
+func newLineLocator(line int, raw string) *lineLocator {
+	return &lineLocator{line: line, raw: raw}
+}
+
+// start retourne la position du premier caractère non blanc de la ligne
+func (l *lineLocator) start() models.SourcePos {
+	idx := strings.IndexFunc(l.raw, func(r rune) bool { return !unicode.IsSpace(r) })
+	if idx < 0 {
+		idx = 0
+	}
+	return l.posAt(idx)
+}
+
+// locate retourne la position du fragment, ou le début de ligne s'il est introuvable
+func (l *lineLocator) locate(fragment string) models.SourcePos {
+	if fragment != "" {
+		if idx := strings.Index(l.raw[l.offset:], fragment); idx >= 0 {
+			start := l.offset + idx
+			l.offset = start + len(fragment)
+			return l.posAt(start)
+		}
+	}
+	return l.start()
+}
+
+// width retourne la largeur de la ligne significative en caractères
+func (l *lineLocator) width() int {
+	return utf8.RuneCountInString(strings.TrimSpace(l.raw))
+}
+
+func (l *lineLocator) posAt(byteOffset int) models.SourcePos {
+	return models.SourcePos{
+		Line:   l.line,
+		Column: utf8.RuneCountInString(l.raw[:byteOffset]) + 1,
+	}
+}
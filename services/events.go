@@ -0,0 +1,94 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	"n4l-editor/models"
+)
+
+// EventKind est le genre des nœuds qui représentent un événement n-aire
+const EventKind = "event"
+
+// Un membre d'événement s'écrit "rôle: participant" ; le rôle est un mot et
+// les deux-points sont suivis d'un espace, pour ne pas confondre "14:30"
+var roleMemberRegex = regexp.MustCompile(`^(\p{L}[\p{L}\p{N}_-]*)\s*:\s+(\S.*)$`)
+
+// eventRoleFields associe les rôles usuels aux champs d'un événement de la
+// chronologie
+var eventRoleFields = map[string]string{
+	"agent":        "actor",
+	"auteur":       "actor",
+	"sujet":        "actor",
+	"qui":          "actor",
+	"objet":        "target",
+	"cible":        "target",
+	"destinataire": "target",
+	"victime":      "target",
+	"lieu":         "location",
+	"où":           "location",
+}
+
+// splitRoles lit les membres "rôle: participant" d'un groupe ; ok est faux
+// dès qu'un membre n'a pas de rôle, le groupe restant alors un groupe simple
+func splitRoles(members []string) (roles, participants []string, ok bool) {
+	if len(members) == 0 {
+		return nil, nil, false
+	}
+	for _, member := range members {
+		matches := roleMemberRegex.FindStringSubmatch(member)
+		if matches == nil {
+			return nil, nil, false
+		}
+		participant := strings.Trim(strings.TrimSpace(matches[2]), `"`)
+		if participant == "" {
+			return nil, nil, false
+		}
+		roles = append(roles, strings.ToLower(matches[1]))
+		participants = append(participants, participant)
+	}
+	return roles, participants, true
+}
+
+// roleLabel retourne le libellé de l'arête d'un rôle : le nom canonique d'une
+// propriété typée ("date"), qui devient un attribut de l'événement, ou le
+// rôle tel quel
+func (p *N4LParser) roleLabel(role string) string {
+	if t, ok := p.relations.Lookup(role); ok && t.ValueType != "" {
+		return t.Name
+	}
+	return role
+}
+
+// buildHyperedges rassemble chaque nœud événement et ses arêtes de rôle
+func buildHyperedges(graph models.GraphData) []models.Hyperedge {
+	var hyperedges []models.Hyperedge
+	index := make(map[string]int)
+	for _, node := range graph.Nodes {
+		if node.Kind != EventKind {
+			continue
+		}
+		hyperedge := models.Hyperedge{
+			ID:           node.ID,
+			Label:        node.Label,
+			Context:      node.Context,
+			Participants: []models.Participant{},
+			Spans:        node.Spans,
+		}
+		for _, property := range node.Properties {
+			if property.Date != nil && hyperedge.Date == nil {
+				hyperedge.Date = property.Date
+			}
+		}
+		index[node.ID] = len(hyperedges)
+		hyperedges = append(hyperedges, hyperedge)
+	}
+
+	for _, edge := range graph.Edges {
+		if i, ok := index[edge.From]; ok && edge.Type == "role" {
+			hyperedges[i].Participants = append(hyperedges[i].Participants,
+				models.Participant{Role: edge.Label, Node: edge.To, Edge: edge.ID})
+		}
+	}
+	return hyperedges
+}
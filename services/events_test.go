@@ -0,0 +1,104 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const eventSource = `:: Enquête ::
+Remise de la lettre => { agent: Jean; objet: lettre; destinataire: Élodie; lieu: manoir; date: 27/08/2025 }
+Élodie (connaît) Victor
+Horaires => { matin; soir }`
+
+func TestHyperedges(t *testing.T) {
+	parser := NewN4LParser()
+	parsed := parser.ParseN4L(eventSource)
+	graph := parser.ParseN4LToGraph(parsed.Notes)
+
+	stmt := parsed.Document.Contexts[0].Statements[0]
+	if stmt.Kind != "event" || stmt.Group.Members[2].Role != "destinataire" || stmt.Group.Members[2].Text != "Élodie" {
+		t.Errorf("instruction d'événement mal analysée : %+v", stmt)
+	}
+	if kind := parsed.Document.Contexts[0].Statements[2].Kind; kind != "group" {
+		t.Errorf("un groupe sans rôles doit rester un groupe, obtenu %q", kind)
+	}
+
+	if len(graph.Hyperedges) != 1 {
+		t.Fatalf("1 événement attendu, obtenu %+v", graph.Hyperedges)
+	}
+	event := graph.Hyperedges[0]
+	var roles []string
+	for _, participant := range event.Participants {
+		roles = append(roles, participant.Role+"="+participant.Node)
+		if participant.Edge == "" {
+			t.Errorf("participant sans arête : %+v", participant)
+		}
+	}
+	if want := []string{"agent=Jean", "objet=lettre", "destinataire=Élodie", "lieu=manoir"}; !reflect.DeepEqual(roles, want) {
+		t.Errorf("participants %v, attendu %v", roles, want)
+	}
+	if event.Date == nil || event.Date.Format("2006-01-02") != "2025-08-27" {
+		t.Errorf("date de l'événement : %v", event.Date)
+	}
+	for _, node := range graph.Nodes {
+		if (node.Kind == EventKind) != (node.ID == "Remise de la lettre") {
+			t.Errorf("genre du nœud %s : %q", node.ID, node.Kind)
+		}
+		if node.ID == "27/08/2025" {
+			t.Errorf("la date doit rester un attribut de l'événement")
+		}
+	}
+
+	filtered := FilterGraphByContext(graph, "Enquête")
+	if len(filtered.Hyperedges) != 1 || len(filtered.Hyperedges[0].Participants) != 4 {
+		t.Errorf("événement perdu par le filtre de contexte : %+v", filtered.Hyperedges)
+	}
+}
+
+func TestHyperedgeAnalysis(t *testing.T) {
+	parser := NewN4LParser()
+	parsed := parser.ParseN4L(eventSource)
+	graph := parser.ParseN4LToGraph(parsed.Notes)
+	ga := NewGraphAnalyzer()
+
+	// L'événement ne compte pas comme un niveau du cône
+	cone, err := ga.ExpansionCone(ConeQuery{NodeID: "Jean", Depth: 1}, graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := make(map[string]bool)
+	for _, node := range cone.Nodes {
+		nodes[node.ID] = true
+	}
+	for _, id := range []string{"Jean", "Remise de la lettre", "Élodie", "manoir"} {
+		if !nodes[id] {
+			t.Errorf("%s absent du cône de profondeur 1 : %v", id, nodes)
+		}
+	}
+	if nodes["Victor"] {
+		t.Errorf("Victor est à distance 2 de Jean : %v", nodes)
+	}
+
+	path, cost := ga.findPath("Jean", "Victor", ga.buildAdjacencyListFromGraphData(graph))
+	if want := []string{"Jean", "Remise de la lettre", "Élodie", "Victor"}; !reflect.DeepEqual(path, want) || cost != 2 {
+		t.Errorf("chemin %v (coût %g), attendu %v (coût 2)", path, cost, want)
+	}
+
+	var found bool
+	for _, event := range ga.GetTimelineEvents(parsed.Notes) {
+		if event.Action != "Remise de la lettre" {
+			continue
+		}
+		found = true
+		if event.Actor != "Jean" || event.Target != "lettre, Élodie" || event.Location != "manoir" || event.DateTime == nil {
+			t.Errorf("événement de chronologie incomplet : %+v", event)
+		}
+	}
+	if !found {
+		t.Error("l'événement n-aire est absent de la chronologie")
+	}
+	if text := NewN4LSerializer().Serialize(graph); !strings.Contains(text, "Remise de la lettre => { agent: Jean;") {
+		t.Errorf("événement mal sérialisé :\n%s", text)
+	}
+}
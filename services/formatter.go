@@ -0,0 +1,171 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// N4LFormatter normalise la mise en page d'un fichier N4L : en-têtes de
+// contexte, indentation, espacement des relations et alignement des
+// continuations '"'. Le contenu sémantique du document n'est jamais modifié.
+type N4LFormatter struct {
+	parser         *N4LParser
+	spaceRegex     *regexp.Regexp
+	arrowRegex     *regexp.Regexp
+	groupRegex     *regexp.Regexp
+	openRegex      *regexp.Regexp
+	closeRegex     *regexp.Regexp
+	separatorRegex *regexp.Regexp
+}
+
+// NewN4LFormatter crée une nouvelle instance du formateur
+func NewN4LFormatter() *N4LFormatter {
+	return &N4LFormatter{
+		parser:         NewN4LParser(),
+		spaceRegex:     regexp.MustCompile(`\s+`),
+		arrowRegex:     regexp.MustCompile(`\s*(<->|->|=>)\s*`),
+		groupRegex:     regexp.MustCompile(`\{\s*(.*?)\s*\}`),
+		openRegex:      regexp.MustCompile(`\s*\(\s*`),
+		closeRegex:     regexp.MustCompile(`\s*\)\s*`),
+		separatorRegex: regexp.MustCompile(`\s*;\s*`),
+	}
+}
+
+// formatIndent est l'indentation des instructions sous un en-tête
+const formatIndent = "    "
+
+// Format retourne le document reformaté. Une erreur est renvoyée si le
+// résultat ne produit pas exactement le même graphe que l'original.
+func (f *N4LFormatter) Format(content string) (string, error) {
+	doc, _ := f.parser.ParseDocument(content)
+	kinds := make(map[int]string)
+	for _, context := range doc.Contexts {
+		for _, stmt := range context.Statements {
+			kinds[stmt.Pos.Line] = stmt.Kind
+		}
+	}
+
+	var out []string
+	blank := false
+	anchor := -1 // colonne de la première parenthèse de la dernière relation
+
+	emit := func(line string) {
+		if blank && len(out) > 0 {
+			out = append(out, "")
+		}
+		blank = false
+		out = append(out, line)
+	}
+
+	for i, raw := range strings.Split(content, "\n") {
+		line := strings.TrimSpace(raw)
+
+		switch {
+		case line == "":
+			blank = true
+
+		case strings.HasPrefix(line, "+::") || strings.HasPrefix(line, "-::"):
+			emit(line)
+
+		case f.parser.contextRegex.MatchString(line):
+			matches := f.parser.contextRegex.FindStringSubmatch(line)
+			var names []string
+			for _, name := range strings.Split(matches[2], "|") {
+				if name = strings.TrimSpace(name); name != "" {
+					names = append(names, f.spaceRegex.ReplaceAllString(name, " "))
+				}
+			}
+			colons := matches[1]
+			blank = true
+			emit(colons + " " + strings.Join(names, ContextListSeparator) + " " + colons)
+			blank = true
+			anchor = -1
+
+		case strings.HasPrefix(line, "#"):
+			emit(formatIndent + line)
+
+		default:
+			kind := kinds[i+1]
+			if kind != "" && kind != "note" {
+				line = f.normalizeStatement(line)
+			}
+
+			indent := formatIndent
+			if strings.HasPrefix(line, `"`) && kind == "relation" && anchor > 2 {
+				// Aligner la parenthèse de la continuation sur celle de la relation précédente
+				indent += strings.Repeat(" ", anchor-2)
+			} else if kind == "relation" {
+				anchor = -1
+				if idx := strings.Index(line, "("); idx > 0 && !strings.Contains(line, " -> ") {
+					anchor = utf8.RuneCountInString(line[:idx])
+				}
+			}
+			emit(indent + line)
+		}
+	}
+
+	formatted := strings.Join(out, "\n")
+	if formatted != "" {
+		formatted += "\n"
+	}
+
+	// Garde-fou : le formatage ne doit jamais changer le graphe du document
+	before := f.parser.ParseN4LToGraph(f.parser.ParseN4L(content).Notes)
+	after := f.parser.ParseN4LToGraph(f.parser.ParseN4L(formatted).Notes)
+	if !reflect.DeepEqual(before, after) {
+		return "", fmt.Errorf("le formatage modifierait le contenu du document")
+	}
+	return formatted, nil
+}
+
+// normalizeStatement normalise l'espacement d'une instruction structurée,
+// sans toucher aux chaînes entre guillemets
+func (f *N4LFormatter) normalizeStatement(line string) string {
+	var b strings.Builder
+	for i, segment := range splitQuoted(line) {
+		if i%2 == 1 {
+			b.WriteString(segment)
+			continue
+		}
+		segment = f.spaceRegex.ReplaceAllString(segment, " ")
+		segment = f.arrowRegex.ReplaceAllString(segment, " $1 ")
+		segment = f.groupRegex.ReplaceAllStringFunc(segment, func(group string) string {
+			inner := f.groupRegex.FindStringSubmatch(group)[1]
+			return "{ " + f.separatorRegex.ReplaceAllString(inner, "; ") + " }"
+		})
+		if !strings.Contains(line, "->") {
+			segment = f.openRegex.ReplaceAllString(segment, " (")
+			segment = f.closeRegex.ReplaceAllString(segment, ") ")
+		}
+		b.WriteString(f.spaceRegex.ReplaceAllString(segment, " "))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// splitQuoted découpe une ligne en alternant texte libre (indices pairs) et
+// chaînes "..." (indices impairs, guillemets compris). Un guillemet sans
+// fermeture, comme la continuation '"', reste dans le texte libre.
+func splitQuoted(line string) []string {
+	var segments []string
+	free, i := 0, 0
+	if strings.HasPrefix(line, `" `) {
+		i = 1
+	}
+	for ; i < len(line); i++ {
+		if line[i] != '"' {
+			continue
+		}
+		end := strings.IndexByte(line[i+1:], '"')
+		if end < 0 {
+			break
+		}
+		end += i + 2
+		segments = append(segments, line[free:i], line[i:end])
+		free = end
+		i = end - 1
+	}
+	return append(segments, line[free:])
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatNormalizesLayout(t *testing.T) {
+	source := strings.Join([]string{
+		"::Preuves|Chronologie::",
+		"Alice   (connaît)Bob",
+		`"(aime)   Carl`,
+		"X ->  ami de  -> Y",
+		":::  Témoins :::",
+		"",
+		"",
+		"G => {a;b ;c}",
+		`@goal   "améliorer  l'enquête"`,
+	}, "\n")
+
+	want := strings.Join([]string{
+		":: Preuves | Chronologie ::",
+		"",
+		"    Alice (connaît) Bob",
+		`        " (aime) Carl`,
+		"    X -> ami de -> Y",
+		"",
+		"::: Témoins :::",
+		"",
+		"    G => { a; b; c }",
+		`    @goal "améliorer  l'enquête"`,
+		"",
+	}, "\n")
+
+	formatter := NewN4LFormatter()
+	got, err := formatter.Format(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("formatage inattendu :\n%s\nattendu :\n%s", got, want)
+	}
+
+	again, err := formatter.Format(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != got {
+		t.Errorf("formatage non idempotent :\n%s", again)
+	}
+}
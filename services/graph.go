@@ -0,0 +1,156 @@
+package services
+
+import "n4l-editor/models"
+
+// Graph est une vue indexée, en lecture seule, d'un models.GraphData :
+// construite une fois en O(N+E), elle répond aux questions de voisinage sans
+// reparcourir toutes les arêtes. Les arêtes d'un nœud sont rangées dans
+// l'ordre du graphe source, ce qui garde les parcours déterministes.
+type Graph struct {
+	data      models.GraphData
+	nodes     map[string]int     // identifiant → indice dans data.Nodes
+	out       map[string][]int32 // indices dans data.Edges
+	in        map[string][]int32
+	incident  map[string][]int32 // une boucle n'y figure qu'une fois
+	neighbors map[string][]string
+	byLabel   map[string][]int32
+	events    map[string]bool
+}
+
+// NewGraph indexe un graphe
+func NewGraph(data models.GraphData) *Graph {
+	g := &Graph{
+		data:      data,
+		nodes:     make(map[string]int, len(data.Nodes)),
+		out:       make(map[string][]int32),
+		in:        make(map[string][]int32),
+		incident:  make(map[string][]int32),
+		neighbors: make(map[string][]string),
+		byLabel:   make(map[string][]int32),
+		events:    make(map[string]bool),
+	}
+	for i, node := range data.Nodes {
+		if _, seen := g.nodes[node.ID]; !seen {
+			g.nodes[node.ID] = i
+		}
+	}
+
+	for i := range data.Edges {
+		edge := &data.Edges[i]
+		g.out[edge.From] = append(g.out[edge.From], int32(i))
+		g.in[edge.To] = append(g.in[edge.To], int32(i))
+		g.incident[edge.From] = append(g.incident[edge.From], int32(i))
+		if edge.To != edge.From {
+			g.incident[edge.To] = append(g.incident[edge.To], int32(i))
+		}
+		g.byLabel[edge.Label] = append(g.byLabel[edge.Label], int32(i))
+		if edge.Type == "role" {
+			g.events[edge.From] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	for id, edges := range g.incident {
+		var neighbors []string
+		for _, i := range edges {
+			other := data.Edges[i].To
+			if other == id {
+				other = data.Edges[i].From
+			}
+			if other != id && !seen[other] {
+				seen[other] = true
+				neighbors = append(neighbors, other)
+			}
+		}
+		if neighbors != nil {
+			g.neighbors[id] = neighbors
+		}
+		clear(seen)
+	}
+	return g
+}
+
+// edges retourne les arêtes d'indices donnés
+func (g *Graph) edges(indices []int32) []models.Edge {
+	if len(indices) == 0 {
+		return nil
+	}
+	edges := make([]models.Edge, len(indices))
+	for j, i := range indices {
+		edges[j] = g.data.Edges[i]
+	}
+	return edges
+}
+
+// Data retourne le graphe indexé
+func (g *Graph) Data() models.GraphData { return g.data }
+
+// Nodes retourne les nœuds dans l'ordre du graphe
+func (g *Graph) Nodes() []models.Node { return g.data.Nodes }
+
+// Edges retourne les arêtes dans l'ordre du graphe
+func (g *Graph) Edges() []models.Edge { return g.data.Edges }
+
+// Node retourne le nœud d'identifiant id
+func (g *Graph) Node(id string) (models.Node, bool) {
+	i, ok := g.nodes[id]
+	if !ok {
+		return models.Node{}, false
+	}
+	return g.data.Nodes[i], true
+}
+
+// HasNode indique si id est un nœud du graphe
+func (g *Graph) HasNode(id string) bool {
+	_, ok := g.nodes[id]
+	return ok
+}
+
+// OutEdges retourne les arêtes qui partent de id
+func (g *Graph) OutEdges(id string) []models.Edge { return g.edges(g.out[id]) }
+
+// InEdges retourne les arêtes qui arrivent à id
+func (g *Graph) InEdges(id string) []models.Edge { return g.edges(g.in[id]) }
+
+// IncidentEdges retourne les arêtes qui touchent id, dans un sens ou l'autre
+func (g *Graph) IncidentEdges(id string) []models.Edge { return g.edges(g.incident[id]) }
+
+// Neighbors retourne les voisins distincts de id, sans tenir compte du sens
+// des arêtes ni des boucles
+func (g *Graph) Neighbors(id string) []string { return g.neighbors[id] }
+
+// Degree retourne le nombre d'arêtes qui touchent id
+func (g *Graph) Degree(id string) int { return len(g.incident[id]) }
+
+// InDegree retourne le nombre d'arêtes qui arrivent à id
+func (g *Graph) InDegree(id string) int { return len(g.in[id]) }
+
+// OutDegree retourne le nombre d'arêtes qui partent de id
+func (g *Graph) OutDegree(id string) int { return len(g.out[id]) }
+
+// EdgesByLabel retourne les arêtes d'étiquette label
+func (g *Graph) EdgesByLabel(label string) []models.Edge { return g.edges(g.byLabel[label]) }
+
+// IsEvent indique si id est le nœud d'un événement
+func (g *Graph) IsEvent(id string) bool { return g.events[id] }
+
+// Connected indique si une arête relie a et b, dans un sens ou l'autre
+func (g *Graph) Connected(a, b string) bool {
+	edges, other := g.incident[a], b
+	if len(g.incident[b]) < len(edges) {
+		edges, other = g.incident[b], a
+	}
+	for _, i := range edges {
+		if g.data.Edges[i].From == other || g.data.Edges[i].To == other {
+			return true
+		}
+	}
+	return false
+}
+
+// Support retourne la certitude moyenne, pondérée par les poids, des arêtes
+// de id et leur nombre : un nœud qui ne repose que sur des hypothèses est
+// moins étayé qu'un nœud relié par des faits établis
+func (g *Graph) Support(id string) (float64, int) {
+	return edgeSupport(g.IncidentEdges(id))
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"container/heap"
 	"fmt"
 	"regexp"
 	"sort"
@@ -13,6 +14,7 @@ import (
 
 // FindClustersAndPaths trouve les clusters de nœuds correspondant aux termes de recherche et les chemins les reliant
 func (ga *GraphAnalyzer) FindClustersAndPaths(terms []string, graphData models.GraphData) (map[string][]string, [][]string) {
+	graph := NewGraph(graphData)
 	matchingNodes := make(map[string]bool)
 	for _, term := range terms {
 		lowerTerm := strings.ToLower(term)
@@ -31,7 +33,7 @@ func (ga *GraphAnalyzer) FindClustersAndPaths(terms []string, graphData models.G
 		if !visited[nodeID] {
 			clusterID := fmt.Sprintf("cluster-%d", clusterIndex)
 			var currentCluster []string
-			ga.findConnectedComponent(nodeID, matchingNodes, graphData, visited, &currentCluster)
+			ga.findConnectedComponent(nodeID, matchingNodes, graph, visited, &currentCluster)
 			if len(currentCluster) > 0 {
 				clusters[clusterID] = currentCluster
 				clusterIndex++
@@ -62,7 +64,7 @@ func (ga *GraphAnalyzer) FindClustersAndPaths(terms []string, graphData models.G
 }
 
 // findConnectedComponent est une aide pour trouver un groupe de nœuds connectés (cluster) via DFS
-func (ga *GraphAnalyzer) findConnectedComponent(startNode string, nodeSet map[string]bool, graphData models.GraphData, visited map[string]bool, component *[]string) {
+func (ga *GraphAnalyzer) findConnectedComponent(startNode string, nodeSet map[string]bool, graph *Graph, visited map[string]bool, component *[]string) {
 	stack := []string{startNode}
 	visited[startNode] = true
 
@@ -74,15 +76,8 @@ func (ga *GraphAnalyzer) findConnectedComponent(startNode string, nodeSet map[st
 			*component = append(*component, nodeID)
 		}
 
-		for _, edge := range graphData.Edges {
-			var neighbor string
-			if edge.From == nodeID {
-				neighbor = edge.To
-			} else if edge.To == nodeID {
-				neighbor = edge.From
-			}
-
-			if neighbor != "" && !visited[neighbor] && nodeSet[neighbor] {
+		for _, neighbor := range graph.Neighbors(nodeID) {
+			if !visited[neighbor] && nodeSet[neighbor] {
 				visited[neighbor] = true
 				stack = append(stack, neighbor)
 			}
@@ -90,27 +85,52 @@ func (ga *GraphAnalyzer) findConnectedComponent(startNode string, nodeSet map[st
 	}
 }
 
+// adjacency associe à chaque nœud ses voisins et le coût du passage : une
+// arête certaine coûte 1, une hypothèse davantage (voir edgeCost)
+type adjacency map[string][]neighbor
+
+type neighbor struct {
+	id   string
+	cost float64
+}
+
+// add ajoute une arête, parcourable dans les deux sens
+func (adj adjacency) add(edge models.Edge) {
+	cost := edgeCost(edge)
+	adj[edge.From] = append(adj[edge.From], neighbor{edge.To, cost})
+	adj[edge.To] = append(adj[edge.To], neighbor{edge.From, cost})
+}
+
+// edgeCost est l'inverse de la force d'une arête : un chemin de deux faits
+// établis est préféré à un raccourci par une rumeur. Une arête de rôle coûte
+// moitié moins : deux participants d'un même événement sont à distance 1,
+// comme s'ils étaient reliés directement.
+func edgeCost(edge models.Edge) float64 {
+	if edge.Type == "role" {
+		return 0.5 / EdgeStrength(edge)
+	}
+	return 1 / EdgeStrength(edge)
+}
+
 // buildAdjacencyListFromGraphData crée une liste d'adjacence à partir de GraphData
-func (ga *GraphAnalyzer) buildAdjacencyListFromGraphData(graphData models.GraphData) map[string][]string {
-	adj := make(map[string][]string)
+func (ga *GraphAnalyzer) buildAdjacencyListFromGraphData(graphData models.GraphData) adjacency {
+	adj := make(adjacency)
 	for _, edge := range graphData.Edges {
-		adj[edge.From] = append(adj[edge.From], edge.To)
-		adj[edge.To] = append(adj[edge.To], edge.From) // Pour un graphe non dirigé
+		adj.add(edge)
 	}
 	return adj
 }
 
-// findShortestPathBetweenClusters trouve le chemin le plus court entre deux groupes de nœuds
-func (ga *GraphAnalyzer) findShortestPathBetweenClusters(cluster1, cluster2 []string, adj map[string][]string) []string {
+// findShortestPathBetweenClusters trouve le chemin le plus sûr entre deux groupes de nœuds
+func (ga *GraphAnalyzer) findShortestPathBetweenClusters(cluster1, cluster2 []string, adj adjacency) []string {
 	var shortestPath []string
+	bestCost := 0.0
 
 	for _, startNode := range cluster1 {
 		for _, endNode := range cluster2 {
-			path := ga.findPath(startNode, endNode, adj) // Utilise BFS, donc trouve le plus court chemin
-			if path != nil {
-				if shortestPath == nil || len(path) < len(shortestPath) {
-					shortestPath = path
-				}
+			path, cost := ga.findPath(startNode, endNode, adj)
+			if path != nil && (shortestPath == nil || cost < bestCost) {
+				shortestPath, bestCost = path, cost
 			}
 		}
 	}
@@ -118,67 +138,20 @@ func (ga *GraphAnalyzer) findShortestPathBetweenClusters(cluster1, cluster2 []st
 }
 
 // GraphAnalyzer fournit des méthodes d'analyse de graphe
-type GraphAnalyzer struct{}
+type GraphAnalyzer struct {
+	relations *RelationRegistry
+	schema    *Schema // nil : pas de contraintes d'ontologie
+}
 
 // NewGraphAnalyzer crée une nouvelle instance de GraphAnalyzer
 func NewGraphAnalyzer() *GraphAnalyzer {
-	return &GraphAnalyzer{}
-}
-
-// GetExpansionCone calcule le cône d'expansion à partir d'un nœud donné
-func (ga *GraphAnalyzer) GetExpansionCone(nodeID string, depth int, graphData models.GraphData) (map[string]bool, []models.Edge) {
-	nodeIDsInCone := make(map[string]bool)
-	var edgesInCone []models.Edge
-
-	queue := []struct {
-		ID    string
-		Level int
-	}{{ID: nodeID, Level: 0}}
-
-	// visited keeps track of nodes we've processed to avoid infinite loops
-	visited := make(map[string]bool)
-	visited[nodeID] = true
-
-	// First, find all nodes in the cone
-	for len(queue) > 0 {
-		current := queue[0]
-		queue = queue[1:]
-
-		nodeIDsInCone[current.ID] = true
-
-		if current.Level >= depth {
-			continue
-		}
-
-		for _, edge := range graphData.Edges {
-			var neighbor string
-			if edge.From == current.ID {
-				neighbor = edge.To
-			} else if edge.To == current.ID {
-				neighbor = edge.From
-			}
-
-			if neighbor != "" && !visited[neighbor] {
-				visited[neighbor] = true
-				queue = append(queue, struct {
-					ID    string
-					Level int
-				}{ID: neighbor, Level: current.Level + 1})
-			}
-		}
-	}
-
-	// Second, collect all edges that connect two nodes within the cone
-	for _, edge := range graphData.Edges {
-		if nodeIDsInCone[edge.From] && nodeIDsInCone[edge.To] {
-			edgesInCone = append(edgesInCone, edge)
-		}
-	}
-
-	return nodeIDsInCone, edgesInCone
+	return &GraphAnalyzer{relations: DefaultRelationRegistry(), schema: DefaultSchema()}
 }
 
 // FindAllPaths trouve tous les chemins dans le graphe
+//
+// Deprecated: le calcul porte sur toutes les paires de nœuds ; FindPaths
+// cherche des chemins bornés entre deux extrémités choisies.
 func (ga *GraphAnalyzer) FindAllPaths(notes map[string][]string) [][]string {
 	adj := ga.buildAdjacencyList(notes)
 	allNodes := ga.extractAllNodes(adj)
@@ -194,7 +167,7 @@ func (ga *GraphAnalyzer) FindAllPaths(notes map[string][]string) [][]string {
 		for j := i + 1; j < len(nodesList); j++ {
 			startNode, endNode := nodesList[i], nodesList[j]
 
-			if path := ga.findPath(startNode, endNode, adj); len(path) > 2 {
+			if path, _ := ga.findPath(startNode, endNode, adj); len(path) > 2 {
 				allPaths = append(allPaths, path)
 			}
 		}
@@ -203,8 +176,9 @@ func (ga *GraphAnalyzer) FindAllPaths(notes map[string][]string) [][]string {
 	return allPaths
 }
 
-// DetectTemporalPatterns détecte les patterns temporels dans les notes
-func (ga *GraphAnalyzer) DetectTemporalPatterns(notes map[string][]string) []models.TemporalPattern {
+// DetectTemporalPatterns détecte les patterns temporels dans les notes. Les
+// spans des notes, s'ils sont fournis, localisent chaque occurrence.
+func (ga *GraphAnalyzer) DetectTemporalPatterns(notes map[string][]string, spans models.NoteSpans) []models.TemporalPattern {
 	temporalMarkers := map[string]string{
 		"avant":     "précède",
 		"après":     "suit",
@@ -256,6 +230,15 @@ func (ga *GraphAnalyzer) DetectTemporalPatterns(notes map[string][]string) []mod
 	// Détecter les heures et dates
 	ga.detectTimeAndDatePatterns(notes, &patterns)
 
+	if spans != nil {
+		index := noteSpanIndex(notes, spans)
+		for i := range patterns {
+			for _, occurrence := range patterns[i].Occurrences {
+				patterns[i].Spans = MergeSpans(patterns[i].Spans, index[occurrence])
+			}
+		}
+	}
+
 	return patterns
 }
 
@@ -278,27 +261,43 @@ func (ga *GraphAnalyzer) CheckSemanticConsistency(graphData models.GraphData) []
 		inconsistencies = append(inconsistencies, incEquiv...)
 	}
 
-	// 4. Détecter les nœuds orphelins suspects
+	// 4. Détecter les faits niés que le graphe affirme malgré tout
+	if negated := ga.detectNegatedFacts(graphData); len(negated) > 0 {
+		inconsistencies = append(inconsistencies, negated...)
+	}
+
+	// 5. Détecter les nœuds orphelins suspects
 	if orphans := ga.detectImportantOrphans(graphData); len(orphans) > 0 {
 		inconsistencies = append(inconsistencies, orphans...)
 	}
 
-	// 5. Détecter les groupes incohérents
+	// 6. Détecter les groupes incohérents
 	if groups := ga.detectDisconnectedGroups(graphData); len(groups) > 0 {
 		inconsistencies = append(inconsistencies, groups...)
 	}
 
+	// 7. Confronter le graphe au schéma du projet
+	if ga.schema != nil {
+		inconsistencies = append(inconsistencies, ga.schema.Validate(graphData)...)
+	}
+
+	// Rattacher chaque incohérence aux lignes source concernées
+	for i := range inconsistencies {
+		inconsistencies[i].Spans = GraphSpans(graphData, inconsistencies[i].Nodes)
+	}
+
 	return inconsistencies
 }
 
 // GenerateInvestigationQuestions génère des questions d'investigation
 func (ga *GraphAnalyzer) GenerateInvestigationQuestions(graphData models.GraphData) []models.InvestigationQuestion {
 	var questions []models.InvestigationQuestion
+	graph := NewGraph(graphData)
 
 	// Analyser les nœuds orphelins
-	orphans := ga.findOrphanNodes(graphData)
+	orphans := ga.findOrphanNodes(graph)
 	for _, orphan := range orphans {
-		importance := ga.calculateNodeImportance(orphan, graphData)
+		importance := ga.calculateNodeImportance(orphan, graph)
 		if importance > 0.5 {
 			questions = append(questions, models.InvestigationQuestion{
 				Question: fmt.Sprintf("Comment '%s' est-il lié aux autres éléments ?", orphan),
@@ -312,11 +311,31 @@ func (ga *GraphAnalyzer) GenerateInvestigationQuestions(graphData models.GraphDa
 	}
 
 	// Analyser les patterns incomplets
-	patterns := ga.analyzeGraphPatterns(graphData)
+	patterns := ga.analyzeGraphPatterns(graph)
 	questions = append(questions, patterns...)
 
+	// Les intermédiaires obligés méritent d'être vérifiés en premier
+	keyPlayers := 0
+	for _, c := range ga.centrality(graph, true).Nodes {
+		if keyPlayers == maxKeyPlayers {
+			break
+		}
+		if c.Betweenness < KeyPlayerBetweenness {
+			continue
+		}
+		keyPlayers++
+		questions = append(questions, models.InvestigationQuestion{
+			Question: fmt.Sprintf("Les liens de '%s' sont-ils bien établis ?", c.Node),
+			Type:     "key_player",
+			Priority: "high",
+			Context:  "Acteur clé",
+			Nodes:    []string{c.Node},
+			Hint:     fmt.Sprintf("%.0f%% des plus courts chemins passent par cet élément (PageRank %.3f) : une erreur à son sujet changerait toute l'analyse.", c.Betweenness*100, c.PageRank),
+		})
+	}
+
 	// Analyser les clusters déconnectés
-	clusters := ga.findDisconnectedClusters(graphData)
+	clusters := ga.findDisconnectedClusters(graph)
 	for _, cluster := range clusters {
 		if len(cluster) > 1 {
 			questions = append(questions, models.InvestigationQuestion{
@@ -340,6 +359,10 @@ func (ga *GraphAnalyzer) GenerateInvestigationQuestions(graphData models.GraphDa
 		questions = questions[:10]
 	}
 
+	for i := range questions {
+		questions[i].Spans = GraphSpans(graphData, questions[i].Nodes)
+	}
+
 	return questions
 }
 
@@ -423,6 +446,9 @@ func (ga *GraphAnalyzer) GetTimelineEvents(notes map[string][]string) []models.T
 			if strings.Contains(note, "---") || note == "" {
 				continue
 			}
+			if _, _, ok := splitSequenceNote(note); ok {
+				continue
+			}
 
 			// Essayer de matcher le pattern
 			if matches := mainPattern.FindStringSubmatch(note); len(matches) == 5 {
@@ -490,6 +516,21 @@ func (ga *GraphAnalyzer) GetTimelineEvents(notes map[string][]string) []models.T
 		}
 	}
 
+	// Les événements n-aires et les attributs de type date
+	// ("Crime (date) 12/03/2024") sont repris du graphe
+	parser := NewN4LParser()
+	parser.relations = ga.relations
+	graph := parser.ParseN4LToGraph(notes)
+	nary := ga.hyperedgeEvents(graph, eventID)
+	events = append(events, nary...)
+	eventID += len(nary)
+	dated := ga.propertyEvents(graph, eventID)
+	events = append(events, dated...)
+	eventID += len(dated)
+
+	// Les séquences donnent des événements ordonnés sans date
+	events = append(events, ga.sequenceEvents(notes, eventID)...)
+
 	// Trier par date/heure
 	sort.Slice(events, func(i, j int) bool {
 		if events[i].DateTime != nil && events[j].DateTime != nil {
@@ -501,6 +542,154 @@ func (ga *GraphAnalyzer) GetTimelineEvents(notes map[string][]string) []models.T
 	return events
 }
 
+// hyperedgeEvents crée un événement de chronologie pour chaque événement
+// n-aire : ses rôles usuels donnent l'acteur, la cible et le lieu
+func (ga *GraphAnalyzer) hyperedgeEvents(graph models.GraphData, eventID int) []models.TimelineEvent {
+	var events []models.TimelineEvent
+	for _, hyperedge := range graph.Hyperedges {
+		eventID++
+		event := models.TimelineEvent{
+			ID:         fmt.Sprintf("event_%d", eventID),
+			DateTime:   hyperedge.Date,
+			IsAbsolute: hyperedge.Date != nil,
+			Action:     hyperedge.Label,
+			Context:    hyperedge.Context,
+			Order:      eventID,
+			Importance: "medium",
+			Color:      "#0ea5e9",
+			Icon:       "🎭",
+		}
+		var roles []string
+		for _, participant := range hyperedge.Participants {
+			roles = append(roles, participant.Role+": "+participant.Node)
+			var field *string
+			switch eventRoleFields[participant.Role] {
+			case "actor":
+				field = &event.Actor
+			case "target":
+				field = &event.Target
+			case "location":
+				field = &event.Location
+			default:
+				continue
+			}
+			if *field == "" {
+				*field = participant.Node
+			} else {
+				*field += ", " + participant.Node
+			}
+		}
+		event.RawDescription = fmt.Sprintf("%s => { %s }", hyperedge.Label, strings.Join(roles, "; "))
+		event.Summary = hyperedge.Label
+		if event.Actor != "" {
+			event.Summary = fmt.Sprintf("%s → %s", event.Actor, hyperedge.Label)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// propertyEvents crée un événement pour chaque attribut de nœud de type date,
+// hors nœuds d'événements n-aires, déjà datés par hyperedgeEvents
+func (ga *GraphAnalyzer) propertyEvents(graph models.GraphData, eventID int) []models.TimelineEvent {
+	var events []models.TimelineEvent
+	for _, node := range graph.Nodes {
+		if node.Kind == EventKind {
+			continue
+		}
+		for _, property := range node.Properties {
+			if property.Date == nil {
+				continue
+			}
+			eventID++
+			events = append(events, models.TimelineEvent{
+				ID:             fmt.Sprintf("event_%d", eventID),
+				DateTime:       property.Date,
+				IsAbsolute:     true,
+				Actor:          node.ID,
+				Action:         property.Name,
+				Context:        property.Context,
+				RawDescription: fmt.Sprintf("%s (%s) %s", node.ID, property.Name, property.Value),
+				Summary:        fmt.Sprintf("%s : %s %s", node.ID, property.Name, property.Value),
+				Order:          eventID,
+				Importance:     "medium",
+				Color:          "#6366f1",
+				Icon:           "📅",
+			})
+		}
+	}
+	return events
+}
+
+// sequenceEvents transforme chaque chaîne "A -> then -> B -> then -> C" d'un
+// contexte en événements relatifs numérotés par étape
+func (ga *GraphAnalyzer) sequenceEvents(notes map[string][]string, eventID int) []models.TimelineEvent {
+	var events []models.TimelineEvent
+
+	contexts := make([]string, 0, len(notes))
+	for context := range notes {
+		contexts = append(contexts, context)
+	}
+	sort.Strings(contexts)
+
+	for _, context := range contexts {
+		next := make(map[string]string)
+		hasPrev := make(map[string]bool)
+		var starts []string
+		for _, note := range notes[context] {
+			from, to, ok := splitSequenceNote(strings.TrimSpace(note))
+			if !ok || next[from] != "" {
+				continue
+			}
+			next[from] = to
+			hasPrev[to] = true
+			starts = append(starts, from)
+		}
+
+		visited := make(map[string]bool)
+		for _, start := range starts {
+			if hasPrev[start] || visited[start] {
+				continue
+			}
+			previous := ""
+			for step, item := 1, start; item != "" && !visited[item]; step, item = step+1, next[item] {
+				visited[item] = true
+				eventID++
+				event := models.TimelineEvent{
+					ID:             fmt.Sprintf("event_%d", eventID),
+					RelativeTime:   fmt.Sprintf("étape %d", step),
+					IsRelative:     true,
+					Action:         item,
+					Context:        context,
+					RawDescription: item,
+					Summary:        item,
+					Order:          eventID,
+					Importance:     "medium",
+					Color:          "#8b5cf6",
+					Icon:           "➡️",
+				}
+				if previous != "" {
+					event.LinkedEvents = []string{previous}
+				}
+				previous = event.ID
+				events = append(events, event)
+			}
+		}
+	}
+	return events
+}
+
+// splitSequenceNote reconnaît une note d'ordre "A -> then -> B" produite par
+// une section séquence
+func splitSequenceNote(note string) (from, to string, ok bool) {
+	parts := strings.Split(note, " -> ")
+	if len(parts) != 3 || strings.TrimSpace(parts[1]) != SequenceLabel {
+		return "", "", false
+	}
+	from, to = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[2])
+	return from, to, from != "" && to != ""
+}
+
 // hasTemporalMarker vérifie si une note contient des marqueurs temporels
 func (ga *GraphAnalyzer) hasTemporalMarker(note string) bool {
 	temporalPatterns := []string{
@@ -1016,30 +1205,15 @@ func (ga *GraphAnalyzer) generateEventSummary(event models.TimelineEvent) string
 
 // --- Méthodes privées ---
 
-func (ga *GraphAnalyzer) buildAdjacencyList(notes map[string][]string) map[string][]string {
-	adj := make(map[string][]string)
-	relationRegex := regexp.MustCompile(`^(.*) -> (.*) -> (.*)$`)
-	equivalenceRegex := regexp.MustCompile(`^(.*) <-> (.*)$`)
-	groupRegex := regexp.MustCompile(`^(.*) => {(.*)}$`)
+func (ga *GraphAnalyzer) buildAdjacencyList(notes map[string][]string) adjacency {
+	adj := make(adjacency)
+	parser := NewN4LParser()
 
-	for _, notesList := range notes {
+	for context, notesList := range notes {
 		for _, note := range notesList {
-			if matches := relationRegex.FindStringSubmatch(note); len(matches) == 4 {
-				source, target := strings.TrimSpace(matches[1]), strings.TrimSpace(matches[3])
-				adj[source] = append(adj[source], target)
-				adj[target] = append(adj[target], source)
-			} else if matches := equivalenceRegex.FindStringSubmatch(note); len(matches) == 3 {
-				source, target := strings.TrimSpace(matches[1]), strings.TrimSpace(matches[2])
-				adj[source] = append(adj[source], target)
-				adj[target] = append(adj[target], source)
-			} else if matches := groupRegex.FindStringSubmatch(note); len(matches) == 3 {
-				parent := strings.TrimSpace(matches[1])
-				children := strings.Split(matches[2], ";")
-				for _, child := range children {
-					childName := strings.TrimSpace(child)
-					adj[parent] = append(adj[parent], childName)
-					adj[childName] = append(adj[childName], parent)
-				}
+			edges, _ := parser.parseNoteToEdges(note, context)
+			for _, edge := range edges {
+				adj.add(edge)
 			}
 		}
 	}
@@ -1047,43 +1221,66 @@ func (ga *GraphAnalyzer) buildAdjacencyList(notes map[string][]string) map[strin
 	return adj
 }
 
-func (ga *GraphAnalyzer) extractAllNodes(adj map[string][]string) map[string]bool {
+func (ga *GraphAnalyzer) extractAllNodes(adj adjacency) map[string]bool {
 	allNodes := make(map[string]bool)
 	for node := range adj {
 		allNodes[node] = true
-		for _, neighbor := range adj[node] {
-			allNodes[neighbor] = true
+		for _, n := range adj[node] {
+			allNodes[n.id] = true
 		}
 	}
 	return allNodes
 }
 
-func (ga *GraphAnalyzer) findPath(start, end string, adj map[string][]string) []string {
-	queue := [][]string{{start}}
-	visited := make(map[string]bool)
-	visited[start] = true
-
-	for len(queue) > 0 {
-		path := queue[0]
-		queue = queue[1:]
-		node := path[len(path)-1]
+// findPath retourne le chemin de moindre coût entre deux nœuds (Dijkstra) et
+// son coût ; sans qualificatifs, c'est le plus court chemin
+func (ga *GraphAnalyzer) findPath(start, end string, adj adjacency) ([]string, float64) {
+	dist := map[string]float64{start: 0}
+	previous := make(map[string]string)
+	done := make(map[string]bool)
+	queue := &pathQueue{{id: start}}
+
+	for queue.Len() > 0 {
+		current := heap.Pop(queue).(neighbor)
+		if done[current.id] {
+			continue
+		}
+		done[current.id] = true
 
-		if node == end {
-			return path
+		if current.id == end {
+			path := []string{end}
+			for node := end; node != start; {
+				node = previous[node]
+				path = append([]string{node}, path...)
+			}
+			return path, current.cost
 		}
 
-		for _, neighbor := range adj[node] {
-			if !visited[neighbor] {
-				visited[neighbor] = true
-				newPath := make([]string, len(path))
-				copy(newPath, path)
-				newPath = append(newPath, neighbor)
-				queue = append(queue, newPath)
+		for _, n := range adj[current.id] {
+			cost := current.cost + n.cost
+			if d, seen := dist[n.id]; !done[n.id] && (!seen || cost < d) {
+				dist[n.id] = cost
+				previous[n.id] = current.id
+				heap.Push(queue, neighbor{n.id, cost})
 			}
 		}
 	}
 
-	return nil
+	return nil, 0
+}
+
+// pathQueue est la file de priorité de findPath, ordonnée par coût cumulé
+type pathQueue []neighbor
+
+func (q pathQueue) Len() int            { return len(q) }
+func (q pathQueue) Less(i, j int) bool  { return q[i].cost < q[j].cost }
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(neighbor)) }
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
 }
 
 func (ga *GraphAnalyzer) analyzeTemporalContext(text, marker, relation string) []string {
@@ -1221,12 +1418,12 @@ func (ga *GraphAnalyzer) detectTemporalCycles(graphData models.GraphData) []mode
 	temporalEdges := make(map[string][]models.Edge)
 
 	for _, edge := range graphData.Edges {
-		if edge.Type == "relation" {
-			lowerLabel := strings.ToLower(edge.Label)
-			if strings.Contains(lowerLabel, "précède") || strings.Contains(lowerLabel, "avant") ||
-				strings.Contains(lowerLabel, "puis") || strings.Contains(lowerLabel, "ensuite") {
-				temporalEdges[edge.From] = append(temporalEdges[edge.From], edge)
-			}
+		if edge.Type == "sequence" {
+			temporalEdges[edge.From] = append(temporalEdges[edge.From], edge)
+		} else if edge.Type == "relation" && ga.relations.Class(edge.Label) == ClassLeadsTo {
+			// "B suit A" compte comme "A précède B"
+			edge.From, edge.Label, edge.To = ga.relations.Orient(edge.From, edge.Label, edge.To)
+			temporalEdges[edge.From] = append(temporalEdges[edge.From], edge)
 		}
 	}
 
@@ -1293,34 +1490,18 @@ func (ga *GraphAnalyzer) detectContradictoryRelations(graphData models.GraphData
 		}
 	}
 
-	contradictoryPairs := map[string]string{
-		"cause":     "empêche",
-		"contient":  "exclut",
-		"précède":   "suit",
-		"identique": "différent",
-		"ami":       "ennemi",
-	}
-
 	for from, targets := range relationMap {
 		for to, labels := range targets {
 			for i, label1 := range labels {
 				for j, label2 := range labels {
-					if i < j {
-						l1Lower := strings.ToLower(label1)
-						l2Lower := strings.ToLower(label2)
-
-						for word1, word2 := range contradictoryPairs {
-							if (strings.Contains(l1Lower, word1) && strings.Contains(l2Lower, word2)) ||
-								(strings.Contains(l1Lower, word2) && strings.Contains(l2Lower, word1)) {
-								inconsistencies = append(inconsistencies, models.Inconsistency{
-									Type:        "contradictory_relations",
-									Description: fmt.Sprintf("%s a des relations contradictoires avec %s : '%s' et '%s'", from, to, label1, label2),
-									Nodes:       []string{from, to},
-									Severity:    "warning",
-									Suggestion:  "Clarifiez la nature de la relation entre ces éléments.",
-								})
-							}
-						}
+					if i < j && ga.relations.Contradicts(label1, label2) {
+						inconsistencies = append(inconsistencies, models.Inconsistency{
+							Type:        "contradictory_relations",
+							Description: fmt.Sprintf("%s a des relations contradictoires avec %s : '%s' et '%s'", from, to, label1, label2),
+							Nodes:       []string{from, to},
+							Severity:    "warning",
+							Suggestion:  "Clarifiez la nature de la relation entre ces éléments.",
+						})
 					}
 				}
 			}
@@ -1332,36 +1513,35 @@ func (ga *GraphAnalyzer) detectContradictoryRelations(graphData models.GraphData
 
 func (ga *GraphAnalyzer) detectInconsistentEquivalences(graphData models.GraphData) []models.Inconsistency {
 	var inconsistencies []models.Inconsistency
-	equivalenceGroups := make(map[string][]string)
+	graph := NewGraph(graphData)
 
+	// Classes d'équivalence, membres dans l'ordre d'apparition
+	classes := ga.equivalenceClosure(graphData)
+	var roots []string
+	equivalenceGroups := make(map[string][]string)
 	for _, edge := range graphData.Edges {
-		if edge.Type == "equivalence" {
-			found := false
-			for key, group := range equivalenceGroups {
-				if ga.contains(group, edge.From) || ga.contains(group, edge.To) {
-					if !ga.contains(group, edge.From) {
-						equivalenceGroups[key] = append(group, edge.From)
-					}
-					if !ga.contains(group, edge.To) {
-						equivalenceGroups[key] = append(group, edge.To)
-					}
-					found = true
-					break
-				}
+		if edge.Type != "equivalence" {
+			continue
+		}
+		for _, node := range []string{edge.From, edge.To} {
+			root := classes[node]
+			if _, ok := equivalenceGroups[root]; !ok {
+				roots = append(roots, root)
 			}
-			if !found {
-				equivalenceGroups[edge.From] = []string{edge.From, edge.To}
+			if !ga.contains(equivalenceGroups[root], node) {
+				equivalenceGroups[root] = append(equivalenceGroups[root], node)
 			}
 		}
 	}
 
-	for _, group := range equivalenceGroups {
+	for _, root := range roots {
+		group := equivalenceGroups[root]
 		if len(group) > 1 {
 			relationsPerNode := make(map[string]map[string]bool)
 			for _, node := range group {
 				relationsPerNode[node] = make(map[string]bool)
-				for _, edge := range graphData.Edges {
-					if edge.From == node && edge.Type == "relation" {
+				for _, edge := range graph.OutEdges(node) {
+					if edge.Type == "relation" {
 						relationsPerNode[node][edge.To+":"+edge.Label] = true
 					}
 				}
@@ -1399,6 +1579,109 @@ func (ga *GraphAnalyzer) detectInconsistentEquivalences(graphData models.GraphDa
 	return inconsistencies
 }
 
+// detectNegatedFacts confronte les négations à la fermeture des équivalences :
+// "A (!eq) B" contredit une chaîne A <-> ... <-> B, et "A (!r) B" contredit
+// une relation r entre des éléments équivalents à A et B
+func (ga *GraphAnalyzer) detectNegatedFacts(graphData models.GraphData) []models.Inconsistency {
+	var inconsistencies []models.Inconsistency
+	classes := ga.equivalenceClosure(graphData)
+	class := func(node string) string {
+		if root, ok := classes[node]; ok {
+			return root
+		}
+		return node
+	}
+
+	for _, negation := range graphData.Edges {
+		switch negation.Type {
+		case "non_equivalence":
+			if class(negation.From) != class(negation.To) {
+				continue
+			}
+			path := ga.equivalencePath(negation.From, negation.To, graphData)
+			inconsistencies = append(inconsistencies, models.Inconsistency{
+				Type:        "negated_equivalence",
+				Description: fmt.Sprintf("%s et %s sont déclarés non équivalents, mais la chaîne %s les rend équivalents", negation.From, negation.To, strings.Join(path, " <-> ")),
+				Nodes:       path,
+				Severity:    "error",
+				Suggestion:  "Retirez l'une des équivalences de la chaîne ou la négation.",
+			})
+
+		case "negation":
+			from, label, to := ga.relations.Orient(negation.From, negation.Label, negation.To)
+			for _, edge := range graphData.Edges {
+				if edge.Type != "relation" {
+					continue
+				}
+				edgeFrom, edgeLabel, edgeTo := ga.relations.Orient(edge.From, edge.Label, edge.To)
+				if edgeLabel != label || class(edgeFrom) != class(from) || class(edgeTo) != class(to) {
+					continue
+				}
+				nodes := []string{negation.From, negation.To}
+				for _, node := range []string{edge.From, edge.To} {
+					if !ga.contains(nodes, node) {
+						nodes = append(nodes, node)
+					}
+				}
+				inconsistencies = append(inconsistencies, models.Inconsistency{
+					Type:        "negated_relation",
+					Description: fmt.Sprintf("« %s %s %s » est nié, mais « %s %s %s » l'affirme", negation.From, negation.Label, negation.To, edge.From, edge.Label, edge.To),
+					Nodes:       nodes,
+					Severity:    "error",
+					Suggestion:  "Corrigez la relation ou la négation, ou vérifiez les équivalences qui les relient.",
+				})
+				break
+			}
+		}
+	}
+	return inconsistencies
+}
+
+// equivalenceClosure regroupe les nœuds reliés par une chaîne d'équivalences
+// et associe à chacun le représentant de sa classe
+func (ga *GraphAnalyzer) equivalenceClosure(graphData models.GraphData) map[string]string {
+	return closure(graphData.Edges, func(edge models.Edge) bool { return edge.Type == "equivalence" })
+}
+
+// equivalencePath retourne la plus courte chaîne d'équivalences entre deux nœuds
+func (ga *GraphAnalyzer) equivalencePath(from, to string, graphData models.GraphData) []string {
+	adj := make(map[string][]string)
+	for _, edge := range graphData.Edges {
+		if edge.Type == "equivalence" {
+			adj[edge.From] = append(adj[edge.From], edge.To)
+			adj[edge.To] = append(adj[edge.To], edge.From)
+		}
+	}
+
+	previous := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 && from != to {
+		node := queue[0]
+		queue = queue[1:]
+		for _, next := range adj[node] {
+			if _, seen := previous[next]; seen {
+				continue
+			}
+			previous[next] = node
+			if next == to {
+				queue = nil
+				break
+			}
+			queue = append(queue, next)
+		}
+	}
+
+	if _, ok := previous[to]; !ok {
+		return []string{from, to}
+	}
+	path := []string{to}
+	for node := to; node != from; {
+		node = previous[node]
+		path = append([]string{node}, path...)
+	}
+	return path
+}
+
 func (ga *GraphAnalyzer) detectImportantOrphans(graphData models.GraphData) []models.Inconsistency {
 	var inconsistencies []models.Inconsistency
 	connectedNodes := make(map[string]bool)
@@ -1427,27 +1710,24 @@ func (ga *GraphAnalyzer) detectImportantOrphans(graphData models.GraphData) []mo
 
 func (ga *GraphAnalyzer) detectDisconnectedGroups(graphData models.GraphData) []models.Inconsistency {
 	var inconsistencies []models.Inconsistency
+	graph := NewGraph(graphData)
 
-	for _, edge := range graphData.Edges {
+	for _, edge := range graph.Edges() {
 		if edge.Type == "group" {
 			groupMembers := []string{}
-			for _, e := range graphData.Edges {
-				if e.Type == "group" && e.From == edge.From {
+			for _, e := range graph.OutEdges(edge.From) {
+				if e.Type == "group" {
 					groupMembers = append(groupMembers, e.To)
 				}
 			}
 
 			if len(groupMembers) > 2 {
 				hasInternalRelations := false
-				for _, m1 := range groupMembers {
-					for _, m2 := range groupMembers {
-						if m1 != m2 {
-							for _, e := range graphData.Edges {
-								if (e.From == m1 && e.To == m2) || (e.From == m2 && e.To == m1) {
-									hasInternalRelations = true
-									break
-								}
-							}
+				for i, m1 := range groupMembers {
+					for _, m2 := range groupMembers[i+1:] {
+						if m1 != m2 && graph.Connected(m1, m2) {
+							hasInternalRelations = true
+							break
 						}
 					}
 				}
@@ -1468,30 +1748,18 @@ func (ga *GraphAnalyzer) detectDisconnectedGroups(graphData models.GraphData) []
 	return inconsistencies
 }
 
-func (ga *GraphAnalyzer) findOrphanNodes(graphData models.GraphData) []string {
-	connected := make(map[string]bool)
-	for _, edge := range graphData.Edges {
-		connected[edge.From] = true
-		connected[edge.To] = true
-	}
-
+func (ga *GraphAnalyzer) findOrphanNodes(graph *Graph) []string {
 	var orphans []string
-	for _, node := range graphData.Nodes {
-		if !connected[node.ID] {
+	for _, node := range graph.Nodes() {
+		if graph.Degree(node.ID) == 0 {
 			orphans = append(orphans, node.ID)
 		}
 	}
 	return orphans
 }
 
-func (ga *GraphAnalyzer) calculateNodeImportance(nodeID string, graphData models.GraphData) float64 {
-	var node models.Node
-	for _, n := range graphData.Nodes {
-		if n.ID == nodeID {
-			node = n
-			break
-		}
-	}
+func (ga *GraphAnalyzer) calculateNodeImportance(nodeID string, graph *Graph) float64 {
+	node, _ := graph.Node(nodeID)
 
 	importance := 0.3 // Base
 
@@ -1507,6 +1775,12 @@ func (ga *GraphAnalyzer) calculateNodeImportance(nodeID string, graphData models
 		importance += 0.2
 	}
 
+	// Un nœud qui ne repose que sur des hypothèses compte moins qu'un nœud
+	// étayé par des faits établis
+	if support, count := graph.Support(nodeID); count > 0 {
+		importance *= 0.5 + 0.5*support
+	}
+
 	return importance
 }
 
@@ -1519,16 +1793,16 @@ func (ga *GraphAnalyzer) getPriorityFromImportance(importance float64) string {
 	return "low"
 }
 
-func (ga *GraphAnalyzer) analyzeGraphPatterns(graphData models.GraphData) []models.InvestigationQuestion {
+func (ga *GraphAnalyzer) analyzeGraphPatterns(graph *Graph) []models.InvestigationQuestion {
 	var questions []models.InvestigationQuestion
-	nodeTypes := ga.classifyNodesByConnections(graphData)
+	nodeTypes := ga.classifyNodesByConnections(graph)
 
 	for _, nodes := range nodeTypes {
 		if len(nodes) > 0 {
-			avgConnections := ga.calculateAverageConnections(nodes, graphData)
+			avgConnections := ga.calculateAverageConnections(nodes, graph)
 
 			for _, node := range nodes {
-				connections := ga.countNodeConnections(node, graphData)
+				connections := graph.Degree(node)
 				if float64(connections) < avgConnections*0.5 {
 					questions = append(questions, models.InvestigationQuestion{
 						Question: fmt.Sprintf("Pourquoi '%s' a-t-il moins de connexions que les autres éléments similaires ?", node),
@@ -1546,29 +1820,20 @@ func (ga *GraphAnalyzer) analyzeGraphPatterns(graphData models.GraphData) []mode
 	return questions
 }
 
-func (ga *GraphAnalyzer) classifyNodesByConnections(graphData models.GraphData) map[string][]string {
+func (ga *GraphAnalyzer) classifyNodesByConnections(graph *Graph) map[string][]string {
 	classified := make(map[string][]string)
 
-	for _, node := range graphData.Nodes {
-		pattern := ga.getConnectionPattern(node.ID, graphData)
+	for _, node := range graph.Nodes() {
+		pattern := ga.getConnectionPattern(node.ID, graph)
 		classified[pattern] = append(classified[pattern], node.ID)
 	}
 
 	return classified
 }
 
-func (ga *GraphAnalyzer) getConnectionPattern(nodeID string, graphData models.GraphData) string {
-	inCount := 0
-	outCount := 0
-
-	for _, edge := range graphData.Edges {
-		if edge.To == nodeID {
-			inCount++
-		}
-		if edge.From == nodeID {
-			outCount++
-		}
-	}
+func (ga *GraphAnalyzer) getConnectionPattern(nodeID string, graph *Graph) string {
+	inCount := graph.InDegree(nodeID)
+	outCount := graph.OutDegree(nodeID)
 
 	if inCount > outCount*2 {
 		return "receiver"
@@ -1582,14 +1847,14 @@ func (ga *GraphAnalyzer) getConnectionPattern(nodeID string, graphData models.Gr
 	return "standard"
 }
 
-func (ga *GraphAnalyzer) findDisconnectedClusters(graphData models.GraphData) [][]string {
+func (ga *GraphAnalyzer) findDisconnectedClusters(graph *Graph) [][]string {
 	visited := make(map[string]bool)
 	var clusters [][]string
 
-	for _, node := range graphData.Nodes {
+	for _, node := range graph.Nodes() {
 		if !visited[node.ID] {
 			cluster := []string{}
-			ga.dfs(node.ID, graphData, visited, &cluster)
+			ga.dfs(node.ID, graph, visited, &cluster)
 			if len(cluster) > 0 {
 				clusters = append(clusters, cluster)
 			}
@@ -1599,43 +1864,30 @@ func (ga *GraphAnalyzer) findDisconnectedClusters(graphData models.GraphData) []
 	return clusters
 }
 
-func (ga *GraphAnalyzer) dfs(nodeID string, graphData models.GraphData, visited map[string]bool, cluster *[]string) {
+func (ga *GraphAnalyzer) dfs(nodeID string, graph *Graph, visited map[string]bool, cluster *[]string) {
 	visited[nodeID] = true
 	*cluster = append(*cluster, nodeID)
 
-	for _, edge := range graphData.Edges {
-		if edge.From == nodeID && !visited[edge.To] {
-			ga.dfs(edge.To, graphData, visited, cluster)
-		}
-		if edge.To == nodeID && !visited[edge.From] {
-			ga.dfs(edge.From, graphData, visited, cluster)
+	for _, neighbor := range graph.Neighbors(nodeID) {
+		if !visited[neighbor] {
+			ga.dfs(neighbor, graph, visited, cluster)
 		}
 	}
 }
 
-func (ga *GraphAnalyzer) calculateAverageConnections(nodes []string, graphData models.GraphData) float64 {
+func (ga *GraphAnalyzer) calculateAverageConnections(nodes []string, graph *Graph) float64 {
 	if len(nodes) == 0 {
 		return 0
 	}
 
 	total := 0
 	for _, node := range nodes {
-		total += ga.countNodeConnections(node, graphData)
+		total += graph.Degree(node)
 	}
 
 	return float64(total) / float64(len(nodes))
 }
 
-func (ga *GraphAnalyzer) countNodeConnections(nodeID string, graphData models.GraphData) int {
-	count := 0
-	for _, edge := range graphData.Edges {
-		if edge.From == nodeID || edge.To == nodeID {
-			count++
-		}
-	}
-	return count
-}
-
 func (ga *GraphAnalyzer) classifyNodeLayer(label, context string) string {
 	lowerLabel := strings.ToLower(label)
 	lowerContext := strings.ToLower(context)
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"n4l-editor/models"
+)
+
+func TestDetectNegatedFacts(t *testing.T) {
+	parser := NewN4LParser()
+	source := strings.Join([]string{
+		"Dupont (!eq) Le Baron",
+		"Dupont <-> Jean D.",
+		"Jean D. (=) Le Baron",
+		"Martin (!connaît) Dupont",
+		"M. Martin <-> Martin",
+		"M. Martin -> connaît -> Jean D.",
+		"Alice (!eq) Bob",
+	}, "\n")
+	graph := parser.ParseN4LToGraph(parser.ParseN4L(source).Notes)
+
+	types := make(map[string]int)
+	for _, edge := range graph.Edges {
+		types[edge.Type]++
+	}
+	if types["non_equivalence"] != 2 || types["negation"] != 1 {
+		t.Fatalf("arêtes de négation attendues : %v", types)
+	}
+
+	found := make(map[string]string)
+	for _, inconsistency := range NewGraphAnalyzer().detectNegatedFacts(graph) {
+		found[inconsistency.Type] = strings.Join(inconsistency.Nodes, ",")
+	}
+	want := map[string]string{
+		"negated_equivalence": "Dupont,Jean D.,Le Baron",
+		"negated_relation":    "Martin,Dupont,M. Martin,Jean D.",
+	}
+	if len(found) != len(want) {
+		t.Errorf("incohérences : %v", found)
+	}
+	for kind, nodes := range want {
+		if found[kind] != nodes {
+			t.Errorf("%s : nœuds %q, attendu %q", kind, found[kind], nodes)
+		}
+	}
+}
+
+func TestSourceSpans(t *testing.T) {
+	parser := NewN4LParser()
+	source := strings.Join([]string{
+		":: Enquête ::",
+		"X -> ami -> Y",
+		"# commentaire",
+		"X -> ennemi de -> Y",
+		"Z (puis) X",
+	}, "\n")
+	parsed := parser.ParseN4L(source)
+	graph := parser.ParseN4LToGraphWithSpans(parsed.Notes, parsed.Spans)
+
+	for _, edge := range graph.Edges {
+		if len(edge.Spans) != 1 {
+			t.Fatalf("un span attendu pour %s -> %s : %+v", edge.From, edge.To, edge.Spans)
+		}
+	}
+	for _, node := range graph.Nodes {
+		if node.ID == "X" && len(node.Spans) != 3 {
+			t.Errorf("X est mentionné sur 3 lignes : %+v", node.Spans)
+		}
+	}
+
+	var found bool
+	for _, inconsistency := range NewGraphAnalyzer().CheckSemanticConsistency(graph) {
+		if inconsistency.Type != "contradictory_relations" {
+			continue
+		}
+		found = true
+		if len(inconsistency.Spans) != 2 || inconsistency.Spans[0].StartLine != 2 || inconsistency.Spans[1].StartLine != 4 {
+			t.Errorf("spans de la contradiction : %+v", inconsistency.Spans)
+		}
+		if inconsistency.Spans[1].Text != "X -> ennemi de -> Y" {
+			t.Errorf("texte original attendu : %q", inconsistency.Spans[1].Text)
+		}
+	}
+	if !found {
+		t.Error("contradiction attendue")
+	}
+
+	// Sans spans, ou avec des spans désynchronisés, le graphe reste sans provenance
+	for _, spans := range []models.NoteSpans{nil, {"Enquête": parsed.Spans["Enquête"][:1]}} {
+		for _, edge := range parser.ParseN4LToGraphWithSpans(parsed.Notes, spans).Edges {
+			if edge.Spans != nil {
+				t.Errorf("aucun span attendu : %+v", edge.Spans)
+			}
+		}
+	}
+}
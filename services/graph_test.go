@@ -0,0 +1,104 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"n4l-editor/models"
+)
+
+func TestGraphIndex(t *testing.T) {
+	source := strings.Join([]string{
+		"Jean (connaît) Marie",
+		"Marie (connaît) Jean",
+		"Jean (était à) Gare",
+		"Jean (se cite) Jean",
+		"Vol => { agent: Jean; lieu: Gare }",
+	}, "\n")
+	parser := NewN4LParser()
+	g := NewGraph(parser.ParseN4LToGraph(parser.ParseN4L(source).Notes))
+
+	if got, want := g.Neighbors("Jean"), []string{"Marie", "Gare", "Vol"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("voisins de Jean : %v, attendu %v", got, want)
+	}
+	// La boucle compte une fois dans le degré, dans chaque sens dans in/out
+	if g.Degree("Jean") != 5 || g.OutDegree("Jean") != 3 || g.InDegree("Jean") != 3 {
+		t.Errorf("degrés de Jean : %d (%d sortantes, %d entrantes)", g.Degree("Jean"), g.OutDegree("Jean"), g.InDegree("Jean"))
+	}
+	if n := len(g.EdgesByLabel("connaît")); n != 2 {
+		t.Errorf("%d arêtes « connaît », attendu 2", n)
+	}
+	if !g.Connected("Marie", "Jean") || g.Connected("Marie", "Gare") {
+		t.Error("Connected incorrect")
+	}
+	if !g.IsEvent("Vol") || g.IsEvent("Jean") {
+		t.Error("IsEvent incorrect")
+	}
+	if node, ok := g.Node("Gare"); !ok || node.Label != "Gare" {
+		t.Errorf("nœud Gare : %+v, %v", node, ok)
+	}
+	if g.HasNode("Paris") || g.Degree("Paris") != 0 || g.Neighbors("Paris") != nil {
+		t.Error("un nœud absent ne doit avoir ni arêtes ni voisins")
+	}
+}
+
+// benchmarkGraph est le graphe du document de 10 000 lignes des benchmarks
+// du parser
+func benchmarkGraph(b *testing.B) models.GraphData {
+	parser := NewN4LParser()
+	graph := parser.ParseN4LToGraph(parser.ParseN4L(largeDocument(10000)).Notes)
+	b.ResetTimer()
+	return graph
+}
+
+// BenchmarkDegreesScan mesure le calcul des degrés par parcours complet des
+// arêtes pour chaque nœud, tel que le faisaient analyseur et handlers, à
+// comparer à BenchmarkDegreesIndexed
+func BenchmarkDegreesScan(b *testing.B) {
+	graph := benchmarkGraph(b)
+	for i := 0; i < b.N; i++ {
+		for _, node := range graph.Nodes {
+			degree := 0
+			for _, edge := range graph.Edges {
+				if edge.From == node.ID || edge.To == node.ID {
+					degree++
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkDegreesIndexed inclut la construction de l'index
+func BenchmarkDegreesIndexed(b *testing.B) {
+	graph := benchmarkGraph(b)
+	for i := 0; i < b.N; i++ {
+		g := NewGraph(graph)
+		for _, node := range graph.Nodes {
+			g.Degree(node.ID)
+		}
+	}
+}
+
+func BenchmarkNewGraph(b *testing.B) {
+	graph := benchmarkGraph(b)
+	for i := 0; i < b.N; i++ {
+		NewGraph(graph)
+	}
+}
+
+func BenchmarkExpansionCone(b *testing.B) {
+	graph := benchmarkGraph(b)
+	ga := NewGraphAnalyzer()
+	for i := 0; i < b.N; i++ {
+		ga.ExpansionCone(ConeQuery{NodeID: "Personne 8", Depth: 3}, graph)
+	}
+}
+
+func BenchmarkInvestigationQuestions(b *testing.B) {
+	graph := benchmarkGraph(b)
+	ga := NewGraphAnalyzer()
+	for i := 0; i < b.N; i++ {
+		ga.GenerateInvestigationQuestions(graph)
+	}
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"n4l-editor/models"
+)
+
+// AliasLabel est le libellé canonique des relations "A (alias) B", traitées
+// comme des équivalences lors de la fusion des alias
+const AliasLabel = "alias"
+
+// IdentityPolicy règle l'identification des nœuds. Les noms sont toujours
+// normalisés (Unicode NFC, espaces, guillemets) ; les replis de casse et
+// d'accents rapprochent des variantes, dont la première rencontrée donne son
+// nom au nœud. MergeAliases fusionne chaque classe d'équivalences en un seul
+// nœud qui liste les autres noms dans Aliases.
+type IdentityPolicy struct {
+	FoldCase     bool
+	FoldAccents  bool
+	MergeAliases bool
+}
+
+// DefaultIdentityPolicy : "Jean" et "jean" désignent le même nœud, mais pas
+// "the" et "thé", ni deux éléments déclarés équivalents
+var DefaultIdentityPolicy = IdentityPolicy{FoldCase: true}
+
+// NormalizeNodeID met un nom de nœud sous forme canonique : NFC, espaces
+// réduits et guillemets englobants retirés
+func NormalizeNodeID(name string) string {
+	runes := []rune(strings.Join(strings.Fields(norm.NFC.String(name)), " "))
+	for len(runes) > 1 && isQuotePair(runes[0], runes[len(runes)-1]) {
+		inner := string(runes[1 : len(runes)-1])
+		if strings.ContainsRune(inner, runes[0]) || strings.ContainsRune(inner, runes[len(runes)-1]) {
+			break // '"a" et "b"' n'est pas un nom entre guillemets
+		}
+		runes = []rune(strings.TrimSpace(inner))
+	}
+	return string(runes)
+}
+
+func isQuotePair(first, last rune) bool {
+	switch first {
+	case '"', '\'':
+		return last == first
+	case '«':
+		return last == '»'
+	case '“':
+		return last == '”'
+	}
+	return false
+}
+
+// Key retourne la clé d'identité d'un nom : deux noms de même clé désignent
+// le même nœud
+func (policy IdentityPolicy) Key(name string) string {
+	return policy.key(NormalizeNodeID(name))
+}
+
+// key retourne la clé d'identité d'un nom déjà normalisé
+func (policy IdentityPolicy) key(name string) string {
+	if policy.FoldAccents {
+		var b strings.Builder
+		for _, r := range norm.NFD.String(name) {
+			if !unicode.Is(unicode.Mn, r) {
+				b.WriteRune(r)
+			}
+		}
+		name = norm.NFC.String(b.String())
+	}
+	if policy.FoldCase {
+		name = strings.ToLower(name)
+	}
+	return name
+}
+
+// nodeIdentity attribue un identifiant unique à toutes les variantes d'un nom
+type nodeIdentity struct {
+	policy IdentityPolicy
+	byKey  map[string]string
+}
+
+func newNodeIdentity(policy IdentityPolicy) *nodeIdentity {
+	return &nodeIdentity{policy: policy, byKey: make(map[string]string)}
+}
+
+// resolve retourne l'identifiant du nœud désigné par un nom
+func (ids *nodeIdentity) resolve(name string) string {
+	name = NormalizeNodeID(name)
+	key := ids.policy.key(name)
+	if id, ok := ids.byKey[key]; ok {
+		return id
+	}
+	ids.byKey[key] = name
+	return name
+}
+
+// closure regroupe les nœuds reliés par les arêtes retenues par link et
+// associe à chacun le représentant de sa classe
+func closure(edges []models.Edge, link func(models.Edge) bool) map[string]string {
+	parent := make(map[string]string)
+	var find func(node string) string
+	find = func(node string) string {
+		p, ok := parent[node]
+		if !ok {
+			parent[node] = node
+			return node
+		}
+		if p == node {
+			return node
+		}
+		root := find(p)
+		parent[node] = root
+		return root
+	}
+
+	for _, edge := range edges {
+		if !link(edge) {
+			continue
+		}
+		a, b := find(edge.From), find(edge.To)
+		if a != b {
+			if b < a {
+				a, b = b, a
+			}
+			parent[b] = a
+		}
+	}
+
+	classes := make(map[string]string, len(parent))
+	for node := range parent {
+		classes[node] = find(node)
+	}
+	return classes
+}
+
+// isAliasEdge indique si une arête déclare deux noms pour un même élément
+func isAliasEdge(edge models.Edge) bool {
+	return edge.Type == "equivalence" || (edge.Type == "relation" && edge.Label == AliasLabel)
+}
+
+// mergeAliases fusionne chaque classe d'équivalences en un nœud unique, nommé
+// d'après le premier membre rencontré. Les équivalences internes disparaissent,
+// les autres arêtes sont redirigées vers le nœud canonique. Une classe dont
+// deux membres sont déclarés non équivalents n'est pas fusionnée : la
+// vérification de cohérence signale le conflit sous les noms d'origine.
+func mergeAliases(graph models.GraphData) models.GraphData {
+	classes := closure(graph.Edges, isAliasEdge)
+	conflicting := make(map[string]bool)
+	for _, edge := range graph.Edges {
+		if root, ok := classes[edge.From]; ok && edge.Type == "non_equivalence" && classes[edge.To] == root {
+			conflicting[root] = true
+		}
+	}
+	for node, root := range classes {
+		if conflicting[root] {
+			delete(classes, node)
+		}
+	}
+	if len(classes) == 0 {
+		return graph
+	}
+
+	canonical := make(map[string]string) // représentant de classe -> nœud canonique
+	index := make(map[string]int)        // nœud canonique -> position dans nodes
+	var nodes []models.Node
+	rename := func(id string) string {
+		if name, ok := canonical[classes[id]]; ok {
+			return name
+		}
+		return id
+	}
+
+	for _, node := range graph.Nodes {
+		root, ok := classes[node.ID]
+		if !ok {
+			nodes = append(nodes, node)
+			continue
+		}
+		id, seen := canonical[root]
+		if !seen {
+			canonical[root] = node.ID
+			index[node.ID] = len(nodes)
+			nodes = append(nodes, node)
+			continue
+		}
+		merged := &nodes[index[id]]
+		merged.Aliases = append(merged.Aliases, node.ID)
+		merged.Contexts = mergeContexts(merged.Contexts, node.Contexts)
+		merged.Spans = MergeSpans(merged.Spans, node.Spans)
+		merged.Properties = append(merged.Properties, node.Properties...)
+		if node.Kind != "" {
+			merged.Kind = node.Kind
+		}
+	}
+
+	var edges []models.Edge
+	for _, edge := range graph.Edges {
+		edge.From, edge.To = rename(edge.From), rename(edge.To)
+		if isAliasEdge(edge) && edge.From == edge.To {
+			continue
+		}
+		edges = append(edges, edge)
+	}
+
+	return models.GraphData{Nodes: nodes, Edges: edges}
+}
+
+// AssignEdgeIDs retourne les arêtes munies d'un identifiant stable, dérivé de
+// leurs extrémités, type, libellé et contexte, et du rang de l'arête parmi
+// celles qui partagent ces valeurs. Reparser un document inchangé redonne les
+// mêmes identifiants ; renommer une relation en change l'identifiant.
+func AssignEdgeIDs(edges []models.Edge) []models.Edge {
+	out := make([]models.Edge, len(edges))
+	occurrences := make(map[string]int)
+	for i, edge := range edges {
+		key := strings.Join([]string{edge.From, edge.Type, edge.Label, edge.To, edge.Context}, "\x00")
+		sum := sha1.Sum([]byte(key + "\x00" + strconv.Itoa(occurrences[key])))
+		occurrences[key]++
+		edge.ID = "e-" + hex.EncodeToString(sum[:6])
+		out[i] = edge
+	}
+	return out
+}
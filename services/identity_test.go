@@ -0,0 +1,137 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeNodeID(t *testing.T) {
+	for input, want := range map[string]string{
+		"Jean ":               "Jean",
+		`"Jean"`:              "Jean",
+		"« Le  Baron »":       "Le Baron",
+		"José":               "José",
+		`"Le" et "La"`:        `"Le" et "La"`,
+		"  Saint\tGermain  ":  "Saint Germain",
+		`'"citation" double'`: `"citation" double`,
+	} {
+		if got := NormalizeNodeID(input); got != want {
+			t.Errorf("NormalizeNodeID(%q) = %q, attendu %q", input, got, want)
+		}
+	}
+}
+
+func TestNodeIdentity(t *testing.T) {
+	source := strings.Join([]string{
+		"Jean -> connaît -> Marie",
+		"jean  -> aime -> Hélène",
+		`"Jean" -> craint -> helene`,
+		"Le Baron <-> Dupont",
+		"Dupont (=) M. Dupont",
+		"Baronne (alias) Mme Dupont",
+		"M. Dupont -> habite -> Manoir",
+	}, "\n")
+	parser := NewN4LParser()
+	notes := parser.ParseN4L(source).Notes
+
+	ids := func(policy IdentityPolicy) []string {
+		var out []string
+		for _, node := range parser.WithIdentityPolicy(policy).ParseN4LToGraph(notes).Nodes {
+			out = append(out, node.ID)
+		}
+		return out
+	}
+
+	if got, want := ids(DefaultIdentityPolicy), []string{
+		"Jean", "Marie", "Hélène", "helene", "Le Baron", "Dupont", "M. Dupont", "Baronne", "Mme Dupont", "Manoir",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("nœuds par défaut : %v", got)
+	}
+
+	merged := parser.WithIdentityPolicy(IdentityPolicy{FoldCase: true, FoldAccents: true, MergeAliases: true}).ParseN4LToGraph(notes)
+	var got []string
+	for _, node := range merged.Nodes {
+		got = append(got, node.ID)
+		if node.ID == "Le Baron" && !reflect.DeepEqual(node.Aliases, []string{"Dupont", "M. Dupont"}) {
+			t.Errorf("alias de Le Baron : %v", node.Aliases)
+		}
+	}
+	if want := []string{"Jean", "Marie", "Hélène", "Le Baron", "Baronne", "Manoir"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("nœuds fusionnés : %v", got)
+	}
+	for _, edge := range merged.Edges {
+		if edge.Type == "equivalence" || edge.Label == AliasLabel {
+			t.Errorf("équivalence interne conservée : %+v", edge)
+		}
+		if edge.Label == "habite" && edge.From != "Le Baron" {
+			t.Errorf("arête non redirigée : %+v", edge)
+		}
+	}
+}
+
+func TestMergeAliasesKeepsNegatedClass(t *testing.T) {
+	source := "A <-> B\nA (!eq) B\nC <-> D\nB -> connaît -> C"
+	parser := NewN4LParser()
+	graph := parser.WithIdentityPolicy(IdentityPolicy{FoldCase: true, MergeAliases: true}).ParseN4LToGraph(parser.ParseN4L(source).Notes)
+
+	var ids []string
+	for _, node := range graph.Nodes {
+		ids = append(ids, node.ID)
+	}
+	if want := []string{"A", "B", "C"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("nœuds %v, attendu %v", ids, want)
+	}
+	for _, edge := range graph.Edges {
+		if edge.From == edge.To {
+			t.Errorf("boucle créée par la fusion : %+v", edge)
+		}
+	}
+
+	var found bool
+	for _, inconsistency := range NewGraphAnalyzer().CheckSemanticConsistency(graph) {
+		if inconsistency.Type == "negated_equivalence" {
+			found = strings.HasPrefix(inconsistency.Description, "A et B ")
+		}
+	}
+	if !found {
+		t.Error("conflit entre A et B non signalé")
+	}
+}
+
+func TestAssignEdgeIDs(t *testing.T) {
+	source := strings.Join([]string{
+		"[Enquête]",
+		"Jean -> connaît -> Marie",
+		"Jean -> craint -> Marie",
+		"Jean -> connaît -> Marie",
+	}, "\n")
+	parser := NewN4LParser()
+	edges := func(source string) []string {
+		var ids []string
+		for _, edge := range parser.ParseN4LToGraph(parser.ParseN4L(source).Notes).Edges {
+			ids = append(ids, edge.ID)
+		}
+		return ids
+	}
+
+	first := edges(source)
+	if len(first) != 3 {
+		t.Fatalf("3 arêtes attendues, obtenu %v", first)
+	}
+	if !reflect.DeepEqual(first, edges(source)) {
+		t.Errorf("identifiants instables entre deux analyses : %v / %v", first, edges(source))
+	}
+	seen := make(map[string]bool)
+	for _, id := range first {
+		if id == "" || seen[id] {
+			t.Errorf("identifiant vide ou en double : %v", first)
+		}
+		seen[id] = true
+	}
+
+	relabeled := edges(strings.Replace(source, "craint", "redoute", 1))
+	if relabeled[0] != first[0] || relabeled[2] != first[2] || relabeled[1] == first[1] {
+		t.Errorf("seule la relation renommée doit changer d'identifiant : %v -> %v", first, relabeled)
+	}
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"n4l-editor/models"
+)
+
+// Codes des règles de lint, qui complètent les diagnostics du parser
+const (
+	LintOrphanSubject         = "N4L101" // sujet cité sans aucune relation
+	LintContradictoryRelation = "N4L102" // relations contradictoires entre deux éléments
+	LintNegatedFact           = "N4L103" // négation "(!eq)" ou "(!r)" contredite par le graphe
+)
+
+// N4LLinter vérifie un document N4L : diagnostics du parser (dont les
+// références non résolues) et règles de l'analyseur de graphe
+type N4LLinter struct {
+	parser   *N4LParser
+	analyzer *GraphAnalyzer
+}
+
+// NewN4LLinter crée une nouvelle instance du linter
+func NewN4LLinter() *N4LLinter {
+	return &N4LLinter{
+		parser:   NewN4LParser(),
+		analyzer: NewGraphAnalyzer(),
+	}
+}
+
+// Lint retourne les diagnostics d'un document, triés par position
+func (l *N4LLinter) Lint(content string) []models.Diagnostic {
+	parsed := l.parser.ParseN4L(content)
+	diagnostics := append([]models.Diagnostic{}, parsed.Diagnostics...)
+
+	graph := l.parser.ParseN4LToGraph(parsed.Notes)
+	index := newSourceIndex(parsed.Document)
+
+	// Les noms propres cités dans les notes deviennent des nœuds candidats,
+	// orphelins s'ils n'apparaissent dans aucune relation
+	known := make(map[string]bool)
+	for _, node := range graph.Nodes {
+		known[node.ID] = true
+	}
+	for _, subject := range parsed.Subjects {
+		if !known[subject] && l.analyzer.isProperNoun(subject) {
+			known[subject] = true
+			graph.Nodes = append(graph.Nodes, models.Node{ID: subject, Label: subject})
+		}
+	}
+
+	for _, orphan := range l.analyzer.findOrphanNodes(NewGraph(graph)) {
+		diagnostics = append(diagnostics, newDiagnostic(SeverityInfo, LintOrphanSubject,
+			index.subject(orphan), utf8.RuneCountInString(orphan),
+			"« %s » n'apparaît dans aucune relation", orphan))
+	}
+
+	for _, inconsistency := range l.analyzer.detectContradictoryRelations(graph) {
+		from, to := inconsistency.Nodes[0], inconsistency.Nodes[1]
+		diagnostics = append(diagnostics, newDiagnostic(SeverityWarning, LintContradictoryRelation,
+			index.relation(from, to), 0, "%s", inconsistency.Description))
+	}
+
+	for _, inconsistency := range l.analyzer.detectNegatedFacts(graph) {
+		from, to := inconsistency.Nodes[0], inconsistency.Nodes[len(inconsistency.Nodes)-1]
+		if inconsistency.Type == "negated_relation" {
+			to = inconsistency.Nodes[1]
+		}
+		diagnostics = append(diagnostics, newDiagnostic(SeverityError, LintNegatedFact,
+			index.negation(from, to), 0, "%s", inconsistency.Description))
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i].Pos, diagnostics[j].Pos
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return diagnostics
+}
+
+// sourceIndex retrouve dans l'AST la position des sujets et des relations
+type sourceIndex struct {
+	subjects  map[string]models.SourcePos
+	relations map[string]models.SourcePos
+	negations map[string]models.SourcePos
+	notes     []models.N4LStatement
+}
+
+func newSourceIndex(doc *models.N4LDocument) *sourceIndex {
+	index := &sourceIndex{
+		subjects:  make(map[string]models.SourcePos),
+		relations: make(map[string]models.SourcePos),
+		negations: make(map[string]models.SourcePos),
+	}
+	if doc == nil {
+		return index
+	}
+
+	remember := func(item models.N4LItem) {
+		if _, ok := index.subjects[item.Text]; !ok {
+			index.subjects[item.Text] = item.Pos
+		}
+	}
+	for _, context := range doc.Contexts {
+		for _, stmt := range context.Statements {
+			for _, rel := range stmt.Relations {
+				remember(rel.Source)
+				remember(rel.Target)
+				// La dernière occurrence est celle qui introduit la contradiction
+				switch rel.Kind {
+				case "relation", "equivalence":
+					index.relations[rel.Source.Text+"\x00"+rel.Target.Text] = rel.LabelPos
+				case "negation":
+					index.negations[rel.Source.Text+"\x00"+rel.Target.Text] = rel.LabelPos
+				}
+			}
+			if stmt.Group != nil {
+				remember(stmt.Group.Parent)
+				for _, member := range stmt.Group.Members {
+					remember(member)
+				}
+			}
+			if stmt.Kind == "note" {
+				index.notes = append(index.notes, stmt)
+			}
+		}
+	}
+	return index
+}
+
+// subject retourne la première position connue d'un sujet
+func (i *sourceIndex) subject(name string) models.SourcePos {
+	if pos, ok := i.subjects[name]; ok {
+		return pos
+	}
+	for _, stmt := range i.notes {
+		if idx := strings.Index(stmt.Raw, name); idx >= 0 {
+			return models.SourcePos{
+				Line:   stmt.Pos.Line,
+				Column: stmt.Pos.Column + utf8.RuneCountInString(stmt.Raw[:idx]),
+			}
+		}
+	}
+	return models.SourcePos{Line: 1, Column: 1}
+}
+
+// relation retourne la position d'une relation entre deux éléments
+func (i *sourceIndex) relation(from, to string) models.SourcePos {
+	if pos, ok := i.relations[from+"\x00"+to]; ok {
+		return pos
+	}
+	return i.subject(from)
+}
+
+// negation retourne la position d'une négation entre deux éléments
+func (i *sourceIndex) negation(from, to string) models.SourcePos {
+	if pos, ok := i.negations[from+"\x00"+to]; ok {
+		return pos
+	}
+	return i.relation(from, to)
+}
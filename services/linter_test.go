@@ -0,0 +1,32 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLintReportsAnalyzerRules(t *testing.T) {
+	source := strings.Join([]string{
+		"X -> ami de -> Y",
+		"X -> ennemi de -> Y",
+		"Z -> r -> $nope.1",
+		"Une note qui cite Dupont.",
+		"X (!ami) Y",
+	}, "\n")
+
+	codes := make(map[string]int)
+	for _, d := range NewN4LLinter().Lint(source) {
+		codes[d.Code] = d.Pos.Line
+	}
+
+	for code, line := range map[string]int{
+		LintContradictoryRelation: 2,
+		DiagUnresolvedReference:   3,
+		LintOrphanSubject:         4,
+		LintNegatedFact:           5,
+	} {
+		if got, ok := codes[code]; !ok || got != line {
+			t.Errorf("diagnostic %s attendu ligne %d, obtenu %v", code, line, codes)
+		}
+	}
+}
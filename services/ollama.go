@@ -122,6 +122,13 @@ func (s *OllamaService) AnalyzeGraph(graphData models.GraphData) (string, error)
 			sb.WriteString(fmt.Sprintf("- The group '%s' contains %s.\n", edge.From, edge.To))
 		}
 	}
+	for _, event := range graphData.Hyperedges {
+		var roles []string
+		for _, participant := range event.Participants {
+			roles = append(roles, fmt.Sprintf("%s: %s", participant.Role, participant.Node))
+		}
+		sb.WriteString(fmt.Sprintf("- The event '%s' involves %s.\n", event.Label, strings.Join(roles, ", ")))
+	}
 
 	prompt := fmt.Sprintf(`Vous êtes un assistant d'enquête intelligent.
 En vous basant uniquement sur les faits suivants, rédigez un résumé de la situation.
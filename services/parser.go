@@ -1,166 +1,531 @@
 package services
 
 import (
-	"bufio"
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"n4l-editor/models"
 )
 
+// SequenceLabel est le libellé des arêtes d'ordre entre les étapes d'une séquence
+const SequenceLabel = "then"
+
+// Négations : "A (!eq) B" nie l'équivalence de A et B, "A (!r) B" nie la relation r
+const (
+	NegationPrefix      = "!"
+	NonEquivalenceLabel = "eq"
+)
+
 // N4LParser gère le parsing des fichiers N4L
 type N4LParser struct {
 	contextRegex        *regexp.Regexp
-	relationRegex       *regexp.Regexp
 	equivalenceRegex    *regexp.Regexp
 	groupRegex          *regexp.Regexp
 	parenthesesRegex    *regexp.Regexp
+	relationLabelRegex  *regexp.Regexp
 	annotationRegex     *regexp.Regexp
 	referenceRegex      *regexp.Regexp
 	altEquivalenceRegex *regexp.Regexp
+	variableRegex       *regexp.Regexp
+	includeRegex        *regexp.Regexp
+	relations           *RelationRegistry
+	identity            IdentityPolicy
+	inProject           bool // conduit par projectLoader, qui résout les @include
 }
 
 // NewN4LParser crée une nouvelle instance du parser
 func NewN4LParser() *N4LParser {
 	return &N4LParser{
-		contextRegex:        regexp.MustCompile(`^:{2,}\s*(.*)\s*:{2,}$`),
-		relationRegex:       regexp.MustCompile(`^(.*) -> (.*) -> (.*)$`),
+		contextRegex:        regexp.MustCompile(`^(:{2,})\s*(.*?)\s*:{2,}$`),
 		equivalenceRegex:    regexp.MustCompile(`^(.*) <-> (.*)$`),
 		groupRegex:          regexp.MustCompile(`^(.*) => {(.*)}$`),
 		parenthesesRegex:    regexp.MustCompile(`^([^()]+)\s*\(([^)]+)\)\s*(.+)$`),
+		relationLabelRegex:  regexp.MustCompile(`\(([^()]+)\)`),
 		annotationRegex:     regexp.MustCompile(`>"([^"]+)"`),
-		referenceRegex:      regexp.MustCompile(`\$(\w+)\.(\d+)`),
+		referenceRegex:      regexp.MustCompile(`\$([\p{L}\p{N}_]+)\.(\d+)`),
 		altEquivalenceRegex: regexp.MustCompile(`^(.+)\s*\(=\)\s*(.+)$`),
+		variableRegex:       regexp.MustCompile(`^@([\p{L}\p{N}_]+)(?:\s+(.*))?$`),
+		includeRegex:        regexp.MustCompile(`^@include(?:\s+(.*))?$`),
+		relations:           DefaultRelationRegistry(),
+		identity:            DefaultIdentityPolicy,
 	}
 }
 
+// WithIdentityPolicy retourne une copie du parser qui identifie les nœuds
+// selon la politique donnée
+func (p *N4LParser) WithIdentityPolicy(policy IdentityPolicy) *N4LParser {
+	clone := *p
+	clone.identity = policy
+	return &clone
+}
+
+// IdentityPolicy retourne la politique d'identification des nœuds du parser
+func (p *N4LParser) IdentityPolicy() IdentityPolicy {
+	return p.identity
+}
+
 // ParseN4L parse le contenu d'un fichier N4L
 func (p *N4LParser) ParseN4L(content string) models.ParsedN4L {
+	doc, diagnostics := p.ParseDocument(content)
+
 	notes := make(map[string][]string)
+	spans := make(models.NoteSpans)
 	subjectsMap := make(map[string]bool)
-	currentContext := "general"
-	lastSubject := ""
-
-	scanner := bufio.NewScanner(strings.NewReader(content))
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-
-		// Ignorer les lignes vides, commentaires et séparateurs
-		if line == "" || strings.HasPrefix(line, "#") ||
-			strings.HasPrefix(line, "+::") || strings.HasPrefix(line, "-::") {
-			continue
+	for _, context := range doc.Contexts {
+		if _, ok := notes[context.Name]; !ok {
+			notes[context.Name] = []string{}
+			spans[context.Name] = []models.SourceSpan{}
 		}
-
-		// Gérer les contextes
-		if matches := p.contextRegex.FindStringSubmatch(line); len(matches) > 1 {
-			contextName := strings.TrimSpace(matches[1])
-			if contextName != "_sequence_" && contextName != "sequence" {
-				currentContext = contextName
-				if _, ok := notes[currentContext]; !ok {
-					notes[currentContext] = []string{}
+		for _, stmt := range context.Statements {
+			if stmt.Note == "" {
+				continue
+			}
+			span := models.SourceSpan{StartLine: stmt.Pos.Line, EndLine: stmt.Pos.Line, Text: stmt.Raw}
+			notes[context.Name] = append(notes[context.Name], stmt.Note)
+			spans[context.Name] = append(spans[context.Name], span)
+			for _, rel := range stmt.Relations {
+				if rel.Kind == "sequence" {
+					// L'arête d'ordre couvre l'étape précédente et celle-ci
+					notes[context.Name] = append(notes[context.Name],
+						fmt.Sprintf("%s -> %s -> %s", rel.Source.Text, rel.Label, rel.Target.Text))
+					sequenceSpan := span
+					if rel.Source.Pos.Line > 0 {
+						sequenceSpan.StartLine = rel.Source.Pos.Line
+					}
+					spans[context.Name] = append(spans[context.Name], sequenceSpan)
 				}
 			}
-			continue
+			for _, s := range stmt.Subjects {
+				subjectsMap[s] = true
+			}
 		}
+	}
 
-		// Nettoyer et parser la ligne
-		cleanedLine, extractedSubjects := p.cleanAnnotations(line)
-		for _, subject := range extractedSubjects {
-			subjectsMap[subject] = true
+	// Convertir la map en slice
+	var subjects []string
+	for s := range subjectsMap {
+		if isNodeName(s) {
+			subjects = append(subjects, s)
 		}
+	}
 
-		// Gérer les références
-		cleanedLine = p.handleReferences(cleanedLine, lastSubject)
+	contextNames := make([]string, 0, len(notes))
+	for name := range notes {
+		contextNames = append(contextNames, name)
+	}
 
-		// Parser les différentes syntaxes
-		if note, subjects := p.parseParenthesesSyntax(cleanedLine, lastSubject, notes[currentContext]); note != "" {
-			notes[currentContext] = append(notes[currentContext], note)
-			for _, s := range subjects {
-				subjectsMap[s] = true
-			}
-			if len(subjects) > 0 {
-				lastSubject = subjects[0]
+	return models.ParsedN4L{
+		Subjects:    subjects,
+		Notes:       notes,
+		Spans:       spans,
+		Contexts:    BuildContextTree(contextNames),
+		Document:    doc,
+		Diagnostics: diagnostics,
+	}
+}
+
+// ParseDocument construit l'arbre syntaxique positionné d'un contenu N4L
+// ainsi que la liste des diagnostics rencontrés
+func (p *N4LParser) ParseDocument(content string) (*models.N4LDocument, []models.Diagnostic) {
+	b := &documentBuilder{
+		doc:       &models.N4LDocument{Contexts: []models.N4LContext{}},
+		current:   -1,
+		variables: make(map[string]int),
+	}
+
+	for i, raw := range strings.Split(content, "\n") {
+		p.parseLine(b, strings.TrimRight(raw, "\r"), i+1)
+	}
+
+	if b.diagnostics == nil {
+		b.diagnostics = []models.Diagnostic{}
+	}
+	return b.doc, b.diagnostics
+}
+
+// documentBuilder accumule l'AST et les diagnostics pendant le parsing
+type documentBuilder struct {
+	doc         *models.N4LDocument
+	diagnostics []models.Diagnostic
+	current     int
+	lastSubject string
+	prevItems   []string       // éléments de la dernière instruction structurée ($PREV)
+	variables   map[string]int // nom de variable -> index dans doc.Variables
+	headers     [][]string     // chemins de contexte ouverts à chaque profondeur
+	sequence    bool           // mode séquence : chaque instruction suit la précédente
+	sequenceEnd *models.N4LItem
+}
+
+// openContext ouvre une nouvelle section de contexte
+func (b *documentBuilder) openContext(name string, depth int, pos models.SourcePos) {
+	b.doc.Contexts = append(b.doc.Contexts, models.N4LContext{
+		Name:       name,
+		Paths:      ContextPaths(name),
+		Depth:      depth,
+		Pos:        pos,
+		Statements: []models.N4LStatement{},
+	})
+	b.current = len(b.doc.Contexts) - 1
+}
+
+// openHeader ouvre la section décrite par un en-tête ":: a | b ::" de profondeur
+// donnée ; un sous-contexte "::: c :::" est rattaché à chacun des contextes parents
+func (b *documentBuilder) openHeader(names []string, depth int, pos models.SourcePos) {
+	if depth > len(b.headers)+1 {
+		depth = len(b.headers) + 1
+	}
+
+	parents := []string{""}
+	if depth > 1 {
+		parents = b.headers[depth-2]
+	}
+
+	var paths []string
+	for _, parent := range parents {
+		for _, name := range names {
+			if parent != "" {
+				name = parent + ContextPathSeparator + name
 			}
-			continue
+			paths = append(paths, name)
 		}
+	}
 
-		if note, subjects := p.parseStandardSyntax(cleanedLine); note != "" {
-			notes[currentContext] = append(notes[currentContext], note)
-			for _, s := range subjects {
-				subjectsMap[s] = true
-			}
-			if len(subjects) > 0 {
-				lastSubject = subjects[0]
-			}
-			continue
+	b.headers = append(b.headers[:depth-1], paths)
+	b.openContext(strings.Join(paths, ContextListSeparator), depth, pos)
+}
+
+// addStatement ajoute une instruction au contexte courant ("general" par défaut)
+func (b *documentBuilder) addStatement(stmt models.N4LStatement) {
+	if b.current < 0 {
+		b.openContext("general", 1, models.SourcePos{Line: stmt.Pos.Line, Column: 1})
+	}
+	b.doc.Contexts[b.current].Statements = append(b.doc.Contexts[b.current].Statements, stmt)
+}
+
+// define enregistre une variable "@nom" dans la table des symboles
+func (b *documentBuilder) define(name string, pos models.SourcePos, items []string) {
+	variable := models.N4LVariable{Name: name, Pos: pos, Items: items}
+	if idx, exists := b.variables[name]; exists {
+		previous := b.doc.Variables[idx]
+		b.report(SeverityWarning, DiagDuplicateVariable, pos, utf8.RuneCountInString(name)+1,
+			"variable @%s redéfinie (déjà définie ligne %d)", name, previous.Pos.Line)
+		b.doc.Variables[idx] = variable
+		return
+	}
+	b.variables[name] = len(b.doc.Variables)
+	b.doc.Variables = append(b.doc.Variables, variable)
+}
+
+// setSequence active ou désactive le mode séquence
+func (b *documentBuilder) setSequence(on bool) {
+	b.sequence = on
+	b.sequenceEnd = nil
+}
+
+// follow relie, en mode séquence, l'élément principal d'une instruction à
+// celui de l'instruction précédente par une arête d'ordre
+func (b *documentBuilder) follow(stmt *models.N4LStatement, anchor models.N4LItem) {
+	if !b.sequence || anchor.Text == "" {
+		return
+	}
+	if b.sequenceEnd != nil && b.sequenceEnd.Text != anchor.Text {
+		stmt.Relations = append(stmt.Relations, models.N4LRelation{
+			Kind:   "sequence",
+			Source: *b.sequenceEnd,
+			Label:  SequenceLabel,
+			Target: anchor,
+		})
+	}
+	b.sequenceEnd = &anchor
+}
+
+// lookup retourne les éléments désignés par une variable ou par PREV
+func (b *documentBuilder) lookup(name string) ([]string, bool) {
+	if name == "PREV" {
+		return b.prevItems, len(b.prevItems) > 0
+	}
+	if idx, ok := b.variables[name]; ok {
+		return b.doc.Variables[idx].Items, true
+	}
+	return nil, false
+}
+
+// lastNote retourne la dernière note du contexte courant
+func (b *documentBuilder) lastNote() string {
+	if b.current < 0 {
+		return ""
+	}
+	statements := b.doc.Contexts[b.current].Statements
+	if len(statements) == 0 {
+		return ""
+	}
+	return statements[len(statements)-1].Note
+}
+
+func (b *documentBuilder) report(severity, code string, pos models.SourcePos, length int, format string, args ...interface{}) {
+	b.diagnostics = append(b.diagnostics, newDiagnostic(severity, code, pos, length, format, args...))
+}
+
+// parseLine analyse une ligne du source et l'ajoute à l'AST
+func (p *N4LParser) parseLine(b *documentBuilder, raw string, lineNo int) {
+	line := strings.TrimSpace(raw)
+	loc := newLineLocator(lineNo, raw)
+
+	// Ignorer les lignes vides et commentaires
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+
+	// Les blocs "+:: _sequence_ ::" ... "-:: _sequence_ ::" activent le mode
+	// séquence ; les autres blocs balisés sont de simples séparateurs
+	if strings.HasPrefix(line, "+::") || strings.HasPrefix(line, "-::") {
+		if matches := p.contextRegex.FindStringSubmatch(line[1:]); len(matches) > 2 && isSequenceMarker(matches[2]) {
+			b.setSequence(line[0] == '+')
 		}
+		return
+	}
 
-		// Si ce n'est pas une relation reconnue, extraire les mots capitalisés
-		if cleanedLine != "" && !strings.HasPrefix(cleanedLine, "::") {
-			words := strings.Fields(cleanedLine)
-			for _, word := range words {
-				word = strings.Trim(word, `"'.,;:!?`)
-				if len(word) > 2 && unicode.IsUpper(rune(word[0])) {
-					subjectsMap[word] = true
-				}
+	// Gérer les contextes : ":: a | b ::" liste plusieurs contextes,
+	// chaque ":" supplémentaire descend d'un niveau dans la hiérarchie
+	if matches := p.contextRegex.FindStringSubmatch(line); len(matches) > 2 {
+		var names []string
+		sequence := false
+		for _, name := range strings.Split(matches[2], "|") {
+			if name = strings.TrimSpace(name); isSequenceMarker(name) {
+				sequence = true
+			} else if name != "" {
+				names = append(names, name)
 			}
-			notes[currentContext] = append(notes[currentContext], cleanedLine)
 		}
+		switch {
+		case len(names) > 0:
+			// Un nouvel en-tête termine la séquence, sauf s'il en déclare une
+			b.openHeader(names, len(matches[1])-1, loc.start())
+			b.setSequence(sequence)
+		case sequence:
+			// ":: _sequence_ ::" seul passe la section courante en mode séquence
+			b.setSequence(true)
+		default:
+			b.report(SeverityWarning, DiagEmptyContext, loc.start(), loc.width(), "en-tête de contexte sans nom")
+		}
+		return
 	}
 
-	// Convertir la map en slice
-	var subjects []string
-	for s := range subjectsMap {
-		if s != "" && s != `""` && s != "[" && s != "]" {
-			subjects = append(subjects, s)
+	stmt := models.N4LStatement{
+		Pos: loc.start(),
+		Raw: line,
+	}
+
+	// Les directives "@include chemin" sont résolues par le chargeur de projet
+	if matches := p.includeRegex.FindStringSubmatch(line); matches != nil {
+		path := strings.TrimSpace(strings.Trim(strings.TrimSpace(matches[1]), `"'`))
+		if path == "" {
+			b.report(SeverityError, DiagEmptyInclude, stmt.Pos, loc.width(), "directive @include sans chemin")
+			return
+		}
+		if !p.inProject {
+			b.report(SeverityWarning, DiagIgnoredInclude, stmt.Pos, loc.width(), "@include ignoré hors projet : %s", path)
 		}
+		b.doc.Includes = append(b.doc.Includes, models.N4LInclude{Path: path, Pos: stmt.Pos})
+		return
 	}
 
-	return models.ParsedN4L{
-		Subjects: subjects,
-		Notes:    notes,
+	// Qualificatifs "[certitude=…; source=…; poids=…]" en fin de ligne
+	if rest, text, ok := splitQualifier(line); ok {
+		qualifier, problems := parseQualifier(text)
+		qualifier.Pos = newLineLocator(lineNo, raw).locate("[" + text)
+		for _, problem := range problems {
+			b.report(SeverityWarning, DiagInvalidQualifier, qualifier.Pos, utf8.RuneCountInString(text)+2, "%s", problem)
+		}
+		stmt.Qualifier = &qualifier
+		line = strings.TrimSpace(rest)
+	}
+	reported := len(b.diagnostics)
+
+	// Gérer les définitions de variables "@nom contenu"
+	if matches := p.variableRegex.FindStringSubmatch(line); len(matches) == 3 {
+		stmt.Alias = matches[1]
+		line = strings.TrimSpace(matches[2])
+		if line == "" {
+			b.report(SeverityError, DiagEmptyVariable, stmt.Pos, loc.width(), "variable @%s définie sans contenu", stmt.Alias)
+			return
+		}
+	}
+
+	// Nettoyer et parser la ligne
+	cleanedLine, extractedSubjects := p.cleanAnnotations(line)
+	stmt.Subjects = append(stmt.Subjects, extractedSubjects...)
+
+	// Gérer les références
+	cleanedLine, stmt.References = p.handleReferences(b, cleanedLine, loc)
+
+	// Parser les différentes syntaxes
+	if p.parseParenthesesSyntax(b, cleanedLine, loc, &stmt) || p.parseStandardSyntax(cleanedLine, loc, &stmt) {
+		items := stmt.Subjects[len(extractedSubjects):]
+		if len(items) > 0 {
+			b.lastSubject = items[0]
+		}
+		b.prevItems = items
+		if stmt.Alias != "" {
+			b.define(stmt.Alias, stmt.Pos, items)
+		}
+		b.follow(&stmt, statementAnchor(stmt))
+		stmt.Note = qualifyNote(stmt.Note, stmt.Qualifier)
+		b.addStatement(stmt)
+		return
+	}
+
+	// Une définition qui n'est pas une relation associe simplement une valeur à la variable
+	if stmt.Alias != "" {
+		value := strings.TrimSpace(strings.Trim(cleanedLine, `"'`))
+		stmt.Kind = "definition"
+		b.define(stmt.Alias, stmt.Pos, []string{value})
+		b.addStatement(stmt)
+		return
+	}
+
+	// Si ce n'est pas une relation reconnue, extraire les mots capitalisés
+	if cleanedLine != "" && !strings.HasPrefix(cleanedLine, "::") {
+		if len(b.diagnostics) == reported {
+			p.reportMalformed(b, cleanedLine, loc)
+		}
+
+		words := strings.Fields(cleanedLine)
+		for _, word := range words {
+			word = strings.Trim(word, `"'.,;:!?`)
+			if len(word) > 2 && unicode.IsUpper(rune(word[0])) {
+				stmt.Subjects = append(stmt.Subjects, word)
+			}
+		}
+		stmt.Kind = "note"
+		stmt.Note = qualifyNote(cleanedLine, stmt.Qualifier)
+		b.follow(&stmt, models.N4LItem{Text: cleanedLine, Pos: stmt.Pos})
+		b.addStatement(stmt)
 	}
 }
 
 // ParseN4LToGraph convertit les notes N4L en graphe
 func (p *N4LParser) ParseN4LToGraph(n4lNotes map[string][]string) models.GraphData {
-	nodesMap := make(map[string]string) // Map ID to context
+	return p.ParseN4LToGraphWithSpans(n4lNotes, nil)
+}
+
+// ParseN4LToGraphWithSpans convertit les notes en graphe en rattachant à chaque
+// nœud et arête les lignes source des notes (voir ParsedN4L.Spans). Les
+// contextes dont les spans ne correspondent pas aux notes en sont privés.
+func (p *N4LParser) ParseN4LToGraphWithSpans(n4lNotes map[string][]string, spans models.NoteSpans) models.GraphData {
+	nodesMap := make(map[string]*models.Node)
+	var nodeOrder []string
 	var edges []models.Edge
+	ids := newNodeIdentity(p.identity)
+	events := make(map[string]bool) // nœuds d'événements n-aires
 
-	for context, notes := range n4lNotes {
-		for _, note := range notes {
+	// Parcourir les contextes dans un ordre stable pour que le contexte
+	// principal d'un nœud ne dépende pas de l'itération de la map
+	contextNames := make([]string, 0, len(n4lNotes))
+	for context := range n4lNotes {
+		contextNames = append(contextNames, context)
+	}
+	sort.Strings(contextNames)
+
+	for _, context := range contextNames {
+		paths := ContextPaths(context)
+		contextSpans := spans[context]
+		if len(contextSpans) != len(n4lNotes[context]) {
+			contextSpans = nil
+		}
+
+		for i, note := range n4lNotes[context] {
 			// Nettoyer les annotations
 			cleanedNote, _ := p.cleanAnnotations(note)
 
 			// Parser les différentes syntaxes
-			if edge, nodes := p.parseNoteToEdge(cleanedNote, context); edge != nil {
-				edges = append(edges, *edge)
-				for _, node := range nodes {
-					nodesMap[node] = context
+			noteEdges, nodes := p.parseNoteToEdges(cleanedNote, context)
+			var noteSpans []models.SourceSpan
+			if contextSpans != nil {
+				noteSpans = contextSpans[i : i+1 : i+1]
+			}
+			// Les relations de propriété deviennent des attributs : leur
+			// valeur n'est un nœud que si une autre arête la mentionne
+			var properties []models.Edge
+			linked := make(map[string]bool)
+			for _, edge := range noteEdges {
+				edge.From = ids.resolve(edge.From)
+				if edge.Type == "role" {
+					events[edge.From] = true
+				}
+				if t, ok := p.relations.Lookup(edge.Label); ok && t.ValueType != "" && (edge.Type == "relation" || edge.Type == "role") {
+					edge.To = NormalizeNodeID(edge.To)
+					if edge.From != "" && edge.To != "" {
+						properties = append(properties, edge)
+						linked[edge.From] = true
+					}
+					continue
 				}
+				edge.To = ids.resolve(edge.To)
+				if !isNodeName(edge.From) || !isNodeName(edge.To) {
+					continue
+				}
+				edge.Spans = noteSpans
+				edges = append(edges, edge)
+				linked[edge.From], linked[edge.To] = true, true
+			}
+			for _, nodeID := range nodes {
+				nodeID = ids.resolve(nodeID)
+				if !linked[nodeID] {
+					continue
+				}
+				node, exists := nodesMap[nodeID]
+				if !exists {
+					node = &models.Node{ID: nodeID, Label: nodeID, Context: context}
+					nodesMap[nodeID] = node
+					nodeOrder = append(nodeOrder, nodeID)
+				}
+				node.Contexts = mergeContexts(node.Contexts, paths)
+				node.Spans = MergeSpans(node.Spans, noteSpans)
+			}
+			for _, edge := range properties {
+				t, _ := p.relations.Lookup(edge.Label)
+				property := parseProperty(t, edge.To, context)
+				property.Spans = noteSpans
+				property.Confidence, property.Source = edge.Confidence, edge.Source
+				node := nodesMap[edge.From]
+				node.Properties = append(node.Properties, property)
 			}
 		}
 	}
 
 	// Créer les nœuds
 	var nodes []models.Node
-	for nodeID, context := range nodesMap {
-		if nodeID != "" && nodeID != `""` && nodeID != "[" && nodeID != "]" {
-			nodes = append(nodes, models.Node{
-				ID:      nodeID,
-				Label:   nodeID,
-				Context: context,
-			})
+	for _, nodeID := range nodeOrder {
+		if isNodeName(nodeID) {
+			if events[nodeID] {
+				nodesMap[nodeID].Kind = EventKind
+			}
+			nodes = append(nodes, *nodesMap[nodeID])
 		}
 	}
 
-	return models.GraphData{
+	graph := models.GraphData{
 		Nodes: nodes,
 		Edges: edges,
 	}
+	if p.identity.MergeAliases {
+		graph = mergeAliases(graph)
+	}
+	graph.Edges = AssignEdgeIDs(graph.Edges)
+	graph.Hyperedges = buildHyperedges(graph)
+	return graph
+}
+
+// isNodeName écarte les noms vides et les restes de ponctuation ('""', "[", "]")
+func isNodeName(name string) bool {
+	return name != "" && name != `""` && name != "[" && name != "]"
 }
 
 // cleanAnnotations nettoie les annotations et extrait les sujets
@@ -179,115 +544,351 @@ func (p *N4LParser) cleanAnnotations(line string) (string, []string) {
 	return cleanedLine, subjects
 }
 
-// handleReferences gère les références $variable
-func (p *N4LParser) handleReferences(line, lastSubject string) string {
+// handleReferences résout les références "$nom.N" (N-ième élément de la ligne
+// définie par @nom) et "$PREV.N" (N-ième élément de l'instruction précédente)
+func (p *N4LParser) handleReferences(b *documentBuilder, line string, loc *lineLocator) (string, []models.N4LReference) {
+	var refs []models.N4LReference
+
 	if matches := p.referenceRegex.FindAllStringSubmatch(line, -1); len(matches) > 0 {
+		refLoc := newLineLocator(loc.line, loc.raw)
 		for _, match := range matches {
-			if match[1] == "goal" || match[1] == "PREV" {
-				if lastSubject != "" {
-					line = strings.ReplaceAll(line, match[0], lastSubject)
-				} else {
-					line = strings.ReplaceAll(line, match[0], "[REF:"+match[1]+"]")
-				}
+			index, _ := strconv.Atoi(match[2])
+			ref := models.N4LReference{
+				Name:  match[1],
+				Index: index,
+				Pos:   refLoc.locate(match[0]),
+			}
+			length := utf8.RuneCountInString(match[0])
+
+			items, found := b.lookup(ref.Name)
+			switch {
+			case !found && ref.Name == "PREV":
+				b.report(SeverityWarning, DiagUnresolvedReference, ref.Pos, length,
+					"référence %s non résolue : aucune instruction précédente", match[0])
+			case !found:
+				b.report(SeverityWarning, DiagUnresolvedReference, ref.Pos, length,
+					"référence %s non résolue : variable @%s non définie", match[0], ref.Name)
+			case index < 1 || index > len(items):
+				b.report(SeverityWarning, DiagUnresolvedReference, ref.Pos, length,
+					"référence %s hors limites : @%s ne contient que %d élément(s)", match[0], ref.Name, len(items))
+			default:
+				ref.Resolved = items[index-1]
+				line = strings.ReplaceAll(line, match[0], ref.Resolved)
 			}
+			refs = append(refs, ref)
 		}
 	}
-	return line
+	return line, refs
 }
 
 // parseParenthesesSyntax parse la syntaxe avec parenthèses
-func (p *N4LParser) parseParenthesesSyntax(line, lastSubject string, currentNotes []string) (string, []string) {
-	if matches := p.parenthesesRegex.FindStringSubmatch(line); len(matches) == 4 {
-		source := strings.TrimSpace(matches[1])
-		relation := strings.TrimSpace(matches[2])
-		target := strings.TrimSpace(matches[3])
+func (p *N4LParser) parseParenthesesSyntax(b *documentBuilder, line string, loc *lineLocator, stmt *models.N4LStatement) bool {
+	// Équivalence alternative (à tester avant la relation, "(=)" étant aussi une parenthèse)
+	if matches := p.altEquivalenceRegex.FindStringSubmatch(line); len(matches) == 3 {
+		source := strings.Trim(strings.TrimSpace(matches[1]), `"`)
+		target := strings.Trim(strings.TrimSpace(matches[2]), `"`)
+
+		if source != "" && target != "" {
+			sourcePos := loc.locate(source)
+			labelPos := loc.locate("(=)")
+			targetPos := loc.locate(target)
+			stmt.Kind = "equivalence"
+			stmt.Note = fmt.Sprintf("%s <-> %s", source, target)
+			stmt.Subjects = append(stmt.Subjects, source, target)
+			stmt.Relations = append(stmt.Relations, models.N4LRelation{
+				Kind:     "equivalence",
+				Source:   models.N4LItem{Text: source, Pos: sourcePos},
+				LabelPos: labelPos,
+				Target:   models.N4LItem{Text: target, Pos: targetPos},
+			})
+			return true
+		}
+	}
+
+	if items, labels, ok := p.parenthesesChain(line); ok {
+		source := items[0]
+		sourcePos := loc.locate(source)
 
 		// Gérer les références vides
 		if source == `""` || source == `"` || source == "" {
-			if lastSubject != "" {
-				source = lastSubject
-			} else if len(currentNotes) > 0 {
-				source = ExtractFirstSubject(currentNotes[len(currentNotes)-1])
+			if b.lastSubject != "" {
+				source = b.lastSubject
+			} else if note := b.lastNote(); note != "" {
+				source = ExtractFirstSubject(note)
+			}
+			if source == `""` || source == `"` || source == "" {
+				b.report(SeverityError, DiagDanglingContinuation, sourcePos, 1,
+					"continuation '\"' sans sujet précédent")
+				return false
 			}
 		}
+		items[0] = strings.Trim(source, `"`)
+
+		// "A (r1) B (r2) C" devient la chaîne "A -> r1 -> B -> r2 -> C"
+		parts := []string{items[0]}
+		current := models.N4LItem{Text: items[0], Pos: sourcePos}
+		for i, relation := range labels {
+			labelPos := loc.locate("(" + relation + ")")
+			labelPos.Column++
+			target := models.N4LItem{Text: strings.Trim(items[i+1], `"`)}
+			target.Pos = loc.locate(items[i+1])
+
+			stmt.Relations = append(stmt.Relations, models.N4LRelation{
+				Kind:     relationKind(relation),
+				Source:   current,
+				Label:    relation,
+				LabelPos: labelPos,
+				Target:   target,
+			})
+			parts = append(parts, relation, target.Text)
+			current = target
+		}
 
-		if source != "" && source != `""` && target != "" {
-			source = strings.Trim(source, `"`)
-			target = strings.Trim(target, `"`)
-			normalizedNote := fmt.Sprintf("%s -> %s -> %s", source, relation, target)
-			return normalizedNote, []string{source, target}
+		stmt.Kind = "relation"
+		stmt.Note = strings.Join(parts, " -> ")
+		seen := make(map[string]bool)
+		for _, item := range items {
+			for _, name := range p.splitItems(strings.Trim(item, `"`)) {
+				if !seen[name] {
+					seen[name] = true
+					stmt.Subjects = append(stmt.Subjects, name)
+				}
+			}
 		}
+		return true
 	}
 
-	// Équivalence alternative
-	if matches := p.altEquivalenceRegex.FindStringSubmatch(line); len(matches) == 3 {
-		source := strings.Trim(strings.TrimSpace(matches[1]), `"`)
-		target := strings.Trim(strings.TrimSpace(matches[2]), `"`)
+	return false
+}
 
-		if source != "" && target != "" {
-			normalizedNote := fmt.Sprintf("%s <-> %s", source, target)
-			return normalizedNote, []string{source, target}
-		}
+// parenthesesChain découpe une relation à parenthèses, éventuellement chaînée :
+// "A (r1) B (r2) C" donne [A B C] et [r1 r2]. La source peut être vide ou '"'
+// (continuation), mais chaque cible doit être renseignée. Des parenthèses en
+// fin de ligne précisent la dernière cible : "Jean (aime) Marie (environ)" a
+// pour cible "Marie (environ)".
+func (p *N4LParser) parenthesesChain(line string) ([]string, []string, bool) {
+	// Une relation fléchée peut contenir des parenthèses dans ses noms
+	if strings.Contains(line, " -> ") || !p.parenthesesRegex.MatchString(line) {
+		return nil, nil, false
+	}
+
+	matches := p.relationLabelRegex.FindAllStringSubmatchIndex(line, -1)
+	for len(matches) > 1 && strings.TrimSpace(line[matches[len(matches)-1][1]:]) == "" {
+		matches = matches[:len(matches)-1]
 	}
 
-	return "", nil
+	var items, labels []string
+	last := 0
+	for _, m := range matches {
+		items = append(items, strings.TrimSpace(line[last:m[0]]))
+		labels = append(labels, strings.TrimSpace(line[m[2]:m[3]]))
+		last = m[1]
+	}
+	items = append(items, strings.TrimSpace(line[last:]))
+
+	if len(labels) == 0 {
+		return nil, nil, false
+	}
+	for i, item := range items[1:] {
+		if strings.Trim(item, `"`) == "" || labels[i] == "" {
+			return nil, nil, false
+		}
+	}
+	return items, labels, true
 }
 
 // parseStandardSyntax parse les syntaxes standard
-func (p *N4LParser) parseStandardSyntax(line string) (string, []string) {
-	// Relation standard
-	if matches := p.relationRegex.FindStringSubmatch(line); len(matches) == 4 {
-		source := strings.TrimSpace(matches[1])
-		target := strings.TrimSpace(matches[3])
-		return line, []string{source, target}
+func (p *N4LParser) parseStandardSyntax(line string, loc *lineLocator, stmt *models.N4LStatement) bool {
+	// Relation standard, éventuellement chaînée ou à cibles multiples
+	if items, labels, ok := p.relationChain(line); ok {
+		stmt.Kind = "relation"
+		stmt.Note = line
+		seen := make(map[string]bool)
+		for _, item := range items {
+			for _, name := range p.splitItems(item) {
+				if !seen[name] {
+					seen[name] = true
+					stmt.Subjects = append(stmt.Subjects, name)
+				}
+			}
+		}
+
+		positions := make(map[string]models.SourcePos)
+		for i, label := range labels {
+			for _, source := range p.splitItems(items[i]) {
+				sourcePos, ok := positions[source]
+				if !ok {
+					sourcePos = loc.locate(source)
+					positions[source] = sourcePos
+				}
+				labelPos := loc.locate(label)
+				for _, target := range p.splitItems(items[i+1]) {
+					targetPos, ok := positions[target]
+					if !ok {
+						targetPos = loc.locate(target)
+						positions[target] = targetPos
+					}
+					stmt.Relations = append(stmt.Relations, models.N4LRelation{
+						Kind:     relationKind(label),
+						Source:   models.N4LItem{Text: source, Pos: sourcePos},
+						Label:    label,
+						LabelPos: labelPos,
+						Target:   models.N4LItem{Text: target, Pos: targetPos},
+					})
+				}
+			}
+		}
+		return true
 	}
 
 	// Équivalence standard
 	if matches := p.equivalenceRegex.FindStringSubmatch(line); len(matches) == 3 {
 		source := strings.TrimSpace(matches[1])
 		target := strings.TrimSpace(matches[2])
-		return line, []string{source, target}
+		stmt.Kind = "equivalence"
+		stmt.Note = line
+		stmt.Subjects = append(stmt.Subjects, source, target)
+		stmt.Relations = append(stmt.Relations, models.N4LRelation{
+			Kind:     "equivalence",
+			Source:   models.N4LItem{Text: source, Pos: loc.locate(source)},
+			LabelPos: loc.locate("<->"),
+			Target:   models.N4LItem{Text: target, Pos: loc.locate(target)},
+		})
+		return true
 	}
 
 	// Groupe
 	if matches := p.groupRegex.FindStringSubmatch(line); len(matches) == 3 {
 		parent := strings.TrimSpace(matches[1])
-		children := strings.Split(matches[2], ";")
 
+		group := &models.N4LGroup{
+			Parent:  models.N4LItem{Text: parent, Pos: loc.locate(parent)},
+			Members: []models.N4LItem{},
+		}
 		subjects := []string{parent}
-		for _, child := range children {
-			childName := strings.Trim(strings.TrimSpace(child), `"`)
-			if childName != "" {
-				subjects = append(subjects, childName)
+		members := p.splitMembers(matches[2])
+		roles, participants, isEvent := splitRoles(members)
+		if isEvent {
+			members = participants
+		}
+		for i, childName := range members {
+			subjects = append(subjects, childName)
+			member := models.N4LItem{Text: childName, Pos: loc.locate(childName)}
+			if isEvent {
+				member.Role = roles[i]
 			}
+			group.Members = append(group.Members, member)
 		}
-		return line, subjects
+		stmt.Kind = "group"
+		if isEvent {
+			stmt.Kind = "event"
+		}
+		stmt.Note = line
+		stmt.Subjects = append(stmt.Subjects, subjects...)
+		stmt.Group = group
+		return true
 	}
 
-	return "", nil
+	return false
 }
 
-// parseNoteToEdge convertit une note en arête
-func (p *N4LParser) parseNoteToEdge(note, context string) (*models.Edge, []string) {
-	// Relation
-	if matches := p.relationRegex.FindStringSubmatch(note); len(matches) == 4 {
-		source := strings.TrimSpace(matches[1])
-		label := strings.TrimSpace(matches[2])
-		target := strings.TrimSpace(matches[3])
+// reportMalformed signale les lignes qui ressemblent à une syntaxe N4L sans la respecter
+func (p *N4LParser) reportMalformed(b *documentBuilder, line string, loc *lineLocator) {
+	pos, width := loc.start(), loc.width()
+	segments := len(strings.Split(line, " -> "))
 
-		// Nettoyer les références
-		source = strings.TrimPrefix(strings.TrimSuffix(source, "]"), "[REF:")
-		target = strings.TrimPrefix(strings.TrimSuffix(target, "]"), "[REF:")
+	switch {
+	case strings.Count(line, "(") != strings.Count(line, ")"):
+		b.report(SeverityWarning, DiagUnbalancedParentheses, pos, width, "parenthèses non appariées")
+	case strings.Contains(line, "=>"):
+		if strings.Contains(line, "{") && !strings.Contains(line, "}") {
+			b.report(SeverityError, DiagMalformedGroup, pos, width, "groupe non fermé : '}' attendu")
+		} else {
+			b.report(SeverityError, DiagMalformedGroup, pos, width, "groupe mal formé : attendu 'Parent => { a; b; c }'")
+		}
+	case segments >= 4 && segments%2 == 0:
+		b.report(SeverityWarning, DiagIncompleteRelation, pos, width, "relation sans cible : la chaîne se termine par une relation")
+	case strings.Contains(line, "->") || strings.Contains(line, "<->"):
+		b.report(SeverityWarning, DiagIncompleteRelation, pos, width, "relation incomplète : attendu 'source -> relation -> cible'")
+	case strings.HasPrefix(line, "("):
+		b.report(SeverityWarning, DiagIncompleteRelation, pos, width, "relation sans source")
+	case strings.HasSuffix(line, ")") && strings.Contains(line, "("):
+		b.report(SeverityWarning, DiagIncompleteRelation, pos, width, "relation sans cible")
+	case b.sequence:
+		// Une étape de séquence est reliée à ses voisines : ce n'est pas une note libre
+	default:
+		b.report(SeverityInfo, DiagUnstructuredNote, pos, width, "note libre : aucune relation reconnue")
+	}
+}
 
-		if source != "" && target != "" {
-			return &models.Edge{
-				From:    source,
-				To:      target,
-				Label:   label,
-				Type:    "relation",
-				Context: context,
-			}, []string{source, target}
+// qualifyNote ajoute à une note la forme canonique de ses qualificatifs
+func qualifyNote(note string, q *models.N4LQualifier) string {
+	if q == nil {
+		return note
+	}
+	if text := formatQualifier(q.Confidence, q.Source, q.Weight); text != "" {
+		return note + " " + text
+	}
+	return note
+}
+
+// parseNoteToEdges convertit une note en arêtes, qui reçoivent toutes les
+// qualificatifs éventuels de la note. Une même instruction peut en produire
+// plusieurs : groupes, relations chaînées et cibles multiples.
+func (p *N4LParser) parseNoteToEdges(note, context string) ([]models.Edge, []string) {
+	note, text, qualified := splitQualifier(note)
+	edges, nodes := p.parseStatementEdges(strings.TrimSpace(note), context)
+	if qualified {
+		qualifier, _ := parseQualifier(text)
+		for i := range edges {
+			edges[i].Confidence = qualifier.Confidence
+			edges[i].Source = qualifier.Source
+			edges[i].Weight = qualifier.Weight
+		}
+	}
+	return edges, nodes
+}
+
+// parseStatementEdges convertit une note sans qualificatifs en arêtes
+func (p *N4LParser) parseStatementEdges(note, context string) ([]models.Edge, []string) {
+	contexts := ContextPaths(context)
+
+	// Relation, éventuellement chaînée ("A -> r1 -> B -> r2 -> C")
+	if items, labels, ok := p.relationChain(note); ok {
+		var edges []models.Edge
+		var nodes []string
+		seen := make(map[string]bool)
+
+		for i, label := range labels {
+			for _, source := range p.splitItems(items[i]) {
+				for _, target := range p.splitItems(items[i+1]) {
+					if source == "" || target == "" {
+						continue
+					}
+					edgeType, edgeLabel := "relation", p.relations.Canonical(label)
+					if label == SequenceLabel {
+						edgeType, edgeLabel = "sequence", label
+					} else if relationKind(label) == "negation" {
+						edgeType, edgeLabel = p.negatedEdge(label)
+					}
+					edges = append(edges, models.Edge{
+						From:     source,
+						To:       target,
+						Label:    edgeLabel,
+						Type:     edgeType,
+						Context:  context,
+						Contexts: contexts,
+					})
+					for _, node := range []string{source, target} {
+						if !seen[node] {
+							seen[node] = true
+							nodes = append(nodes, node)
+						}
+					}
+				}
+			}
 		}
+		return edges, nodes
 	}
 
 	// Équivalence
@@ -295,44 +896,55 @@ func (p *N4LParser) parseNoteToEdge(note, context string) (*models.Edge, []strin
 		source := strings.TrimSpace(matches[1])
 		target := strings.TrimSpace(matches[2])
 		if source != "" && target != "" {
-			return &models.Edge{
-				From:    source,
-				To:      target,
-				Label:   "",
-				Type:    "equivalence",
-				Context: context,
-			}, []string{source, target}
+			return []models.Edge{{
+				From:     source,
+				To:       target,
+				Label:    "",
+				Type:     "equivalence",
+				Context:  context,
+				Contexts: contexts,
+			}}, []string{source, target}
 		}
 	}
 
 	// Groupe
 	if matches := p.groupRegex.FindStringSubmatch(note); len(matches) == 3 {
 		parent := strings.TrimSpace(matches[1])
-		childrenStr := strings.TrimSpace(matches[2])
-		children := strings.Split(childrenStr, ";")
 
 		if parent != "" {
 			nodes := []string{parent}
-			for _, child := range children {
-				childName := strings.Trim(strings.TrimSpace(child), `"`)
-				if childName != "" {
-					nodes = append(nodes, childName)
+			var edges []models.Edge
+
+			// Événement : une arête de rôle vers chaque participant
+			if roles, participants, ok := splitRoles(p.splitMembers(matches[2])); ok {
+				for i, participant := range participants {
+					nodes = append(nodes, participant)
+					edges = append(edges, models.Edge{
+						From:     parent,
+						To:       participant,
+						Label:    p.roleLabel(roles[i]),
+						Type:     "role",
+						Context:  context,
+						Contexts: contexts,
+					})
 				}
+				return edges, nodes
 			}
 
 			// Créer une arête pour chaque enfant
-			var edges []models.Edge
-			for i := 1; i < len(nodes); i++ {
+			for _, child := range p.splitMembers(matches[2]) {
+				nodes = append(nodes, child)
 				edges = append(edges, models.Edge{
-					From:    parent,
-					To:      nodes[i],
-					Label:   "contient",
-					Type:    "group",
-					Context: context,
+					From:     parent,
+					To:       child,
+					Label:    "contient",
+					Type:     "group",
+					Context:  context,
+					Contexts: contexts,
 				})
 			}
 			if len(edges) > 0 {
-				return &edges[0], nodes // Retourner la première arête et tous les nœuds
+				return edges, nodes
 			}
 		}
 	}
@@ -340,6 +952,94 @@ func (p *N4LParser) parseNoteToEdge(note, context string) (*models.Edge, []strin
 	return nil, nil
 }
 
+// relationKind distingue les relations niées ("!r") des relations ordinaires
+func relationKind(label string) string {
+	if strings.HasPrefix(label, NegationPrefix) {
+		return "negation"
+	}
+	return "relation"
+}
+
+// negatedEdge retourne le type et le libellé de l'arête d'une relation niée :
+// "!eq" (ou "!=") nie une équivalence, "!r" nie la relation r
+func (p *N4LParser) negatedEdge(label string) (string, string) {
+	negated := strings.TrimSpace(strings.TrimPrefix(label, NegationPrefix))
+	if negated == NonEquivalenceLabel || negated == "=" {
+		return "non_equivalence", ""
+	}
+	return "negation", p.relations.Canonical(negated)
+}
+
+// NegatedLabel retourne le libellé N4L d'une arête de négation ("!eq", "!r")
+func NegatedLabel(edge models.Edge) (string, bool) {
+	switch edge.Type {
+	case "non_equivalence":
+		return NegationPrefix + NonEquivalenceLabel, true
+	case "negation":
+		return NegationPrefix + edge.Label, true
+	}
+	return "", false
+}
+
+// isSequenceMarker indique si un nom de contexte déclare une séquence
+func isSequenceMarker(name string) bool {
+	name = strings.TrimSpace(name)
+	return name == "_sequence_" || name == "sequence"
+}
+
+// statementAnchor retourne l'élément principal d'une instruction structurée,
+// celui qu'une séquence relie à l'instruction précédente
+func statementAnchor(stmt models.N4LStatement) models.N4LItem {
+	if stmt.Group != nil {
+		return stmt.Group.Parent
+	}
+	if len(stmt.Relations) > 0 {
+		return stmt.Relations[0].Source
+	}
+	return models.N4LItem{}
+}
+
+// relationChain découpe une relation fléchée en éléments et libellés :
+// "A -> r1 -> B -> r2 -> C" donne [A B C] et [r1 r2]. Une chaîne à nombre
+// pair de segments se termine par une relation sans cible : elle est rejetée.
+func (p *N4LParser) relationChain(note string) ([]string, []string, bool) {
+	segments := strings.Split(note, " -> ")
+	if len(segments) < 3 || len(segments)%2 == 0 {
+		return nil, nil, false
+	}
+
+	var items, labels []string
+	for i, segment := range segments {
+		if i%2 == 0 {
+			items = append(items, strings.TrimSpace(segment))
+		} else {
+			labels = append(labels, strings.TrimSpace(segment))
+		}
+	}
+	return items, labels, true
+}
+
+// splitItems développe un élément de relation en liste de cibles :
+// "{ a; b }" donne [a b], tout autre texte est un élément unique
+func (p *N4LParser) splitItems(item string) []string {
+	item = strings.TrimSpace(item)
+	if strings.HasPrefix(item, "{") && strings.HasSuffix(item, "}") {
+		return p.splitMembers(item[1 : len(item)-1])
+	}
+	return []string{item}
+}
+
+// splitMembers découpe la liste "a; b; c" d'un groupe
+func (p *N4LParser) splitMembers(list string) []string {
+	var members []string
+	for _, member := range strings.Split(list, ";") {
+		if name := strings.Trim(strings.TrimSpace(member), `"`); name != "" {
+			members = append(members, name)
+		}
+	}
+	return members
+}
+
 // ExtractFirstSubject extrait le premier sujet d'une note
 func ExtractFirstSubject(note string) string {
 	parts := strings.Fields(note)
@@ -355,34 +1055,25 @@ func ExtractFirstSubject(note string) string {
 // BuildPathStory construit une histoire à partir d'un chemin
 func BuildPathStory(path []string, notes map[string][]string) string {
 	var storyBuilder strings.Builder
-	relationRegex := regexp.MustCompile(`^(.*) -> (.*) -> (.*)$`)
-	groupRegex := regexp.MustCompile(`^(.*) => {(.*)}$`)
+	parser := NewN4LParser()
 
 	for i := 0; i < len(path)-1; i++ {
 		fromNode := path[i]
 		toNode := path[i+1]
 		foundRelation := false
 
-		for _, notesList := range notes {
+		for context, notesList := range notes {
 			for _, note := range notesList {
-				if matches := relationRegex.FindStringSubmatch(note); len(matches) == 4 {
-					source, _, target := strings.TrimSpace(matches[1]), strings.TrimSpace(matches[2]), strings.TrimSpace(matches[3])
-					if (source == fromNode && target == toNode) || (source == toNode && target == fromNode) {
+				edges, _ := parser.parseNoteToEdges(note, context)
+				for _, edge := range edges {
+					if (edge.From == fromNode && edge.To == toNode) || (edge.From == toNode && edge.To == fromNode) {
 						storyBuilder.WriteString(fmt.Sprintf("Fait %d: %s.\n", i+1, note))
 						foundRelation = true
 						break
 					}
-				} else if matches := groupRegex.FindStringSubmatch(note); len(matches) == 3 {
-					parent := strings.TrimSpace(matches[1])
-					children := strings.Split(matches[2], ";")
-					for _, child := range children {
-						childName := strings.TrimSpace(child)
-						if (parent == fromNode && childName == toNode) || (parent == toNode && childName == fromNode) {
-							storyBuilder.WriteString(fmt.Sprintf("Fait %d: %s.\n", i+1, note))
-							foundRelation = true
-							break
-						}
-					}
+				}
+				if foundRelation {
+					break
 				}
 			}
 			if foundRelation {
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// largeDocument génère un document de n instructions qui mêle les syntaxes
+// courantes, réparties en contextes de 100 lignes
+func largeDocument(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		if i%100 == 0 {
+			fmt.Fprintf(&b, ":: Chapitre %d | Enquête ::\n", i/100)
+		}
+		switch i % 8 {
+		case 0:
+			fmt.Fprintf(&b, "Personne %d -> connaît -> Personne %d\n", i, (i*7)%n)
+		case 1:
+			fmt.Fprintf(&b, "Personne %d (était à) Lieu %d (proche de) Lieu %d\n", i, i%50, (i+1)%50)
+		case 2:
+			fmt.Fprintf(&b, "\" (a vu) Objet %d [certitude=0.6; source=témoin %d]\n", i%30, i)
+		case 3:
+			fmt.Fprintf(&b, "Groupe %d => { Personne %d; Personne %d; Objet %d }\n", i, i-1, i-2, i%30)
+		case 4:
+			fmt.Fprintf(&b, "Personne %d <-> Alias %d\n", i-4, i)
+		case 5:
+			fmt.Fprintf(&b, "Personne %d (âge) %d ans\n", i-5, 20+i%60)
+		case 6:
+			fmt.Fprintf(&b, "Réunion %d => { agent: Personne %d; lieu: Lieu %d; date: %02d/03/2024 }\n", i, i-6, i%50, 1+i%28)
+		default:
+			fmt.Fprintf(&b, "Note libre numéro %d sur l'enquête\n", i)
+		}
+	}
+	return b.String()
+}
+
+func benchmarkParseN4L(b *testing.B, n int) {
+	content := largeDocument(n)
+	parser := NewN4LParser()
+	b.SetBytes(int64(len(content)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parser.ParseN4L(content)
+	}
+}
+
+func BenchmarkParseN4L1k(b *testing.B)  { benchmarkParseN4L(b, 1000) }
+func BenchmarkParseN4L10k(b *testing.B) { benchmarkParseN4L(b, 10000) }
+
+func benchmarkParseN4LToGraph(b *testing.B, n int) {
+	parser := NewN4LParser()
+	parsed := parser.ParseN4L(largeDocument(n))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parser.ParseN4LToGraphWithSpans(parsed.Notes, parsed.Spans)
+	}
+}
+
+func BenchmarkParseN4LToGraph1k(b *testing.B)  { benchmarkParseN4LToGraph(b, 1000) }
+func BenchmarkParseN4LToGraph10k(b *testing.B) { benchmarkParseN4LToGraph(b, 10000) }
+
+func BenchmarkSerialize10k(b *testing.B) {
+	parser := NewN4LParser()
+	graph := parser.ParseN4LToGraph(parser.ParseN4L(largeDocument(10000)).Notes)
+	serializer := NewN4LSerializer()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		serializer.Serialize(graph)
+	}
+}
+
+// TestLargeDocument vérifie les invariants du graphe sur le document des benchmarks
+func TestLargeDocument(t *testing.T) {
+	parser := NewN4LParser()
+	content := largeDocument(2000)
+	parsed := parser.ParseN4L(content)
+	checkParsed(t, content, parsed)
+	checkGraph(t, parser.ParseN4LToGraphWithSpans(parsed.Notes, parsed.Spans))
+}
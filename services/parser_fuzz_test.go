@@ -0,0 +1,95 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"n4l-editor/models"
+)
+
+// addGoldenSeeds amorce un fuzzer avec les documents de référence
+func addGoldenSeeds(f *testing.F) {
+	files, _ := filepath.Glob(filepath.Join("testdata", "golden", "*.n4l"))
+	for _, file := range files {
+		if content, err := os.ReadFile(file); err == nil {
+			f.Add(string(content))
+		}
+	}
+	f.Add("A -> r -> B -> s -> C\n\" (t) D\n@v X (r) Y\n$v.2 -> u -> $PREV.1")
+	f.Add(":: a | _sequence_ ::\nx\ny\n-:: _sequence_ ::\nG => { r: a; s: b } [certitude=0,5]")
+}
+
+// checkParsed vérifie les invariants d'un document analysé
+func checkParsed(t *testing.T, content string, parsed models.ParsedN4L) {
+	t.Helper()
+	lines := strings.Count(content, "\n") + 1
+	for _, d := range parsed.Diagnostics {
+		if d.Pos.Line < 1 || d.Pos.Line > lines || d.Pos.Column < 1 {
+			t.Errorf("diagnostic hors du document (%d lignes) : %+v", lines, d)
+		}
+	}
+	for context, notes := range parsed.Notes {
+		if len(parsed.Spans[context]) != len(notes) {
+			t.Errorf("contexte %q : %d notes pour %d extraits", context, len(notes), len(parsed.Spans[context]))
+		}
+		for _, span := range parsed.Spans[context] {
+			if span.StartLine < 1 || span.StartLine > span.EndLine || span.EndLine > lines {
+				t.Errorf("extrait hors du document : %+v", span)
+			}
+		}
+	}
+}
+
+// checkGraph vérifie les invariants d'un graphe : identifiants uniques et
+// extrémités des arêtes présentes parmi les nœuds
+func checkGraph(t *testing.T, graph models.GraphData) {
+	t.Helper()
+	nodes := make(map[string]bool)
+	for _, node := range graph.Nodes {
+		if node.ID == "" || nodes[node.ID] {
+			t.Errorf("nœud vide ou en double : %q", node.ID)
+		}
+		nodes[node.ID] = true
+	}
+	edges := make(map[string]bool)
+	for _, edge := range graph.Edges {
+		if !nodes[edge.From] || !nodes[edge.To] {
+			t.Errorf("arête %q -> %q -> %q vers un nœud absent", edge.From, edge.Label, edge.To)
+		}
+		if edge.ID == "" || edges[edge.ID] {
+			t.Errorf("identifiant d'arête vide ou en double : %q", edge.ID)
+		}
+		edges[edge.ID] = true
+	}
+	for _, hyperedge := range graph.Hyperedges {
+		if !nodes[hyperedge.ID] {
+			t.Errorf("événement %q sans nœud", hyperedge.ID)
+		}
+		for _, participant := range hyperedge.Participants {
+			if !nodes[participant.Node] || !edges[participant.Edge] {
+				t.Errorf("participant %+v absent du graphe", participant)
+			}
+		}
+	}
+}
+
+func FuzzParseN4L(f *testing.F) {
+	addGoldenSeeds(f)
+	parser := NewN4LParser()
+	f.Fuzz(func(t *testing.T, content string) {
+		checkParsed(t, content, parser.ParseN4L(content))
+	})
+}
+
+func FuzzParseN4LToGraph(f *testing.F) {
+	addGoldenSeeds(f)
+	parser := NewN4LParser()
+	merging := parser.WithIdentityPolicy(IdentityPolicy{FoldCase: true, FoldAccents: true, MergeAliases: true})
+	f.Fuzz(func(t *testing.T, content string) {
+		parsed := parser.ParseN4L(content)
+		checkGraph(t, parser.ParseN4LToGraphWithSpans(parsed.Notes, parsed.Spans))
+		checkGraph(t, merging.ParseN4LToGraph(parsed.Notes))
+	})
+}
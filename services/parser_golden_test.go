@@ -0,0 +1,72 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"n4l-editor/models"
+)
+
+var updateGolden = flag.Bool("update", false, "réécrire les fichiers de référence de testdata/golden")
+
+// goldenResult est ce que les fichiers .golden figent pour un document :
+// diagnostics et graphe, extraits source compris
+type goldenResult struct {
+	Diagnostics []models.Diagnostic `json:"diagnostics"`
+	Graph       models.GraphData    `json:"graph"`
+}
+
+// TestParserGolden compare l'analyse de chaque testdata/golden/*.n4l à son
+// fichier .golden. Après un changement voulu du parser :
+//
+//	go test ./services -run TestParserGolden -update
+func TestParserGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "golden", "*.n4l"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("aucun document de référence : %v", err)
+	}
+
+	parser := NewN4LParser()
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".n4l")
+		t.Run(name, func(t *testing.T) {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed := parser.ParseN4L(string(content))
+			result := goldenResult{
+				Diagnostics: parsed.Diagnostics,
+				Graph:       parser.ParseN4LToGraphWithSpans(parsed.Notes, parsed.Spans),
+			}
+			var buf bytes.Buffer
+			encoder := json.NewEncoder(&buf)
+			encoder.SetEscapeHTML(false) // "->" plutôt que "-\u003e"
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(result); err != nil {
+				t.Fatal(err)
+			}
+			got := buf.Bytes()
+
+			golden := strings.TrimSuffix(file, ".n4l") + ".golden"
+			if *updateGolden {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (générer avec -update)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s diffère de la référence :\n%s", golden, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseEdges analyse un document et résume ses arêtes en "From -label-> To"
+// et ses diagnostics en "code message"
+func parseEdges(source string) ([]string, []string) {
+	parser := NewN4LParser()
+	parsed := parser.ParseN4L(source)
+	var edges, diagnostics []string
+	for _, edge := range parser.ParseN4LToGraph(parsed.Notes).Edges {
+		edges = append(edges, edge.From+" -"+edge.Label+"-> "+edge.To)
+	}
+	for _, d := range parsed.Diagnostics {
+		diagnostics = append(diagnostics, d.Code+" "+d.Message)
+	}
+	return edges, diagnostics
+}
+
+func TestRelationChains(t *testing.T) {
+	for _, tt := range []struct {
+		line  string
+		edges []string
+		diag  string // préfixe attendu du diagnostic, vide s'il n'y en a pas
+	}{
+		{"A -> r1 -> B -> r2 -> C", []string{"A -r1-> B", "B -r2-> C"}, ""},
+		{"A -> r -> { B; C }", []string{"A -r-> B", "A -r-> C"}, ""},
+		{"A -> r1 -> B -> r2", nil, DiagIncompleteRelation + " relation sans cible"},
+		{"A -> r1 -> B -> r2 ->", nil, DiagIncompleteRelation + " relation sans cible"},
+	} {
+		edges, diagnostics := parseEdges(tt.line)
+		if !reflect.DeepEqual(edges, tt.edges) {
+			t.Errorf("%q : arêtes %q, attendu %q", tt.line, edges, tt.edges)
+		}
+		if tt.diag == "" && len(diagnostics) > 0 || tt.diag != "" && (len(diagnostics) != 1 || !strings.HasPrefix(diagnostics[0], tt.diag)) {
+			t.Errorf("%q : diagnostics %q, attendu %q", tt.line, diagnostics, tt.diag)
+		}
+	}
+}
+
+func TestParenthesesChains(t *testing.T) {
+	for _, tt := range []struct {
+		line  string
+		edges []string
+	}{
+		{"Jean (aime) Marie (parle à) Paul", []string{"Jean -aime-> Marie", "Marie -parle à-> Paul"}},
+		// Les parenthèses finales précisent la cible, comme avant les chaînes
+		{"Jean (aime) Marie (environ)", []string{"Jean -aime-> Marie (environ)"}},
+		{"Jean (aime) Marie (parle à) Paul (peut-être)", []string{"Jean -aime-> Marie", "Marie -parle à-> Paul (peut-être)"}},
+	} {
+		edges, diagnostics := parseEdges(tt.line)
+		if !reflect.DeepEqual(edges, tt.edges) || len(diagnostics) > 0 {
+			t.Errorf("%q : arêtes %q (diagnostics %q), attendu %q", tt.line, edges, diagnostics, tt.edges)
+		}
+	}
+
+	// Une propriété garde sa précision
+	parser := NewN4LParser()
+	graph := parser.ParseN4LToGraph(parser.ParseN4L("Jean (âge) 67 ans (environ)").Notes)
+	if len(graph.Nodes) != 1 || len(graph.Nodes[0].Properties) != 1 || graph.Nodes[0].Properties[0].Value != "67 ans (environ)" {
+		t.Errorf("propriété perdue : %+v", graph.Nodes)
+	}
+
+	// Une cible intermédiaire vide reste une erreur
+	if edges, diagnostics := parseEdges("Jean (aime) (parle à) Paul"); len(edges) > 0 || len(diagnostics) == 0 {
+		t.Errorf("cible vide acceptée : %q %q", edges, diagnostics)
+	}
+}
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"container/heap"
+	"fmt"
+	"strings"
+
+	"n4l-editor/models"
+)
+
+// Sens de parcours d'une recherche de chemins
+const (
+	PathUndirected = "undirected" // les arêtes se parcourent dans les deux sens
+	PathDirected   = "directed"   // les arêtes se parcourent dans leur sens
+)
+
+// MaxPaths borne le nombre de chemins d'une recherche
+const MaxPaths = 50
+
+// PathQuery décrit une recherche de chemins entre deux nœuds
+type PathQuery struct {
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	MaxLength int      `json:"maxLength,omitempty"` // nombre maximal d'arêtes, 0 : sans limite
+	K         int      `json:"k,omitempty"`         // nombre de chemins, 1 par défaut
+	Direction string   `json:"direction,omitempty"` // PathUndirected par défaut
+	Labels    []string `json:"labels,omitempty"`    // relations admises, toutes si vide
+	Contexts  []string `json:"contexts,omitempty"`  // contextes admis, tous si vide
+}
+
+// FindPaths retourne les K chemins sans cycle de moindre coût entre deux
+// nœuds (algorithme de Yen), du plus sûr au moins sûr. Le coût d'une arête
+// est celui de findPath : un fait établi coûte moins qu'une hypothèse. En
+// mode orienté, équivalences, rôles d'événements et relations symétriques se
+// parcourent tout de même dans les deux sens.
+func (ga *GraphAnalyzer) FindPaths(query PathQuery, graphData models.GraphData) ([]models.Path, error) {
+	if query.K <= 0 {
+		query.K = 1
+	}
+	if query.K > MaxPaths {
+		query.K = MaxPaths
+	}
+	if query.MaxLength < 0 {
+		return nil, fmt.Errorf("longueur maximale négative : %d", query.MaxLength)
+	}
+	switch query.Direction {
+	case "":
+		query.Direction = PathUndirected
+	case PathUndirected, PathDirected:
+	default:
+		return nil, fmt.Errorf("sens de parcours inconnu %q (%s ou %s)", query.Direction, PathDirected, PathUndirected)
+	}
+
+	graph := NewGraph(graphData)
+	for _, id := range []string{query.From, query.To} {
+		if !graph.HasNode(id) {
+			return nil, fmt.Errorf("nœud inconnu %q", id)
+		}
+	}
+
+	search := ga.newPathSearch(query, graph)
+	paths := []models.Path{}
+	for _, found := range search.yen(query.From, query.To, query.K) {
+		path := models.Path{Nodes: found.nodes, Edges: make([]models.Edge, len(found.edges)), Cost: found.cost}
+		for i, e := range found.edges {
+			path.Edges[i] = graphData.Edges[e]
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// pathStep est le passage d'un nœud à un autre par une arête
+type pathStep struct {
+	edge int32 // indice dans GraphData.Edges
+	to   string
+	cost float64
+}
+
+// foundPath est un chemin en cours de recherche
+type foundPath struct {
+	nodes []string
+	edges []int32
+	cost  float64
+}
+
+// pathSearch porte les passages admis par une requête
+type pathSearch struct {
+	edges     []models.Edge
+	steps     map[string][]pathStep
+	maxLength int
+}
+
+func (ga *GraphAnalyzer) newPathSearch(query PathQuery, graph *Graph) *pathSearch {
+	labels := make(map[string]bool)
+	for _, label := range query.Labels {
+		labels[ga.relations.Canonical(label)] = true
+	}
+	contexts := make(map[string]bool)
+	for _, context := range query.Contexts {
+		contexts[context] = true
+	}
+
+	s := &pathSearch{edges: graph.Edges(), steps: make(map[string][]pathStep), maxLength: query.MaxLength}
+	// Entre deux nœuds, seule l'arête la plus sûre compte : une même relation
+	// déclarée dans plusieurs contextes ne fait pas plusieurs chemins
+	position := make(map[[2]string]int)
+	add := func(from, to string, edge int32, cost float64) {
+		if i, ok := position[[2]string{from, to}]; ok {
+			if cost < s.steps[from][i].cost {
+				s.steps[from][i] = pathStep{edge, to, cost}
+			}
+			return
+		}
+		position[[2]string{from, to}] = len(s.steps[from])
+		s.steps[from] = append(s.steps[from], pathStep{edge, to, cost})
+	}
+	for i, edge := range graph.Edges() {
+		if edge.From == edge.To {
+			continue // une boucle ne mène nulle part
+		}
+		if len(labels) > 0 && !labels[ga.relations.Canonical(edge.Label)] {
+			continue
+		}
+		if len(contexts) > 0 && !edgeInContexts(edge, contexts) {
+			continue
+		}
+		cost := edgeCost(edge)
+		add(edge.From, edge.To, int32(i), cost)
+		if query.Direction == PathUndirected || ga.bidirectional(edge) {
+			add(edge.To, edge.From, int32(i), cost)
+		}
+	}
+	return s
+}
+
+// bidirectional indique si une arête se parcourt dans les deux sens même en
+// mode orienté
+func (ga *GraphAnalyzer) bidirectional(edge models.Edge) bool {
+	switch edge.Type {
+	case "equivalence", "role":
+		return true
+	case "relation":
+		inverse, ok := ga.relations.Inverse(edge.Label)
+		return ok && strings.EqualFold(inverse, ga.relations.Canonical(edge.Label))
+	}
+	return false
+}
+
+func edgeInContexts(edge models.Edge, contexts map[string]bool) bool {
+	if contexts[edge.Context] {
+		return true
+	}
+	for _, context := range edge.Contexts {
+		if contexts[context] {
+			return true
+		}
+	}
+	return false
+}
+
+// yen retourne au plus k chemins sans cycle de from à to, par coût croissant
+func (s *pathSearch) yen(from, to string, k int) []foundPath {
+	first, ok := s.shortest(from, to, s.maxLength, nil, nil)
+	if !ok {
+		return nil
+	}
+	accepted := []foundPath{first}
+	seen := map[string]bool{pathKey(first): true}
+	var candidates []foundPath
+
+	for len(accepted) < k {
+		last := accepted[len(accepted)-1]
+		for i := 0; i < len(last.edges); i++ {
+			budget := 0
+			if s.maxLength > 0 {
+				if budget = s.maxLength - i; budget <= 0 {
+					break
+				}
+			}
+			spur := last.nodes[i]
+			rootEdges := last.edges[:i]
+
+			// Les chemins retenus qui partagent la racine ne peuvent pas
+			// repartir du nœud d'embranchement par la même arête
+			blockedSteps := make(map[pathStepKey]bool)
+			for _, p := range accepted {
+				if len(p.edges) > i && sameEdges(p.edges[:i], rootEdges) {
+					blockedSteps[pathStepKey{spur, p.edges[i]}] = true
+				}
+			}
+			blockedNodes := make(map[string]bool)
+			for _, node := range last.nodes[:i] {
+				blockedNodes[node] = true
+			}
+
+			spurPath, ok := s.shortest(spur, to, budget, blockedNodes, blockedSteps)
+			if !ok {
+				continue
+			}
+			candidate := foundPath{
+				nodes: append(append([]string{}, last.nodes[:i]...), spurPath.nodes...),
+				edges: append(append([]int32{}, rootEdges...), spurPath.edges...),
+				cost:  spurPath.cost,
+			}
+			for _, e := range rootEdges {
+				candidate.cost += edgeCost(s.edges[e])
+			}
+			if key := pathKey(candidate); !seen[key] {
+				seen[key] = true
+				candidates = append(candidates, candidate)
+			}
+		}
+		if len(candidates) == 0 {
+			break
+		}
+
+		best := 0
+		for i, c := range candidates {
+			if c.cost < candidates[best].cost ||
+				(c.cost == candidates[best].cost && len(c.edges) < len(candidates[best].edges)) {
+				best = i
+			}
+		}
+		accepted = append(accepted, candidates[best])
+		candidates = append(candidates[:best], candidates[best+1:]...)
+	}
+	return accepted
+}
+
+type pathStepKey struct {
+	from string
+	edge int32
+}
+
+// pathState est un nœud atteint en hops arêtes ; hops reste à 0 si la
+// longueur n'est pas bornée
+type pathState struct {
+	node string
+	hops int
+}
+
+// shortest est un Dijkstra borné à maxLength arêtes (0 : sans limite) qui
+// évite les nœuds et passages bloqués
+func (s *pathSearch) shortest(from, to string, maxLength int, blockedNodes map[string]bool, blockedSteps map[pathStepKey]bool) (foundPath, bool) {
+	type previous struct {
+		state pathState
+		edge  int32
+	}
+	start := pathState{node: from}
+	dist := map[pathState]float64{start: 0}
+	prev := make(map[pathState]previous)
+	done := make(map[pathState]bool)
+	queue := &stateQueue{{state: start}}
+	seq := 0
+
+	for queue.Len() > 0 {
+		current := heap.Pop(queue).(queuedState)
+		if done[current.state] {
+			continue
+		}
+		done[current.state] = true
+
+		if current.state.node == to {
+			path := foundPath{nodes: []string{to}, cost: current.cost}
+			for state := current.state; state != start; {
+				p := prev[state]
+				path.nodes = append(path.nodes, p.state.node)
+				path.edges = append(path.edges, p.edge)
+				state = p.state
+			}
+			reverseStrings(path.nodes)
+			for i, j := 0, len(path.edges)-1; i < j; i, j = i+1, j-1 {
+				path.edges[i], path.edges[j] = path.edges[j], path.edges[i]
+			}
+			return path, true
+		}
+		if maxLength > 0 && current.state.hops >= maxLength {
+			continue
+		}
+
+		for _, step := range s.steps[current.state.node] {
+			if blockedNodes[step.to] || blockedSteps[pathStepKey{current.state.node, step.edge}] {
+				continue
+			}
+			next := pathState{node: step.to}
+			if maxLength > 0 {
+				next.hops = current.state.hops + 1
+			}
+			cost := current.cost + step.cost
+			if d, seen := dist[next]; done[next] || (seen && cost >= d) {
+				continue
+			}
+			dist[next] = cost
+			prev[next] = previous{current.state, step.edge}
+			seq++
+			heap.Push(queue, queuedState{state: next, cost: cost, seq: seq})
+		}
+	}
+	return foundPath{}, false
+}
+
+func reverseStrings(s []string) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
+
+func sameEdges(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pathKey identifie un chemin par la suite de ses nœuds
+func pathKey(path foundPath) string {
+	return strings.Join(path.nodes, "\x00")
+}
+
+// queuedState est un état en attente, ordonné par coût puis par ordre
+// d'arrivée pour un résultat déterministe
+type queuedState struct {
+	state pathState
+	cost  float64
+	seq   int
+}
+
+type stateQueue []queuedState
+
+func (q stateQueue) Len() int { return len(q) }
+func (q stateQueue) Less(i, j int) bool {
+	if q[i].cost != q[j].cost {
+		return q[i].cost < q[j].cost
+	}
+	return q[i].seq < q[j].seq
+}
+func (q stateQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *stateQueue) Push(x interface{}) { *q = append(*q, x.(queuedState)) }
+func (q *stateQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"n4l-editor/models"
+)
+
+func TestFindPaths(t *testing.T) {
+	source := strings.Join([]string{
+		"Jean (connaît) Marie",
+		"Marie (connaît) Paul",
+		"Jean (a croisé) Paul [certitude=0.3]",
+		"Anne (marié à) Paul",
+		"",
+		":: travail ::",
+		"Jean (collègue de) Luc",
+		"Luc (collègue de) Paul",
+	}, "\n")
+	parser := NewN4LParser()
+	graph := parser.ParseN4LToGraph(parser.ParseN4L(source).Notes)
+	ga := NewGraphAnalyzer()
+
+	nodes := func(paths []models.Path) []string {
+		var got []string
+		for _, path := range paths {
+			if len(path.Edges) != len(path.Nodes)-1 {
+				t.Errorf("chemin %v : %d arêtes", path.Nodes, len(path.Edges))
+			}
+			got = append(got, strings.Join(path.Nodes, " > "))
+		}
+		return got
+	}
+
+	for _, tt := range []struct {
+		name  string
+		query PathQuery
+		want  []string
+	}{
+		{"plus court", PathQuery{From: "Jean", To: "Paul"},
+			[]string{"Jean > Marie > Paul"}},
+		{"k chemins, l'hypothèse en dernier", PathQuery{From: "Jean", To: "Paul", K: 5},
+			[]string{"Jean > Marie > Paul", "Jean > Luc > Paul", "Jean > Paul"}},
+		{"longueur bornée", PathQuery{From: "Jean", To: "Paul", K: 5, MaxLength: 1},
+			[]string{"Jean > Paul"}},
+		{"relations", PathQuery{From: "Jean", To: "Paul", K: 5, Labels: []string{"connaît"}},
+			[]string{"Jean > Marie > Paul"}},
+		{"contextes", PathQuery{From: "Jean", To: "Paul", K: 5, Contexts: []string{"travail"}},
+			[]string{"Jean > Luc > Paul"}},
+		{"orienté", PathQuery{From: "Paul", To: "Jean", K: 5, Direction: PathDirected},
+			nil},
+		{"relation symétrique à rebours", PathQuery{From: "Marie", To: "Anne", Direction: PathDirected},
+			[]string{"Marie > Paul > Anne"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := ga.FindPaths(tt.query, graph)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := nodes(paths); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chemins %q, attendu %q", got, tt.want)
+			}
+			for i := 1; i < len(paths); i++ {
+				if paths[i].Cost < paths[i-1].Cost {
+					t.Errorf("chemins mal classés : %v", paths)
+				}
+			}
+		})
+	}
+
+	for _, query := range []PathQuery{
+		{From: "Jean", To: "Inconnu"},
+		{From: "Jean", To: "Paul", Direction: "diagonal"},
+		{From: "Jean", To: "Paul", MaxLength: -1},
+	} {
+		if _, err := ga.FindPaths(query, graph); err == nil {
+			t.Errorf("%+v : erreur attendue", query)
+		}
+	}
+}
+
+// TestFindPathsYen vérifie sur une grille que les k chemins sont distincts,
+// sans cycle et par coût croissant
+func TestFindPathsYen(t *testing.T) {
+	var lines []string
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			if i < 3 {
+				lines = append(lines, cell(i, j)+" (mène à) "+cell(i+1, j))
+			}
+			if j < 3 {
+				lines = append(lines, cell(i, j)+" (mène à) "+cell(i, j+1))
+			}
+		}
+	}
+	parser := NewN4LParser()
+	graph := parser.ParseN4LToGraph(parser.ParseN4L(strings.Join(lines, "\n")).Notes)
+
+	// 20 chemins monotones de 6 arêtes d'un coin à l'autre
+	paths, err := NewGraphAnalyzer().FindPaths(PathQuery{From: cell(0, 0), To: cell(3, 3), K: 30, Direction: PathDirected}, graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 20 {
+		t.Fatalf("%d chemins, attendu 20", len(paths))
+	}
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		key := strings.Join(path.Nodes, ">")
+		if seen[key] || len(path.Edges) != 6 || path.Cost != 6 {
+			t.Errorf("chemin en double ou trop long : %s (coût %.1f)", key, path.Cost)
+		}
+		seen[key] = true
+	}
+
+	// Sans orientation, les détours suivent les 20 plus courts
+	paths, _ = NewGraphAnalyzer().FindPaths(PathQuery{From: cell(0, 0), To: cell(3, 3), K: 30}, graph)
+	for i, path := range paths {
+		visited := make(map[string]bool)
+		for _, node := range path.Nodes {
+			if visited[node] {
+				t.Errorf("chemin avec cycle : %v", path.Nodes)
+			}
+			visited[node] = true
+		}
+		if (i < 20) != (len(path.Edges) == 6) {
+			t.Errorf("chemin %d de %d arêtes", i, len(path.Edges))
+		}
+	}
+}
+
+func cell(i, j int) string {
+	return "C" + string(rune('0'+i)) + string(rune('0'+j))
+}
+
+func BenchmarkFindPaths(b *testing.B) {
+	graph := benchmarkGraph(b)
+	ga := NewGraphAnalyzer()
+	query := PathQuery{From: "Personne 9", To: "Lieu 10", K: 5, MaxLength: 6}
+	for i := 0; i < b.N; i++ {
+		if _, err := ga.FindPaths(query, graph); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"n4l-editor/models"
+)
+
+// ParseProject parse un projet N4L réparti en plusieurs fichiers : root puis,
+// récursivement, les fichiers qu'il inclut par "@include chemin". Les chemins
+// sont relatifs au fichier qui contient la directive et chaque fichier n'est
+// lu qu'une fois. Les notes des contextes de même nom sont fusionnées, les
+// fichiers inclus avant celui qui les inclut ; extraits source et diagnostics
+// indiquent leur fichier. Seule l'absence de root est une erreur, les autres
+// problèmes d'inclusion sont signalés par des diagnostics.
+func (p *N4LParser) ParseProject(fsys fs.FS, root string) (models.ParsedN4L, error) {
+	parser := *p
+	parser.inProject = true
+	l := &projectLoader{
+		parser:   &parser,
+		fsys:     fsys,
+		visited:  make(map[string]bool),
+		notes:    make(map[string][]string),
+		spans:    make(models.NoteSpans),
+		subjects: make(map[string]bool),
+	}
+	if err := l.load(path.Clean(root)); err != nil {
+		return models.ParsedN4L{}, err
+	}
+
+	var subjects []string
+	for s := range l.subjects {
+		subjects = append(subjects, s)
+	}
+	contextNames := make([]string, 0, len(l.notes))
+	for name := range l.notes {
+		contextNames = append(contextNames, name)
+	}
+	if l.diagnostics == nil {
+		l.diagnostics = []models.Diagnostic{}
+	}
+
+	return models.ParsedN4L{
+		Subjects:    subjects,
+		Notes:       l.notes,
+		Spans:       l.spans,
+		Contexts:    BuildContextTree(contextNames),
+		Diagnostics: l.diagnostics,
+		Files:       l.files,
+	}, nil
+}
+
+// ParseProjectFiles parse un projet dont les fichiers sont fournis en mémoire,
+// indexés par leur chemin relatif ("temoins/marie.n4l")
+func (p *N4LParser) ParseProjectFiles(files map[string]string, root string) (models.ParsedN4L, error) {
+	fsys := make(memoryFS, len(files))
+	for name, content := range files {
+		fsys[path.Clean(strings.TrimPrefix(name, "/"))] = content
+	}
+	return p.ParseProject(fsys, strings.TrimPrefix(root, "/"))
+}
+
+// memoryFS est un système de fichiers en lecture seule, sans répertoires,
+// qui associe un contenu à chaque chemin
+type memoryFS map[string]string
+
+func (m memoryFS) Open(name string) (fs.File, error) {
+	content, ok := m[name]
+	if !ok || !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &memoryFile{Reader: strings.NewReader(content), name: name}, nil
+}
+
+// memoryFile est un fichier ouvert de memoryFS, qui sert aussi de fs.FileInfo
+type memoryFile struct {
+	*strings.Reader
+	name string
+}
+
+func (f *memoryFile) Stat() (fs.FileInfo, error) { return f, nil }
+func (f *memoryFile) Close() error               { return nil }
+func (f *memoryFile) Name() string               { return path.Base(f.name) }
+func (f *memoryFile) Mode() fs.FileMode          { return 0o444 }
+func (f *memoryFile) ModTime() time.Time         { return time.Time{} }
+func (f *memoryFile) IsDir() bool                { return false }
+func (f *memoryFile) Sys() interface{}           { return nil }
+
+// projectLoader suit les inclusions d'un projet et accumule son contenu
+type projectLoader struct {
+	parser      *N4LParser
+	fsys        fs.FS
+	visited     map[string]bool // fichiers déjà chargés ou en cours de chargement
+	stack       []string        // chaîne d'inclusion en cours
+	notes       map[string][]string
+	spans       models.NoteSpans
+	subjects    map[string]bool
+	diagnostics []models.Diagnostic
+	files       []string
+}
+
+// load charge un fichier après ceux qu'il inclut
+func (l *projectLoader) load(name string) error {
+	content, err := fs.ReadFile(l.fsys, name)
+	if err != nil {
+		return err
+	}
+	l.visited[name] = true
+	l.stack = append(l.stack, name)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	parsed := l.parser.ParseN4L(string(content))
+
+	for _, include := range parsed.Document.Includes {
+		target := path.Join(path.Dir(name), include.Path)
+		switch {
+		case strings.HasPrefix(include.Path, "/") || !fs.ValidPath(target):
+			l.report(name, DiagMissingInclude, include, "fichier inclus hors du projet : %s", include.Path)
+		case l.including(target):
+			l.report(name, DiagIncludeCycle, include, "inclusion circulaire : %s -> %s",
+				strings.Join(l.stack, " -> "), target)
+		case l.visited[target]:
+			// Déjà fusionné par une autre inclusion
+		default:
+			if err := l.load(target); err != nil {
+				l.report(name, DiagMissingInclude, include, "fichier inclus introuvable : %s", include.Path)
+			}
+		}
+	}
+
+	for context, notes := range parsed.Notes {
+		if _, ok := l.notes[context]; !ok {
+			l.notes[context] = []string{}
+			l.spans[context] = []models.SourceSpan{}
+		}
+		l.notes[context] = append(l.notes[context], notes...)
+		for _, span := range parsed.Spans[context] {
+			span.File = name
+			l.spans[context] = append(l.spans[context], span)
+		}
+	}
+	for _, s := range parsed.Subjects {
+		l.subjects[s] = true
+	}
+	for _, d := range parsed.Diagnostics {
+		d.File = name
+		l.diagnostics = append(l.diagnostics, d)
+	}
+	l.files = append(l.files, name)
+	return nil
+}
+
+// including indique si un fichier fait partie de la chaîne d'inclusion en cours
+func (l *projectLoader) including(name string) bool {
+	for _, file := range l.stack {
+		if file == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *projectLoader) report(file, code string, include models.N4LInclude, format string, args ...interface{}) {
+	d := newDiagnostic(SeverityError, code, include.Pos, 0, format, args...)
+	d.File = file
+	l.diagnostics = append(l.diagnostics, d)
+}
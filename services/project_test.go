@@ -0,0 +1,88 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseProject(t *testing.T) {
+	fsys := fstest.MapFS{
+		"enquete.n4l": {Data: []byte(strings.Join([]string{
+			`@include "temoins/marie.n4l"`,
+			"@include chronologie.n4l",
+			"",
+			":: Enquête ::",
+			"Marie -> accuse -> Paul",
+		}, "\n"))},
+		"temoins/marie.n4l": {Data: []byte(strings.Join([]string{
+			"@include ../chronologie.n4l",
+			"@include absent.n4l",
+			":: Enquête ::",
+			"Marie -> a vu -> Paul",
+		}, "\n"))},
+		"chronologie.n4l": {Data: []byte(strings.Join([]string{
+			"@include enquete.n4l",
+			":: Chronologie ::",
+			"Crime -> précède -> Arrestation",
+		}, "\n"))},
+	}
+
+	parsed, err := NewN4LParser().ParseProject(fsys, "enquete.n4l")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"chronologie.n4l", "temoins/marie.n4l", "enquete.n4l"}; !reflect.DeepEqual(parsed.Files, want) {
+		t.Errorf("ordre de fusion : %v", parsed.Files)
+	}
+	if want := []string{"Marie -> a vu -> Paul", "Marie -> accuse -> Paul"}; !reflect.DeepEqual(parsed.Notes["Enquête"], want) {
+		t.Errorf("contexte fusionné : %v", parsed.Notes["Enquête"])
+	}
+	if spans := parsed.Spans["Enquête"]; len(spans) != 2 || spans[0].File != "temoins/marie.n4l" || spans[0].StartLine != 4 || spans[1].File != "enquete.n4l" {
+		t.Errorf("provenance : %+v", spans)
+	}
+
+	codes := make(map[string]string)
+	for _, d := range parsed.Diagnostics {
+		codes[d.Code] = d.File + ":" + d.Message
+	}
+	if got := codes[DiagIncludeCycle]; got != "chronologie.n4l:inclusion circulaire : enquete.n4l -> temoins/marie.n4l -> chronologie.n4l -> enquete.n4l" {
+		t.Errorf("cycle : %q", got)
+	}
+	if got := codes[DiagMissingInclude]; got != "temoins/marie.n4l:fichier inclus introuvable : absent.n4l" {
+		t.Errorf("inclusion manquante : %q", got)
+	}
+	if got, ok := codes[DiagIgnoredInclude]; ok {
+		t.Errorf("inclusion résolue signalée comme ignorée : %q", got)
+	}
+
+	if _, err := NewN4LParser().ParseProject(fsys, "inconnu.n4l"); err == nil {
+		t.Error("erreur attendue pour un fichier principal absent")
+	}
+}
+
+func TestParseProjectFiles(t *testing.T) {
+	parsed, err := NewN4LParser().ParseProjectFiles(map[string]string{
+		"/enquete.n4l":      "@include temoins/marie.n4l\n@include absent.n4l\n:: Enquête ::\nMarie -> accuse -> Paul",
+		"temoins/marie.n4l": ":: Enquête ::\nMarie -> a vu -> Paul",
+	}, "/enquete.n4l")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"temoins/marie.n4l", "enquete.n4l"}; !reflect.DeepEqual(parsed.Files, want) {
+		t.Errorf("fichiers : %v", parsed.Files)
+	}
+	if len(parsed.Diagnostics) != 1 || parsed.Diagnostics[0].Code != DiagMissingInclude {
+		t.Errorf("diagnostics : %+v", parsed.Diagnostics)
+	}
+}
+
+func TestIncludeOutsideProject(t *testing.T) {
+	parsed := NewN4LParser().ParseN4L("@include temoins.n4l\nMarie -> accuse -> Paul")
+	if len(parsed.Diagnostics) != 1 || parsed.Diagnostics[0].Code != DiagIgnoredInclude ||
+		parsed.Diagnostics[0].Severity != SeverityWarning || parsed.Diagnostics[0].Message != "@include ignoré hors projet : temoins.n4l" {
+		t.Errorf("diagnostics : %+v", parsed.Diagnostics)
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"n4l-editor/models"
+)
+
+var (
+	decimalRegex    = regexp.MustCompile(`^[-+]?\d+(?:\.\d+)?(?:[eE][-+]?\d+)?$`)
+	quantityRegex   = regexp.MustCompile(`^([-+]?\d[\d\s]*(?:[.,]\d+)?)\s*(.*)$`)
+	hourRegex       = regexp.MustCompile(`(\d{1,2})h(\d{2})?$`)
+	frenchDateRegex = regexp.MustCompile(`^(\d{1,2})(?:er)?\s+(\p{L}+)\s+(\d{4})(?:\s+(?:à\s+)?(\d{1,2})[h:](\d{2})?)?$`)
+)
+
+var frenchMonths = map[string]time.Month{
+	"janvier": time.January, "février": time.February, "fevrier": time.February,
+	"mars": time.March, "avril": time.April, "mai": time.May, "juin": time.June,
+	"juillet": time.July, "août": time.August, "aout": time.August,
+	"septembre": time.September, "octobre": time.October, "novembre": time.November,
+	"décembre": time.December, "decembre": time.December,
+}
+
+// propertyDateFormats sont les formats de date reconnus, heure facultative
+var propertyDateFormats = []string{
+	time.RFC3339,
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"02/01/2006 15:04",
+	"2/1/2006 15:04",
+	"02/01/2006",
+	"2/1/2006",
+}
+
+// parseProperty construit l'attribut décrit par une relation de propriété.
+// Une valeur qui ne respecte pas le type déclaré est conservée comme texte.
+func parseProperty(t RelationType, value, context string) models.NodeProperty {
+	property := models.NodeProperty{Name: t.Name, Type: ValueString, Value: value, Context: context}
+
+	switch t.ValueType {
+	case ValueNumber:
+		if n, ok := parseNumber(value); ok {
+			property.Type, property.Number = ValueNumber, &n
+		}
+	case ValueQuantity:
+		if matches := quantityRegex.FindStringSubmatch(value); matches != nil {
+			if n, ok := parseNumber(matches[1]); ok {
+				property.Type, property.Number, property.Unit = ValueQuantity, &n, strings.TrimSpace(matches[2])
+				if property.Unit == "" {
+					property.Unit = t.Unit
+				}
+			}
+		}
+	case ValueDate:
+		if date := parsePropertyDate(value); date != nil {
+			property.Type, property.Date = ValueDate, date
+		}
+	}
+	return property
+}
+
+// parseNumber lit un nombre décimal écrit à la française ou non : "1 500",
+// "2,5", "2.5". "inf", "NaN" ou "0x10" ne sont pas des nombres : ils restent
+// du texte, et un infini ne pourrait pas être encodé en JSON.
+func parseNumber(s string) (float64, bool) {
+	s = strings.Replace(strings.Join(strings.Fields(s), ""), ",", ".", 1)
+	if !decimalRegex.MatchString(s) {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	return n, err == nil
+}
+
+// parsePropertyDate lit une date ISO, "JJ/MM/AAAA" ou "12 mars 2024", suivie
+// éventuellement d'une heure ("14h30", "14:30")
+func parsePropertyDate(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	normalized := hourRegex.ReplaceAllStringFunc(s, func(hour string) string {
+		h, m, _ := strings.Cut(hour, "h")
+		if m == "" {
+			m = "00"
+		}
+		return h + ":" + m
+	})
+	for _, format := range propertyDateFormats {
+		if t, err := time.Parse(format, normalized); err == nil {
+			return &t
+		}
+	}
+
+	matches := frenchDateRegex.FindStringSubmatch(strings.ToLower(s))
+	if matches == nil {
+		return nil
+	}
+	month, ok := frenchMonths[matches[2]]
+	if !ok {
+		return nil
+	}
+	day, _ := strconv.Atoi(matches[1])
+	year, _ := strconv.Atoi(matches[3])
+	hour, _ := strconv.Atoi(matches[4])
+	minute, _ := strconv.Atoi(matches[5])
+	t := time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	if t.Day() != day {
+		return nil // "31 février"
+	}
+	return &t
+}
+
+// propertyEdge retourne la relation N4L qui déclare un attribut
+func propertyEdge(nodeID string, property models.NodeProperty) models.Edge {
+	return models.Edge{
+		From:     nodeID,
+		To:       property.Value,
+		Label:    property.Name,
+		Type:     "relation",
+		Context:  property.Context,
+		Contexts: ContextPaths(property.Context),
+		Spans:    property.Spans,
+
+		Confidence: property.Confidence,
+		Source:     property.Source,
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"n4l-editor/models"
+)
+
+func TestNodeProperties(t *testing.T) {
+	source := strings.Join([]string{
+		"Victor Moreau (âge) 67 ans",
+		"Victor Moreau (pèse) 82,5",
+		"Victor Moreau (métier) notaire",
+		"Victor Moreau -> connaît -> Marie",
+		"Crime (date) 12/03/2024 14h30",
+		"Arrestation (date) 2 avril 2024",
+		"Marie (nombre) environ trois",
+		"Lettre (montant) 1 500 €",
+	}, "\n")
+	parser := NewN4LParser()
+	graph := parser.ParseN4LToGraph(parser.ParseN4L(source).Notes)
+
+	nodes := make(map[string]models.Node)
+	for _, node := range graph.Nodes {
+		nodes[node.ID] = node
+	}
+	for _, value := range []string{"67 ans", "82,5", "notaire", "12/03/2024 14h30"} {
+		if _, ok := nodes[value]; ok {
+			t.Errorf("la valeur %q ne doit pas être un nœud", value)
+		}
+	}
+	if len(graph.Edges) != 1 {
+		t.Errorf("seule la relation connaît doit rester une arête : %+v", graph.Edges)
+	}
+
+	props := make(map[string]models.NodeProperty)
+	for _, node := range graph.Nodes {
+		for _, p := range node.Properties {
+			props[node.ID+"."+p.Name] = p
+		}
+	}
+	check := func(key, typ string, number float64, unit string) {
+		t.Helper()
+		p, ok := props[key]
+		switch {
+		case !ok:
+			t.Errorf("%s absent", key)
+		case p.Type != typ || p.Unit != unit:
+			t.Errorf("%s : %+v", key, p)
+		case typ == ValueQuantity && (p.Number == nil || *p.Number != number):
+			t.Errorf("%s : valeur %v", key, p.Number)
+		}
+	}
+	check("Victor Moreau.âge", ValueQuantity, 67, "ans")
+	check("Victor Moreau.poids", ValueQuantity, 82.5, "kg")
+	check("Lettre.montant", ValueQuantity, 1500, "€")
+	check("Victor Moreau.profession", ValueString, 0, "")
+	check("Marie.nombre", ValueString, 0, "") // valeur non numérique conservée en texte
+
+	if d := props["Crime.date"].Date; d == nil || !d.Equal(time.Date(2024, 3, 12, 14, 30, 0, 0, time.UTC)) {
+		t.Errorf("date du crime : %v", d)
+	}
+	if d := props["Arrestation.date"].Date; d == nil || !d.Equal(time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("date de l'arrestation : %v", d)
+	}
+
+	var dated []string
+	for _, event := range NewGraphAnalyzer().GetTimelineEvents(parser.ParseN4L(source).Notes) {
+		if event.DateTime != nil {
+			dated = append(dated, event.Actor)
+		}
+	}
+	if strings.Join(dated, ",") != "Crime,Arrestation" {
+		t.Errorf("événements datés : %v", dated)
+	}
+}
+
+func TestNonDecimalValuesStayText(t *testing.T) {
+	source := strings.Join([]string{
+		"Sonde (nombre) inf",
+		"Capteur (nombre) NaN",
+		"Compteur (nombre) 0x10",
+		"Relevé (nombre) 1e400",
+		"Mesure (nombre) -2,5e3",
+	}, "\n")
+	parser := NewN4LParser()
+	graph := parser.ParseN4LToGraph(parser.ParseN4L(source).Notes)
+	for _, node := range graph.Nodes {
+		for _, p := range node.Properties {
+			if node.ID == "Mesure" {
+				if p.Type != ValueNumber || p.Number == nil || *p.Number != -2500 {
+					t.Errorf("%s : %+v", node.ID, p)
+				}
+			} else if p.Type != ValueString || p.Number != nil {
+				t.Errorf("%s : %q doit rester du texte : %+v", node.ID, p.Value, p)
+			}
+		}
+	}
+	if _, err := json.Marshal(graph); err != nil {
+		t.Errorf("graphe non encodable : %v", err)
+	}
+
+	for _, name := range []string{"nan", "Inf", "infinity", "-NaN"} {
+		if isValueName(name) {
+			t.Errorf("%q typé comme valeur", name)
+		}
+	}
+}
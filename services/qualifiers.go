@@ -0,0 +1,191 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"n4l-editor/models"
+)
+
+// Qualificatifs d'une relation, en fin de ligne :
+//
+//	Marie (a vu) Paul [certitude=0.4; source=rumeur du village]
+//	Couteau (porte) Empreinte [certitude=certain; source=rapport du légiste; poids=3]
+var qualifierRegex = regexp.MustCompile(`\s*\[([^\[\]]*=[^\[\]]*)\]\s*$`)
+
+// Niveaux de certitude nommés
+var confidenceLevels = map[string]float64{
+	"certain":   1,
+	"certaine":  1,
+	"probable":  0.75,
+	"possible":  0.5,
+	"hypothèse": 0.5,
+	"douteux":   0.25,
+	"douteuse":  0.25,
+	"rumeur":    0.1,
+}
+
+// qualifierKeys associe les clés acceptées à leur nom canonique
+var qualifierKeys = map[string]string{
+	"certitude":  "certitude",
+	"confiance":  "certitude",
+	"certainty":  "certitude",
+	"confidence": "certitude",
+	"source":     "source",
+	"poids":      "poids",
+	"weight":     "poids",
+}
+
+// Bornes d'un poids : au-delà, le coût 1/poids d'une arête cesse d'être un
+// nombre fini utilisable par les calculs de chemins et de centralité
+const (
+	minEdgeWeight = 1e-6
+	maxEdgeWeight = 1e6
+)
+
+// splitQualifier sépare une ligne de ses qualificatifs "[clé=valeur; ...]"
+func splitQualifier(line string) (string, string, bool) {
+	loc := qualifierRegex.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return line, "", false
+	}
+	return line[:loc[0]], line[loc[2]:loc[3]], true
+}
+
+// parseQualifier lit les qualificatifs d'une relation et signale les valeurs
+// invalides, qui sont ignorées
+func parseQualifier(text string) (models.N4LQualifier, []string) {
+	var q models.N4LQualifier
+	var problems []string
+
+	separator := ","
+	if strings.Contains(text, ";") {
+		separator = ";" // la source peut alors contenir des virgules
+	}
+	lastKey := ""
+	for _, field := range strings.Split(text, separator) {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(field, "=")
+		if !ok && lastKey == "source" {
+			// "[source=rapport, page 3]" : la virgule fait partie de la source
+			q.Source += separator + " " + field
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		lastKey = qualifierKeys[key]
+		if !ok || value == "" {
+			problems = append(problems, fmt.Sprintf("qualificatif %q sans valeur", field))
+			continue
+		}
+
+		switch qualifierKeys[key] {
+		case "certitude":
+			if c, ok := parseConfidence(value); ok {
+				q.Confidence = c
+			} else {
+				problems = append(problems, fmt.Sprintf("certitude %q invalide (nombre dans ]0, 1], pourcentage ou certain, probable, possible, douteux, rumeur)", value))
+			}
+		case "source":
+			q.Source = value
+		case "poids":
+			if w, ok := parseWeight(value); ok {
+				q.Weight = w
+			} else {
+				problems = append(problems, fmt.Sprintf("poids %q invalide (nombre entre %g et %g attendu)", value, minEdgeWeight, maxEdgeWeight))
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("qualificatif inconnu %q (certitude, source, poids)", key))
+		}
+	}
+	return q, problems
+}
+
+// parseConfidence lit une certitude : "0.8", "0,8", "80%" ou un niveau nommé.
+// 0 est refusé : une arête sans certitude est tenue pour certaine (voir
+// EdgeConfidence) et son coût 1/force serait infini.
+func parseConfidence(value string) (float64, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if c, ok := confidenceLevels[value]; ok {
+		return c, true
+	}
+	scale := 1.0
+	if strings.HasSuffix(value, "%") {
+		value, scale = strings.TrimSpace(strings.TrimSuffix(value, "%")), 100
+	}
+	c, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	if err != nil {
+		return 0, false
+	}
+	c /= scale
+	return c, c > 0 && c <= 1
+}
+
+// parseWeight lit un poids : "3", "0,5"... L'infini, NaN et les valeurs hors
+// bornes sont refusés, comme pour les propriétés (voir parseNumber), car ils
+// ne s'encodent pas en JSON
+func parseWeight(value string) (float64, bool) {
+	w, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	if err != nil || math.IsInf(w, 0) || math.IsNaN(w) {
+		return 0, false
+	}
+	return w, w >= minEdgeWeight && w <= maxEdgeWeight
+}
+
+// formatQualifier écrit les qualificatifs d'une arête ("" s'il n'y en a pas)
+func formatQualifier(confidence float64, source string, weight float64) string {
+	var fields []string
+	if confidence > 0 {
+		fields = append(fields, "certitude="+strconv.FormatFloat(confidence, 'g', -1, 64))
+	}
+	if source != "" {
+		fields = append(fields, "source="+source)
+	}
+	if weight > 0 {
+		fields = append(fields, "poids="+strconv.FormatFloat(weight, 'g', -1, 64))
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(fields, "; ") + "]"
+}
+
+// EdgeConfidence retourne la certitude d'une arête, 1 si elle n'est pas précisée
+func EdgeConfidence(edge models.Edge) float64 {
+	if edge.Confidence > 0 {
+		return edge.Confidence
+	}
+	return 1
+}
+
+// EdgeWeight retourne le poids d'une arête, 1 s'il n'est pas précisé
+func EdgeWeight(edge models.Edge) float64 {
+	if edge.Weight > 0 {
+		return edge.Weight
+	}
+	return 1
+}
+
+// EdgeStrength combine certitude et poids : un fait établi et important
+// pèse plus qu'une hypothèse secondaire
+func EdgeStrength(edge models.Edge) float64 {
+	return EdgeConfidence(edge) * EdgeWeight(edge)
+}
+
+// edgeSupport retourne la certitude moyenne, pondérée par les poids, des
+// arêtes d'un nœud et leur nombre
+func edgeSupport(edges []models.Edge) (float64, int) {
+	if len(edges) == 0 {
+		return 0, 0
+	}
+	var total, weights float64
+	for _, edge := range edges {
+		total += EdgeStrength(edge)
+		weights += EdgeWeight(edge)
+	}
+	return total / weights, len(edges)
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"n4l-editor/models"
+)
+
+func TestEdgeQualifiers(t *testing.T) {
+	source := strings.Join([]string{
+		"Marie (a vu) Paul [certitude=40%; source=rumeur du village, selon le boulanger]",
+		"Couteau -> porte -> Empreinte [certitude=certain, source=rapport du légiste, poids=3]",
+		"Paul (connaît) Marie [certitude=2]",
+		"Paul (évite) Marie [certitude=0]",
+		"Paul (suit) Marie [certitude=0%]",
+		"Paul (craint) Marie [couleur=rouge]",
+		"Introduction [à relire]",
+	}, "\n")
+	parser := NewN4LParser()
+	parsed := parser.ParseN4L(source)
+	graph := parser.ParseN4LToGraph(parsed.Notes)
+
+	got := make(map[string]models.Edge)
+	for _, edge := range graph.Edges {
+		got[edge.Label] = edge
+	}
+	if e := got["a vu"]; e.Confidence != 0.4 || e.Source != "rumeur du village, selon le boulanger" {
+		t.Errorf("a vu : %+v", e)
+	}
+	if e := got["porte"]; e.Confidence != 1 || e.Source != "rapport du légiste" || e.Weight != 3 {
+		t.Errorf("porte : %+v", e)
+	}
+	if e := got["connaît"]; EdgeConfidence(e) != 1 || e.To != "Marie" {
+		t.Errorf("une certitude invalide est ignorée : %+v", e)
+	}
+	for _, label := range []string{"évite", "suit"} {
+		if e := got[label]; e.Confidence != 0 || EdgeConfidence(e) != 1 {
+			t.Errorf("certitude nulle hors de ]0, 1] : %+v", e)
+		}
+	}
+
+	var lines []int
+	for _, d := range parsed.Diagnostics {
+		if d.Code == DiagInvalidQualifier {
+			lines = append(lines, d.Pos.Line)
+		}
+	}
+	if !reflect.DeepEqual(lines, []int{3, 4, 5, 6}) {
+		t.Errorf("diagnostics N4L013 attendus lignes 3 à 6 : %v", parsed.Diagnostics)
+	}
+}
+
+func TestPathsPreferEstablishedFacts(t *testing.T) {
+	parser := NewN4LParser()
+	source := strings.Join([]string{
+		"Suspect (vu à) Gare [certitude=rumeur]",
+		"Suspect (achète) Billet",
+		"Billet (valable pour) Gare",
+	}, "\n")
+	graph := parser.ParseN4LToGraph(parser.ParseN4L(source).Notes)
+
+	ga := NewGraphAnalyzer()
+	path, _ := ga.findPath("Suspect", "Gare", ga.buildAdjacencyListFromGraphData(graph))
+	if want := []string{"Suspect", "Billet", "Gare"}; !reflect.DeepEqual(path, want) {
+		t.Errorf("chemin %v, attendu %v", path, want)
+	}
+
+	if weak, strong := ga.calculateNodeImportance("Gare", NewGraph(graph)), ga.calculateNodeImportance("Billet", NewGraph(graph)); weak >= strong {
+		t.Errorf("importance : Gare %.2f, Billet %.2f", weak, strong)
+	}
+}
+
+func TestInvalidEdgeWeights(t *testing.T) {
+	for _, weight := range []string{"inf", "+Inf", "NaN", "1e999", "5e-324", "0", "-2", "1e7"} {
+		parser := NewN4LParser()
+		parsed := parser.ParseN4L("A (cause) B [poids=" + weight + "]")
+		graph := parser.ParseN4LToGraph(parsed.Notes)
+
+		if len(graph.Edges) != 1 || graph.Edges[0].Weight != 0 {
+			t.Errorf("poids=%s : %+v", weight, graph.Edges)
+		}
+		reported := false
+		for _, d := range parsed.Diagnostics {
+			reported = reported || d.Code == DiagInvalidQualifier
+		}
+		if !reported {
+			t.Errorf("poids=%s : diagnostic N4L013 attendu, %v", weight, parsed.Diagnostics)
+		}
+		if _, err := json.Marshal(graph); err != nil {
+			t.Errorf("poids=%s : %v", weight, err)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Classes sémantiques des relations
+const (
+	ClassLeadsTo     = "leads-to"    // ordre, causalité : A mène à B
+	ClassContains    = "contains"    // appartenance : A contient B
+	ClassProperty    = "property"    // attribut : A a pour propriété B
+	ClassSimilarity  = "similarity"  // proximité : A ressemble à B
+	ClassAssociation = "association" // lien social ou personnel : A est ami de B
+)
+
+// Types de valeur des relations de propriété : "Victor (âge) 67 ans" devient
+// un attribut du nœud Victor plutôt qu'une arête vers un nœud "67 ans"
+const (
+	ValueString   = "string"
+	ValueNumber   = "number"
+	ValueDate     = "date"
+	ValueQuantity = "quantity" // nombre suivi d'une unité
+)
+
+var valueTypes = map[string]bool{
+	ValueString:   true,
+	ValueNumber:   true,
+	ValueDate:     true,
+	ValueQuantity: true,
+}
+
+var relationClasses = map[string]bool{
+	ClassLeadsTo:     true,
+	ClassContains:    true,
+	ClassProperty:    true,
+	ClassSimilarity:  true,
+	ClassAssociation: true,
+}
+
+// RelationType décrit une flèche du registre. Une relation dont Inverse vaut
+// son propre nom est symétrique ; une relation de classe property dotée d'un
+// ValueType produit des attributs de nœud typés au lieu d'arêtes.
+type RelationType struct {
+	Name           string   `json:"name"`
+	Aliases        []string `json:"aliases,omitempty"`
+	Inverse        string   `json:"inverse,omitempty"`
+	InverseAliases []string `json:"inverseAliases,omitempty"`
+	Class          string   `json:"class,omitempty"`
+	Contradicts    []string `json:"contradicts,omitempty"`
+	ValueType      string   `json:"valueType,omitempty"`
+	Unit           string   `json:"unit,omitempty"` // unité par défaut d'une quantité
+
+	reversed bool // déclarée comme inverse d'une autre relation
+}
+
+// RelationRegistry normalise les libellés de relation et expose leur sémantique
+type RelationRegistry struct {
+	types   map[string]*RelationType // par nom canonique
+	byLabel map[string]string        // libellé en minuscules -> nom canonique
+	names   []string                 // noms canoniques, dans l'ordre de déclaration
+}
+
+//go:embed relations.json
+var defaultRelationsJSON []byte
+
+var (
+	defaultRelationsMu sync.RWMutex
+	defaultRelations   *RelationRegistry
+)
+
+// DefaultRelationRegistry retourne le registre utilisé par les nouveaux
+// parsers et analyseurs : celui fourni avec l'application, sauf remplacement
+// par SetDefaultRelationRegistry
+func DefaultRelationRegistry() *RelationRegistry {
+	defaultRelationsMu.RLock()
+	registry := defaultRelations
+	defaultRelationsMu.RUnlock()
+	if registry != nil {
+		return registry
+	}
+
+	defaultRelationsMu.Lock()
+	defer defaultRelationsMu.Unlock()
+	if defaultRelations == nil {
+		registry, err := ParseRelationRegistry(defaultRelationsJSON)
+		if err != nil {
+			panic("registre de relations par défaut invalide : " + err.Error())
+		}
+		defaultRelations = registry
+	}
+	return defaultRelations
+}
+
+// SetDefaultRelationRegistry remplace le registre utilisé par les parsers et
+// analyseurs créés ensuite
+func SetDefaultRelationRegistry(registry *RelationRegistry) {
+	defaultRelationsMu.Lock()
+	defaultRelations = registry
+	defaultRelationsMu.Unlock()
+}
+
+// LoadRelationRegistry lit un registre depuis un fichier JSON
+func LoadRelationRegistry(path string) (*RelationRegistry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	registry, err := ParseRelationRegistry(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", path, err)
+	}
+	return registry, nil
+}
+
+// ParseRelationRegistry construit un registre à partir de sa description JSON :
+//
+//	{"relations": [{"name": "précède", "aliases": ["avant"], "inverse": "suit", "class": "leads-to"}]}
+func ParseRelationRegistry(data []byte) (*RelationRegistry, error) {
+	var config struct {
+		Relations []RelationType `json:"relations"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return NewRelationRegistry(config.Relations)
+}
+
+// NewRelationRegistry construit un registre et vérifie sa cohérence : noms et
+// alias uniques, classes connues, contradictions vers des relations déclarées
+func NewRelationRegistry(types []RelationType) (*RelationRegistry, error) {
+	r := &RelationRegistry{
+		types:   make(map[string]*RelationType),
+		byLabel: make(map[string]string),
+	}
+
+	for _, t := range types {
+		t.Name = strings.TrimSpace(t.Name)
+		if t.Name == "" {
+			return nil, fmt.Errorf("relation sans nom")
+		}
+		if t.Class != "" && !relationClasses[t.Class] {
+			return nil, fmt.Errorf("relation %q : classe inconnue %q", t.Name, t.Class)
+		}
+		if t.ValueType != "" && (!valueTypes[t.ValueType] || t.Class != ClassProperty) {
+			return nil, fmt.Errorf("relation %q : type de valeur %q invalide (types connus : string, number, date, quantity, pour la classe property)", t.Name, t.ValueType)
+		}
+		if t.ValueType != "" && t.Inverse != "" {
+			return nil, fmt.Errorf("relation %q : une propriété typée n'a pas d'inverse", t.Name)
+		}
+		if err := r.add(t, t.Name, t.Aliases); err != nil {
+			return nil, err
+		}
+
+		inverse := strings.TrimSpace(t.Inverse)
+		if inverse == "" || inverse == t.Name {
+			continue
+		}
+		if err := r.add(RelationType{
+			Name:     inverse,
+			Inverse:  t.Name,
+			Class:    t.Class,
+			reversed: true,
+		}, inverse, t.InverseAliases); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range r.names {
+		for _, other := range r.types[name].Contradicts {
+			if _, ok := r.Lookup(other); !ok {
+				return nil, fmt.Errorf("relation %q : contradiction avec une relation inconnue %q", name, other)
+			}
+		}
+	}
+	return r, nil
+}
+
+// add enregistre une relation sous son nom et ses alias
+func (r *RelationRegistry) add(t RelationType, name string, aliases []string) error {
+	if _, exists := r.types[name]; exists {
+		return fmt.Errorf("relation %q déclarée plusieurs fois", name)
+	}
+	t.Name = name
+	t.Aliases = nil
+	r.types[name] = &t
+	r.names = append(r.names, name)
+
+	for _, label := range append([]string{name}, aliases...) {
+		key := strings.ToLower(strings.TrimSpace(label))
+		if key == "" {
+			continue
+		}
+		if previous, exists := r.byLabel[key]; exists && previous != name {
+			return fmt.Errorf("libellé %q attribué à %q et à %q", label, previous, name)
+		}
+		r.byLabel[key] = name
+		if label != name {
+			t.Aliases = append(t.Aliases, label)
+		}
+	}
+	return nil
+}
+
+// Lookup retourne la relation désignée par un libellé ou l'un de ses alias
+func (r *RelationRegistry) Lookup(label string) (RelationType, bool) {
+	name, ok := r.byLabel[strings.ToLower(strings.TrimSpace(label))]
+	if !ok {
+		return RelationType{}, false
+	}
+	return *r.types[name], true
+}
+
+// Canonical retourne le nom canonique d'un libellé, ou le libellé inchangé
+// s'il est absent du registre
+func (r *RelationRegistry) Canonical(label string) string {
+	if t, ok := r.Lookup(label); ok {
+		return t.Name
+	}
+	return label
+}
+
+// Class retourne la classe sémantique d'un libellé ("" si inconnue)
+func (r *RelationRegistry) Class(label string) string {
+	t, _ := r.Lookup(label)
+	return t.Class
+}
+
+// ValueType retourne le type de valeur d'une relation de propriété ("" pour
+// une relation ordinaire)
+func (r *RelationRegistry) ValueType(label string) string {
+	t, _ := r.Lookup(label)
+	return t.ValueType
+}
+
+// Inverse retourne le nom de la relation inverse d'un libellé
+func (r *RelationRegistry) Inverse(label string) (string, bool) {
+	t, ok := r.Lookup(label)
+	if !ok || t.Inverse == "" {
+		return "", false
+	}
+	return t.Inverse, true
+}
+
+// Orient ramène une relation à son sens de déclaration : "B suit A" devient
+// "A précède B". Les libellés inconnus sont rendus tels quels.
+func (r *RelationRegistry) Orient(from, label, to string) (string, string, string) {
+	t, ok := r.Lookup(label)
+	if !ok {
+		return from, label, to
+	}
+	if t.reversed {
+		return to, t.Inverse, from
+	}
+	return from, t.Name, to
+}
+
+// Contradicts indique si deux relations entre les mêmes éléments, dans le même
+// sens, s'excluent : contradiction déclarée, ou relation et son inverse
+func (r *RelationRegistry) Contradicts(label1, label2 string) bool {
+	t1, ok1 := r.Lookup(label1)
+	t2, ok2 := r.Lookup(label2)
+	if !ok1 || !ok2 || t1.Name == t2.Name {
+		return false
+	}
+	if t1.Inverse == t2.Name {
+		return true
+	}
+	return containsString(t1.Contradicts, t2.Name) || containsString(t2.Contradicts, t1.Name)
+}
+
+// Types retourne les relations du registre, triées par nom
+func (r *RelationRegistry) Types() []RelationType {
+	names := append([]string(nil), r.names...)
+	sort.Strings(names)
+
+	types := make([]RelationType, 0, len(names))
+	for _, name := range names {
+		types = append(types, *r.types[name])
+	}
+	return types
+}
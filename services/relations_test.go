@@ -0,0 +1,98 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRelationRegistry(t *testing.T) {
+	registry, err := ParseRelationRegistry([]byte(`{"relations": [
+		{"name": "précède", "aliases": ["avant"], "inverse": "suit", "inverseAliases": ["après"], "class": "leads-to"},
+		{"name": "ami de", "inverse": "ami de", "class": "association", "contradicts": ["ennemi de"]},
+		{"name": "ennemi de", "class": "association"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := registry.Canonical("Avant"); got != "précède" {
+		t.Errorf("Canonical(Avant) = %q", got)
+	}
+	if got := registry.Canonical("connaît"); got != "connaît" {
+		t.Errorf("un libellé inconnu doit rester inchangé : %q", got)
+	}
+	if got := registry.Class("après"); got != ClassLeadsTo {
+		t.Errorf("l'inverse hérite de la classe : %q", got)
+	}
+	// Les relations entre personnes ne sont pas des ressemblances
+	for _, name := range []string{"ennemi", "ami de", "épouse de"} {
+		if got := DefaultRelationRegistry().Class(name); got != ClassAssociation {
+			t.Errorf("classe de %q : %q", name, got)
+		}
+	}
+	if from, label, to := registry.Orient("B", "après", "A"); from != "A" || label != "précède" || to != "B" {
+		t.Errorf("Orient(B après A) = %s %s %s", from, label, to)
+	}
+
+	for _, tt := range []struct {
+		a, b string
+		want bool
+	}{
+		{"précède", "suit", true},
+		{"avant", "après", true},
+		{"ennemi de", "ami de", true},
+		{"ami de", "ami de", false},
+		{"précède", "ami de", false},
+		{"précède", "inconnu", false},
+	} {
+		if got := registry.Contradicts(tt.a, tt.b); got != tt.want {
+			t.Errorf("Contradicts(%q, %q) = %v", tt.a, tt.b, got)
+		}
+	}
+}
+
+func TestRelationRegistryRejectsInvalidConfig(t *testing.T) {
+	for name, config := range map[string]string{
+		"alias en double":    `{"relations": [{"name": "a", "aliases": ["x"]}, {"name": "b", "aliases": ["X"]}]}`,
+		"classe inconnue":    `{"relations": [{"name": "a", "class": "cause"}]}`,
+		"contradiction":      `{"relations": [{"name": "a", "contradicts": ["b"]}]}`,
+		"nom manquant":       `{"relations": [{"aliases": ["x"]}]}`,
+		"inverse déjà connu": `{"relations": [{"name": "a", "inverse": "b"}, {"name": "b"}]}`,
+		"type de valeur":     `{"relations": [{"name": "a", "class": "similarity", "valueType": "number"}]}`,
+	} {
+		if _, err := ParseRelationRegistry([]byte(config)); err == nil {
+			t.Errorf("%s : erreur attendue", name)
+		}
+	}
+}
+
+func TestDefaultRegistryDrivesParserAndAnalyzer(t *testing.T) {
+	parser := NewN4LParser()
+	source := strings.Join([]string{
+		"A -> avant -> B",
+		"B -> ensuite -> C",
+		"A -> suit -> C",
+		"X -> ami -> Y",
+		"X -> ennemi de -> Y",
+	}, "\n")
+	graph := parser.ParseN4LToGraph(parser.ParseN4L(source).Notes)
+
+	labels := make(map[string]bool)
+	for _, edge := range graph.Edges {
+		labels[edge.Label] = true
+	}
+	for _, want := range []string{"précède", "puis", "suit", "ami de"} {
+		if !labels[want] {
+			t.Errorf("libellé canonique %q attendu : %+v", want, graph.Edges)
+		}
+	}
+
+	types := make(map[string]int)
+	for _, inconsistency := range NewGraphAnalyzer().CheckSemanticConsistency(graph) {
+		types[inconsistency.Type]++
+	}
+	// "A suit C" ferme le cycle A -> B -> C -> A
+	if types["temporal_cycle"] == 0 || types["contradictory_relations"] != 1 {
+		t.Errorf("incohérences inattendues : %v", types)
+	}
+}
@@ -0,0 +1,384 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"n4l-editor/models"
+)
+
+// TypeLabel est le nom canonique de la propriété qui déclare le type d'un
+// nœud : "Jean (type) personne"
+const TypeLabel = "type"
+
+// ValueNodeType est le type implicite des nœuds dont le nom est une valeur
+// (nombre, quantité, date) : "45 ans", "12/03/2024"
+const ValueNodeType = "valeur"
+
+// NodeType est un type de nœud du schéma. Un sous-type (Parent) hérite des
+// relations permises et des propriétés requises de son parent.
+type NodeType struct {
+	Name     string   `json:"name"`
+	Aliases  []string `json:"aliases,omitempty"`
+	Parent   string   `json:"parent,omitempty"`
+	Required []string `json:"required,omitempty"` // propriétés ou relations obligatoires
+}
+
+// RelationConstraint restreint une relation (ou une propriété typée) : types
+// permis à la source et à la cible, nombre maximal par source (0 : illimité)
+type RelationConstraint struct {
+	Relation string   `json:"relation"`
+	From     []string `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+	Max      int      `json:"max,omitempty"`
+}
+
+// Schema décrit l'ontologie d'un projet. Seuls les nœuds typés sont
+// contrôlés : un nœud sans type déclaré accepte toutes les relations.
+type Schema struct {
+	types       map[string]*NodeType // par nom
+	byLabel     map[string]string    // nom ou alias en minuscules -> nom
+	names       []string
+	constraints []RelationConstraint
+	relations   *RelationRegistry
+}
+
+var (
+	defaultSchemaMu sync.RWMutex
+	defaultSchema   *Schema
+)
+
+// DefaultSchema retourne le schéma utilisé par les nouveaux analyseurs, nil
+// tant qu'aucun n'a été déclaré par SetDefaultSchema
+func DefaultSchema() *Schema {
+	defaultSchemaMu.RLock()
+	defer defaultSchemaMu.RUnlock()
+	return defaultSchema
+}
+
+// SetDefaultSchema remplace le schéma utilisé par les analyseurs créés ensuite
+func SetDefaultSchema(schema *Schema) {
+	defaultSchemaMu.Lock()
+	defaultSchema = schema
+	defaultSchemaMu.Unlock()
+}
+
+// LoadSchema lit un schéma depuis un fichier JSON
+func LoadSchema(path string) (*Schema, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := ParseSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", path, err)
+	}
+	return schema, nil
+}
+
+// ParseSchema construit un schéma à partir de sa description JSON :
+//
+//	{"types": [{"name": "personne", "required": ["naissance"]}, {"name": "lieu"}],
+//	 "relations": [{"relation": "marié à", "from": ["personne"], "to": ["personne"], "max": 1}]}
+func ParseSchema(data []byte) (*Schema, error) {
+	var config struct {
+		Types     []NodeType           `json:"types"`
+		Relations []RelationConstraint `json:"relations"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return NewSchema(config.Types, config.Relations, DefaultRelationRegistry())
+}
+
+// NewSchema construit un schéma et vérifie sa cohérence : noms et alias
+// uniques, parents connus et sans cycle, contraintes portant sur des types
+// déclarés. Les relations sont ramenées à leur nom canonique du registre.
+func NewSchema(types []NodeType, constraints []RelationConstraint, relations *RelationRegistry) (*Schema, error) {
+	s := &Schema{
+		types:     make(map[string]*NodeType),
+		byLabel:   map[string]string{ValueNodeType: ValueNodeType},
+		relations: relations,
+	}
+
+	for _, t := range types {
+		t.Name = strings.TrimSpace(t.Name)
+		if t.Name == "" {
+			return nil, fmt.Errorf("type de nœud sans nom")
+		}
+		for _, label := range append([]string{t.Name}, t.Aliases...) {
+			key := strings.ToLower(strings.TrimSpace(label))
+			if _, exists := s.byLabel[key]; exists {
+				return nil, fmt.Errorf("type %q : nom %q déjà utilisé", t.Name, label)
+			}
+			s.byLabel[key] = t.Name
+		}
+		t := t
+		s.types[t.Name] = &t
+		s.names = append(s.names, t.Name)
+	}
+
+	for _, name := range s.names {
+		t := s.types[name]
+		if t.Parent == "" {
+			continue
+		}
+		parent, ok := s.resolve(t.Parent)
+		if !ok || parent == ValueNodeType {
+			return nil, fmt.Errorf("type %q : parent inconnu %q", name, t.Parent)
+		}
+		t.Parent = parent
+	}
+	for _, name := range s.names {
+		seen := map[string]bool{name: true}
+		for p := s.types[name].Parent; p != ""; p = s.types[p].Parent {
+			if seen[p] {
+				return nil, fmt.Errorf("type %q : héritage circulaire", name)
+			}
+			seen[p] = true
+		}
+	}
+
+	for _, c := range constraints {
+		c.Relation = strings.TrimSpace(c.Relation)
+		if c.Relation == "" {
+			return nil, fmt.Errorf("contrainte sans relation")
+		}
+		c.Relation = relations.Canonical(c.Relation)
+		var err error
+		if c.From, err = s.resolveAll(c.Relation, c.From); err != nil {
+			return nil, err
+		}
+		if c.To, err = s.resolveAll(c.Relation, c.To); err != nil {
+			return nil, err
+		}
+		if c.Max < 0 {
+			return nil, fmt.Errorf("relation %q : cardinalité négative", c.Relation)
+		}
+		s.constraints = append(s.constraints, c)
+	}
+	return s, nil
+}
+
+// resolve retourne le nom d'un type désigné par son nom ou un alias
+func (s *Schema) resolve(label string) (string, bool) {
+	name, ok := s.byLabel[strings.ToLower(strings.TrimSpace(label))]
+	return name, ok
+}
+
+// resolveAll remplace les types permis par une contrainte par leur nom
+func (s *Schema) resolveAll(relation string, labels []string) ([]string, error) {
+	var names []string
+	for _, label := range labels {
+		name, ok := s.resolve(label)
+		if !ok {
+			return nil, fmt.Errorf("relation %q : type inconnu %q", relation, label)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// isA indique si un type est celui attendu ou l'un de ses sous-types
+func (s *Schema) isA(name, expected string) bool {
+	for name != "" {
+		if name == expected {
+			return true
+		}
+		t, ok := s.types[name]
+		if !ok {
+			return false
+		}
+		name = t.Parent
+	}
+	return false
+}
+
+// matches indique si l'un des types d'un nœud figure parmi les types permis
+func (s *Schema) matches(nodeTypes, allowed []string) bool {
+	for _, t := range nodeTypes {
+		for _, a := range allowed {
+			if s.isA(t, a) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NodeTypes retourne les types d'un nœud : ceux déclarés par la propriété
+// "type", "événement" pour un nœud d'événement si le schéma le connaît,
+// "valeur" pour un nom qui est un nombre, une quantité ou une date. Les
+// déclarations inconnues du schéma sont renvoyées à part.
+func (s *Schema) NodeTypes(node models.Node) (types, unknown []string) {
+	for _, property := range node.Properties {
+		if s.relations.Canonical(property.Name) != TypeLabel {
+			continue
+		}
+		if name, ok := s.resolve(property.Value); ok {
+			types = append(types, name)
+		} else {
+			unknown = append(unknown, property.Value)
+		}
+	}
+	if node.Kind == EventKind {
+		if name, ok := s.resolve(EventKind); ok {
+			types = append(types, name)
+		}
+	}
+	if len(types) == 0 && isValueName(node.ID) {
+		types = append(types, ValueNodeType)
+	}
+	return types, unknown
+}
+
+// isValueName indique si un nom de nœud est une valeur plutôt qu'une entité
+func isValueName(name string) bool {
+	if _, ok := parseNumber(name); ok {
+		return true
+	}
+	if m := quantityRegex.FindStringSubmatch(name); m != nil && len(strings.Fields(m[2])) <= 1 {
+		if _, ok := parseNumber(m[1]); ok {
+			return true
+		}
+	}
+	return parsePropertyDate(name) != nil
+}
+
+// Validate confronte un graphe au schéma : types inconnus, relations entre
+// types non permis, cardinalités dépassées et propriétés requises absentes
+func (s *Schema) Validate(graph models.GraphData) []models.Inconsistency {
+	var inconsistencies []models.Inconsistency
+
+	nodeTypes := make(map[string][]string)
+	for _, node := range graph.Nodes {
+		types, unknown := s.NodeTypes(node)
+		nodeTypes[node.ID] = types
+		for _, name := range unknown {
+			inconsistencies = append(inconsistencies, models.Inconsistency{
+				Type:        "schema_unknown_type",
+				Description: fmt.Sprintf("Type inconnu du schéma : %s (type) %s", node.ID, name),
+				Nodes:       []string{node.ID},
+				Severity:    "warning",
+				Suggestion:  fmt.Sprintf("Types déclarés : %s.", strings.Join(s.names, ", ")),
+			})
+		}
+	}
+
+	// Faits du graphe : arêtes et attributs, désignés par leur relation canonique
+	type fact struct {
+		from, label, to string
+		value           bool // attribut : la cible n'est pas un nœud
+	}
+	var facts []fact
+	for _, edge := range graph.Edges {
+		if edge.Type == "relation" || edge.Type == "role" {
+			facts = append(facts, fact{edge.From, s.relations.Canonical(edge.Label), edge.To, false})
+		}
+	}
+	for _, node := range graph.Nodes {
+		for _, property := range node.Properties {
+			facts = append(facts, fact{node.ID, s.relations.Canonical(property.Name), property.Value, true})
+		}
+	}
+
+	for _, c := range s.constraints {
+		counts := make(map[string][]string)
+		for _, f := range facts {
+			if f.label != c.Relation {
+				continue
+			}
+			counts[f.from] = append(counts[f.from], f.to)
+
+			if from := nodeTypes[f.from]; len(from) > 0 && len(c.From) > 0 && !s.matches(from, c.From) {
+				inconsistencies = append(inconsistencies, models.Inconsistency{
+					Type:        "schema_type",
+					Description: fmt.Sprintf("%s (%s) ne peut pas être la source de « %s » : %s attendu", f.from, strings.Join(from, ", "), c.Relation, strings.Join(c.From, " ou ")),
+					Nodes:       []string{f.from, f.to},
+					Severity:    "error",
+					Suggestion:  "Vérifiez le sujet de la relation ou le type déclaré du nœud.",
+				})
+			}
+			if to := nodeTypes[f.to]; !f.value && len(to) > 0 && len(c.To) > 0 && !s.matches(to, c.To) {
+				inconsistencies = append(inconsistencies, models.Inconsistency{
+					Type:        "schema_type",
+					Description: fmt.Sprintf("%s (%s) ne peut pas être la cible de « %s » : %s attendu", f.to, strings.Join(to, ", "), c.Relation, strings.Join(c.To, " ou ")),
+					Nodes:       []string{f.from, f.to},
+					Severity:    "error",
+					Suggestion:  "Vérifiez la cible de la relation ou le type déclaré du nœud.",
+				})
+			}
+		}
+
+		if c.Max == 0 {
+			continue
+		}
+		sources := make([]string, 0, len(counts))
+		for from := range counts {
+			sources = append(sources, from)
+		}
+		sort.Strings(sources)
+		for _, from := range sources {
+			if targets := counts[from]; len(targets) > c.Max {
+				inconsistencies = append(inconsistencies, models.Inconsistency{
+					Type:        "schema_cardinality",
+					Description: fmt.Sprintf("%s a %d « %s » (%s), au plus %d permis", from, len(targets), c.Relation, strings.Join(targets, ", "), c.Max),
+					Nodes:       []string{from},
+					Severity:    "error",
+					Suggestion:  "Une seule de ces valeurs est exacte : précisez la certitude ou la source de chacune.",
+				})
+			}
+		}
+	}
+
+	for _, node := range graph.Nodes {
+		for _, required := range s.required(nodeTypes[node.ID]) {
+			found := false
+			for _, f := range facts {
+				if f.from == node.ID && f.label == required {
+					found = true
+					break
+				}
+			}
+			if !found {
+				inconsistencies = append(inconsistencies, models.Inconsistency{
+					Type:        "schema_required",
+					Description: fmt.Sprintf("%s n'a pas de « %s », requis pour le type %s", node.ID, required, strings.Join(nodeTypes[node.ID], ", ")),
+					Nodes:       []string{node.ID},
+					Severity:    "warning",
+					Suggestion:  fmt.Sprintf("Ajoutez la ligne : %s (%s) ...", node.ID, required),
+				})
+			}
+		}
+	}
+
+	return inconsistencies
+}
+
+// required retourne les relations requises par des types, héritage compris
+func (s *Schema) required(types []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, name := range types {
+		for t := s.types[name]; t != nil; t = s.types[t.Parent] {
+			for _, r := range t.Required {
+				if r = s.relations.Canonical(strings.TrimSpace(r)); !seen[r] {
+					seen[r] = true
+					out = append(out, r)
+				}
+			}
+		}
+	}
+	return out
+}
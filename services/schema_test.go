@@ -0,0 +1,79 @@
+package services
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const testSchema = `{
+  "types": [
+    {"name": "personne", "aliases": ["person"], "required": ["naissance"]},
+    {"name": "suspect", "parent": "personne"},
+    {"name": "lieu", "aliases": ["place"]},
+    {"name": "événement", "aliases": ["event"]}
+  ],
+  "relations": [
+    {"relation": "est marié à", "from": ["personne"], "to": ["personne"], "max": 1},
+    {"relation": "date de naissance", "from": ["personne"], "max": 1},
+    {"relation": "agent", "from": ["événement"], "to": ["personne"]}
+  ]
+}`
+
+func TestSchemaValidate(t *testing.T) {
+	schema, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := strings.Join([]string{
+		"Jean (type) suspect",
+		"\" (né le) 12/03/1970",
+		"\" (née le) 14/03/1970",
+		"Marie (type) person",
+		"\" (naissance) 01/01/1975",
+		"Jean (marié à) Marie",
+		"Cave à vin (type) place",
+		"Cave à vin -> est marié à -> 45 ans",
+		"Paul (type) personnage",
+		"Vol => { agent: Cave à vin }",
+	}, "\n")
+	parser := NewN4LParser()
+	graph := parser.ParseN4LToGraph(parser.ParseN4L(source).Notes)
+
+	var got []string
+	for _, inc := range schema.Validate(graph) {
+		got = append(got, inc.Type+" "+strings.Join(inc.Nodes, ","))
+	}
+	sort.Strings(got)
+	want := []string{
+		"schema_cardinality Jean",
+		"schema_type Cave à vin,45 ans", // source de type lieu
+		"schema_type Cave à vin,45 ans", // cible de type valeur
+		"schema_type Vol,Cave à vin",
+		"schema_unknown_type Paul",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("incohérences :\n%s\nattendu :\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Une personne sans date de naissance
+	graph = parser.ParseN4LToGraph(parser.ParseN4L("Élodie (type) personne\n\" (connaît) Jean").Notes)
+	if inc := schema.Validate(graph); len(inc) != 1 || inc[0].Type != "schema_required" {
+		t.Errorf("propriété requise non signalée : %+v", inc)
+	}
+}
+
+func TestParseSchemaInvalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"type sans nom":       `{"types": [{"name": ""}]}`,
+		"alias en double":     `{"types": [{"name": "a", "aliases": ["b"]}, {"name": "b"}]}`,
+		"parent inconnu":      `{"types": [{"name": "a", "parent": "z"}]}`,
+		"héritage circulaire": `{"types": [{"name": "a", "parent": "b"}, {"name": "b", "parent": "a"}]}`,
+		"type de contrainte":  `{"types": [{"name": "a"}], "relations": [{"relation": "r", "from": ["z"]}]}`,
+	} {
+		if _, err := ParseSchema([]byte(data)); err == nil {
+			t.Errorf("%s : erreur attendue", name)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"n4l-editor/models"
+)
+
+// N4LSerializer reconstruit un texte N4L canonique à partir d'un graphe.
+// Le texte produit, une fois reparsé, redonne les mêmes nœuds et arêtes.
+type N4LSerializer struct{}
+
+// NewN4LSerializer crée une nouvelle instance du sérialiseur
+func NewN4LSerializer() *N4LSerializer {
+	return &N4LSerializer{}
+}
+
+// serializedLine est une ligne N4L en cours de construction
+type serializedLine struct {
+	kind      string   // "relation", "arrow", "equivalence", "group", "event"
+	items     []string // éléments de la relation, ou parent puis membres du groupe ("rôle: participant" pour un événement)
+	labels    []string
+	ditto     bool   // la source est le sujet de la ligne précédente
+	qualifier string // "[certitude=…; source=…; poids=…]" commun aux arêtes de la ligne
+}
+
+// Serialize produit le texte N4L d'un graphe : un en-tête par contexte, les
+// relations chaînées ou en continuation '"', les équivalences, les groupes et
+// les événements, dont les attributs sont écrits comme des rôles.
+// Seuls les nœuds reliés par une arête ou dotés d'attributs peuvent être
+// représentés.
+func (s *N4LSerializer) Serialize(graph models.GraphData) string {
+	edges := graph.Edges
+	for _, node := range graph.Nodes {
+		for _, property := range node.Properties {
+			edge := propertyEdge(node.ID, property)
+			if node.Kind == EventKind {
+				edge.Type = "role"
+			}
+			edges = append(edges, edge)
+		}
+	}
+
+	edgesByContext := make(map[string][]models.Edge)
+	for _, edge := range edges {
+		if edge.From == "" || edge.To == "" {
+			continue
+		}
+		context := edge.Context
+		if context == "" {
+			context = "general"
+		}
+		edgesByContext[context] = append(edgesByContext[context], edge)
+	}
+
+	contexts := make([]string, 0, len(edgesByContext))
+	for context := range edgesByContext {
+		contexts = append(contexts, context)
+	}
+	sort.Strings(contexts)
+
+	var out strings.Builder
+	for i, context := range contexts {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString(":: " + context + " ::\n\n")
+		for _, line := range s.buildLines(edgesByContext[context]) {
+			out.WriteString("    " + s.formatLine(line) + "\n")
+		}
+	}
+	return out.String()
+}
+
+// buildLines regroupe les arêtes d'un contexte en lignes N4L
+func (s *N4LSerializer) buildLines(edges []models.Edge) []*serializedLine {
+	var lines []*serializedLine
+	groups := make(map[string]*serializedLine)
+	var current *serializedLine
+
+	for _, edge := range edges {
+		// Une négation s'écrit comme une relation au libellé préfixé de "!"
+		if label, ok := NegatedLabel(edge); ok {
+			edge.Type, edge.Label = "relation", label
+		}
+		qualifier := formatQualifier(edge.Confidence, edge.Source, edge.Weight)
+		if strings.ContainsAny(edge.Source, "[]") {
+			qualifier = formatQualifier(edge.Confidence, "", edge.Weight)
+		}
+
+		switch edge.Type {
+		case "equivalence":
+			lines = append(lines, &serializedLine{kind: "equivalence", items: []string{edge.From, edge.To}, qualifier: qualifier})
+			current = nil
+
+		case "group":
+			groupKey := edge.From + "\x00" + qualifier
+			if group, ok := groups[groupKey]; ok && s.safeMember(edge.To) {
+				group.items = append(group.items, edge.To)
+				continue
+			}
+			if s.safeItem(edge.From) && s.safeMember(edge.To) {
+				group := &serializedLine{kind: "group", items: []string{edge.From, edge.To}, qualifier: qualifier}
+				groups[groupKey] = group
+				lines = append(lines, group)
+			} else {
+				// Un groupe non représentable reste une relation "contient"
+				lines = append(lines, &serializedLine{kind: "arrow", items: []string{edge.From, edge.To}, labels: []string{edge.Label}, qualifier: qualifier})
+			}
+			current = nil
+
+		case "role":
+			member := edge.Label + ": " + edge.To
+			eventKey := "role\x00" + edge.From + "\x00" + qualifier
+			representable := s.safeItem(edge.To) && roleMemberRegex.MatchString(member)
+			if event, ok := groups[eventKey]; ok && representable {
+				event.items = append(event.items, member)
+				continue
+			}
+			if s.safeItem(edge.From) && representable {
+				event := &serializedLine{kind: "event", items: []string{edge.From, member}, qualifier: qualifier}
+				groups[eventKey] = event
+				lines = append(lines, event)
+			} else {
+				// Un rôle non représentable reste une relation
+				lines = append(lines, &serializedLine{kind: "arrow", items: []string{edge.From, edge.To}, labels: []string{edge.Label}, qualifier: qualifier})
+			}
+			current = nil
+
+		default:
+			if !s.safeItem(edge.From) || !s.safeItem(edge.To) || !s.safeLabel(edge.Label) {
+				lines = append(lines, &serializedLine{kind: "arrow", items: []string{edge.From, edge.To}, labels: []string{edge.Label}, qualifier: qualifier})
+				current = nil
+				continue
+			}
+
+			switch {
+			case current != nil && current.qualifier == qualifier && current.items[len(current.items)-1] == edge.From:
+				// Prolonger la chaîne "A (r1) B (r2) C"
+				current.items = append(current.items, edge.To)
+				current.labels = append(current.labels, edge.Label)
+			case current != nil && current.items[0] == edge.From:
+				// Même sujet que la ligne précédente : continuation '"'
+				current = &serializedLine{kind: "relation", items: []string{edge.From, edge.To}, labels: []string{edge.Label}, ditto: true, qualifier: qualifier}
+				lines = append(lines, current)
+			default:
+				current = &serializedLine{kind: "relation", items: []string{edge.From, edge.To}, labels: []string{edge.Label}, qualifier: qualifier}
+				lines = append(lines, current)
+			}
+		}
+	}
+	return lines
+}
+
+// formatLine écrit une ligne dans sa syntaxe N4L, suivie de ses qualificatifs
+func (s *N4LSerializer) formatLine(line *serializedLine) string {
+	if line.qualifier != "" {
+		return s.formatStatement(line) + " " + line.qualifier
+	}
+	return s.formatStatement(line)
+}
+
+// formatStatement écrit l'instruction d'une ligne
+func (s *N4LSerializer) formatStatement(line *serializedLine) string {
+	switch line.kind {
+	case "equivalence":
+		return line.items[0] + " <-> " + line.items[1]
+	case "group", "event":
+		return line.items[0] + " => { " + strings.Join(line.items[1:], "; ") + " }"
+	case "arrow":
+		return line.items[0] + " -> " + line.labels[0] + " -> " + line.items[1]
+	}
+
+	var b strings.Builder
+	if line.ditto {
+		b.WriteString(`"`)
+	} else {
+		b.WriteString(line.items[0])
+	}
+	for i, label := range line.labels {
+		b.WriteString(" (" + label + ") " + line.items[i+1])
+	}
+	return b.String()
+}
+
+// safeItem indique si un nom peut être écrit tel quel dans une relation
+// à parenthèses ou une liste de groupe sans être relu autrement
+func (s *N4LSerializer) safeItem(name string) bool {
+	if name != strings.TrimSpace(name) || strings.ContainsAny(name, `(){};"`) {
+		return false
+	}
+	for _, marker := range []string{"->", "<->", "=>", ">\"", "$", "@", "#", "::"} {
+		if strings.Contains(name, marker) {
+			return false
+		}
+	}
+	return true
+}
+
+// safeMember indique si un nom peut être membre d'un groupe sans être relu
+// comme le participant "rôle: nom" d'un événement
+func (s *N4LSerializer) safeMember(name string) bool {
+	return s.safeItem(name) && !roleMemberRegex.MatchString(name)
+}
+
+// safeLabel indique si un libellé peut être écrit entre parenthèses
+func (s *N4LSerializer) safeLabel(label string) bool {
+	return label != "" && label != "=" && s.safeItem(label)
+}
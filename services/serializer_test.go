@@ -0,0 +1,138 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"n4l-editor/models"
+)
+
+// canonicalGraph trie nœuds et arêtes pour comparer deux graphes
+// indépendamment de l'ordre de production
+func canonicalGraph(g models.GraphData) models.GraphData {
+	nodes := append([]models.Node(nil), g.Nodes...)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	edges := append([]models.Edge(nil), g.Edges...)
+	key := func(e models.Edge) string {
+		return strings.Join([]string{e.Context, e.From, e.Label, e.To, e.Type}, "\x00")
+	}
+	sort.SliceStable(edges, func(i, j int) bool { return key(edges[i]) < key(edges[j]) })
+
+	return models.GraphData{Nodes: nodes, Edges: edges}
+}
+
+func roundTrip(t *testing.T, g models.GraphData) models.GraphData {
+	t.Helper()
+	parser := NewN4LParser()
+	text := NewN4LSerializer().Serialize(g)
+	parsed := parser.ParseN4L(text)
+	if HasErrors(parsed.Diagnostics) {
+		t.Fatalf("le texte sérialisé produit des erreurs %v :\n%s", parsed.Diagnostics, text)
+	}
+	return parser.ParseN4LToGraph(parsed.Notes)
+}
+
+func TestSerializeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"relation simple", "Alice -> connaît -> Bob"},
+		{"chaîne", "Départ (puis) Porte (puis) Sortie"},
+		{"continuation", "Alice (connaît) Bob\n\" (aime) Carl\n\" (craint) Dave"},
+		{"cibles multiples", "Alice -> connaît -> { Bob; Carl }"},
+		{"équivalence", "IA <-> Intelligence artificielle\nML (=) Apprentissage"},
+		{"groupe", "Preuves => { Couteau; Empreinte; Lettre }"},
+		{"contextes", ":: Preuves | Chronologie ::\nA -> r -> B\n::: Témoins :::\nB -> vu -> C\n:: Autre ::\nC -> r -> D"},
+		{"mélange", ":: enquête ::\nSuspect (était à) Gare\nGare => { Quai; Hall }\n\" (proche de) Hôtel\nHôtel <-> Palace\nQuai (mène à) Voie 3 (mène à) Train"},
+		{"arêtes dupliquées", "A -> r -> B\nA -> r -> B"},
+		{"négations", "A (!eq) B\nA (!connaît) C\nB -> !eq -> C"},
+		{"qualificatifs", "Marie (a vu) Paul [certitude=0.4; source=rumeur, village]\nCouteau (porte) Empreinte (appartient à) Paul [certitude=certain; poids=3]\nPreuves => { A; B } [source=labo]\nA <-> B [certitude=probable]"},
+		{"propriétés", "Victor Moreau (âge) 67 ans\n\" (connaît) Marie\nMarie -> date -> 12/03/2024\nMarie (profession) notaire"},
+		{"événements", "Remise => { agent: Jean; objet: lettre; date: 27/08/2025 } [source=Élodie]\nRéunion => { lieu: manoir; qui: Jean }"},
+	}
+
+	parser := NewN4LParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := parser.ParseN4LToGraph(parser.ParseN4L(tt.source).Notes)
+			if len(original.Edges) == 0 {
+				t.Fatalf("aucune arête pour %q", tt.source)
+			}
+
+			got := canonicalGraph(roundTrip(t, original))
+			want := canonicalGraph(original)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("aller-retour différent\nobtenu : %+v\nattendu : %+v\ntexte :\n%s",
+					got, want, NewN4LSerializer().Serialize(original))
+			}
+		})
+	}
+}
+
+func TestSerializeUnsafeNames(t *testing.T) {
+	// Des noms contenant des parenthèses ne peuvent pas être écrits en
+	// syntaxe à parenthèses : la forme fléchée doit être utilisée
+	graph := models.GraphData{
+		Edges: []models.Edge{
+			{From: "Paris (France)", To: "Lyon", Label: "route", Type: "relation", Context: "general"},
+			{From: "X", To: "Y", Label: "=", Type: "relation", Context: "general"},
+		},
+	}
+	graph.Nodes = []models.Node{
+		{ID: "Lyon", Label: "Lyon", Context: "general", Contexts: []string{"general"}},
+		{ID: "Paris (France)", Label: "Paris (France)", Context: "general", Contexts: []string{"general"}},
+		{ID: "X", Label: "X", Context: "general", Contexts: []string{"general"}},
+		{ID: "Y", Label: "Y", Context: "general", Contexts: []string{"general"}},
+	}
+	for i := range graph.Edges {
+		graph.Edges[i].Contexts = []string{"general"}
+	}
+	graph.Edges = AssignEdgeIDs(graph.Edges) // identifiants attribués par le parser
+
+	got := canonicalGraph(roundTrip(t, graph))
+	want := canonicalGraph(graph)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("aller-retour différent\nobtenu : %+v\nattendu : %+v", got, want)
+	}
+}
+
+func TestSerializeCanonicalText(t *testing.T) {
+	parser := NewN4LParser()
+	graph := parser.ParseN4LToGraph(parser.ParseN4L("A -> r1 -> B\nB -> r2 -> C\nA -> r3 -> D\nG => { x; y }").Notes)
+
+	want := strings.Join([]string{
+		":: general ::",
+		"",
+		"    A (r1) B (r2) C",
+		`    " (r3) D`,
+		"    G => { x; y }",
+		"",
+	}, "\n")
+	if got := NewN4LSerializer().Serialize(graph); got != want {
+		t.Errorf("texte inattendu :\n%s\nattendu :\n%s", got, want)
+	}
+
+	// Le texte canonique est stable : le resérialiser ne le modifie pas
+	again := NewN4LSerializer().Serialize(parser.ParseN4LToGraph(parser.ParseN4L(want).Notes))
+	if again != want {
+		t.Errorf("sérialisation instable :\n%s", again)
+	}
+}
+
+func ExampleN4LSerializer_Serialize() {
+	graph := models.GraphData{Edges: []models.Edge{
+		{From: "Alice", To: "Bob", Label: "connaît", Type: "relation", Context: "enquête"},
+		{From: "Alice", To: "Gare", Label: "était à", Type: "relation", Context: "enquête"},
+	}}
+	fmt.Print(NewN4LSerializer().Serialize(graph))
+	// Output:
+	// :: enquête ::
+	//
+	//     Alice (connaît) Bob
+	//     " (était à) Gare
+}
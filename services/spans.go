@@ -0,0 +1,78 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"n4l-editor/models"
+)
+
+// MergeSpans ajoute à une liste de spans ceux qu'elle ne contient pas encore
+func MergeSpans(spans, extra []models.SourceSpan) []models.SourceSpan {
+	for _, span := range extra {
+		found := false
+		for _, existing := range spans {
+			if existing.File == span.File && existing.StartLine == span.StartLine && existing.EndLine == span.EndLine {
+				found = true
+				break
+			}
+		}
+		if !found {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// GraphSpans retourne les lignes source à l'origine d'un ensemble de nœuds :
+// celles des arêtes qui les relient entre eux, ou à défaut celles qui les
+// mentionnent. Le résultat est trié par fichier et par ligne.
+func GraphSpans(graph models.GraphData, nodeIDs []string) []models.SourceSpan {
+	if len(nodeIDs) == 0 {
+		return nil
+	}
+	wanted := make(map[string]bool, len(nodeIDs))
+	for _, id := range nodeIDs {
+		wanted[id] = true
+	}
+
+	var spans []models.SourceSpan
+	if len(nodeIDs) > 1 {
+		for _, edge := range graph.Edges {
+			if wanted[edge.From] && wanted[edge.To] {
+				spans = MergeSpans(spans, edge.Spans)
+			}
+		}
+	}
+	if len(spans) == 0 {
+		for _, node := range graph.Nodes {
+			if wanted[node.ID] {
+				spans = MergeSpans(spans, node.Spans)
+			}
+		}
+	}
+
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].File != spans[j].File {
+			return spans[i].File < spans[j].File
+		}
+		return spans[i].StartLine < spans[j].StartLine
+	})
+	return spans
+}
+
+// noteSpanIndex associe le texte de chaque note aux lignes qui l'ont produite
+func noteSpanIndex(notes map[string][]string, spans models.NoteSpans) map[string][]models.SourceSpan {
+	index := make(map[string][]models.SourceSpan)
+	for context, list := range notes {
+		contextSpans := spans[context]
+		if len(contextSpans) != len(list) {
+			continue
+		}
+		for i, note := range list {
+			key := strings.TrimSpace(note)
+			index[key] = MergeSpans(index[key], contextSpans[i:i+1])
+		}
+	}
+	return index
+}
@@ -170,8 +170,14 @@ class N4LApp {
 
     async handleUpload(e, type) {
         console.log(`LOG: Handling upload for type: ${type}`);
-        const file = e.target.files[0];
+        let file = e.target.files[0];
         if (!file) return;
+
+        this.state.projectFiles = null;
+        this.state.projectRoot = null;
+        if (type === 'n4l' && e.target.files.length > 1) {
+            file = await this.loadProject(Array.from(e.target.files));
+        }
         
         this.state.fileName = file.name.replace(/\.[^/.]+$/, "");
         document.getElementById('file-name').textContent = file.name;
@@ -210,6 +216,33 @@ class N4LApp {
         }
     }
 
+    /**
+     * Charge les fichiers d'un projet multi-fichiers. Le fichier principal,
+     * qu'aucun autre n'inclut, est ouvert dans l'éditeur ; les autres sont
+     * envoyés avec lui à /api/parse-project.
+     * @param {File[]} files - Fichiers .n4l sélectionnés.
+     * @returns {File} Le fichier principal.
+     */
+    async loadProject(files) {
+        const contents = {};
+        for (const f of files) {
+            contents[f.name] = await f.text();
+        }
+
+        const included = new Set();
+        Object.values(contents).forEach(content => {
+            for (const match of content.matchAll(/^\s*@include\s+["']?([^"'\n]+?)["']?\s*$/gm)) {
+                included.add(match[1].split('/').pop());
+            }
+        });
+        const root = files.find(f => !included.has(f.name)) || files[0];
+
+        delete contents[root.name];
+        this.state.projectFiles = contents;
+        this.state.projectRoot = root.name;
+        return root;
+    }
+
     switchTab(tabName) {
         console.log(`LOG: Switching to tab: ${tabName}`);
         ['n4l', 'graph', 'timeline'].forEach(t => {
@@ -525,7 +558,7 @@ class N4LApp {
             const response = await fetch('/api/detect-temporal-patterns', {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify(this.state.n4lNotes)
+                body: JSON.stringify({ notes: this.state.n4lNotes, spans: this.state.n4lSpans })
             });
             
             if (!response.ok) throw new Error(await response.text());
@@ -565,6 +598,7 @@ class N4LApp {
         patterns.forEach(pattern => {
             htmlContent += '<div class="border rounded-lg p-3 bg-orange-50 border-orange-400">';
             htmlContent += `<div class="font-semibold text-orange-800 mb-2">Pattern: ${pattern.pattern}</div>`;
+            htmlContent += this.utils.spanLinks(pattern.spans);
             
             if (pattern.occurrences && pattern.occurrences.length > 0) {
                 htmlContent += '<div class="mb-2"><div class="text-sm font-medium text-gray-700 mb-1">Occurrences trouvées:</div>';
@@ -721,6 +755,7 @@ class N4LApp {
             html += `<div class="border-l-4 border-${color}-400 bg-${color}-50 p-3 mb-2 rounded">`;
             html += `<div class="text-xs font-semibold text-gray-600 mb-1">${this.getInconsistencyTypeLabel(item.type)}</div>`;
             html += `<div class="text-sm text-gray-800 mb-2">${item.description}</div>`;
+            html += this.utils.spanLinks(item.spans);
             
             if (item.nodes && item.nodes.length > 0) {
                 html += '<div class="flex flex-wrap gap-1 mb-2">';
@@ -747,8 +782,14 @@ class N4LApp {
             'temporal_cycle': 'Boucle temporelle',
             'contradictory_relations': 'Relations contradictoires',
             'inconsistent_equivalence': 'équivalence incohérente',
+            'negated_equivalence': 'Équivalence niée',
+            'negated_relation': 'Relation niée',
             'orphan_node': 'Noeud isolé',
-            'disconnected_group': 'Groupe déconnecté'
+            'disconnected_group': 'Groupe déconnecté',
+            'schema_type': 'Type non permis',
+            'schema_cardinality': 'Cardinalité dépassée',
+            'schema_required': 'Propriété requise absente',
+            'schema_unknown_type': 'Type inconnu'
         };
         return labels[type] || type;
     }
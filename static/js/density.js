@@ -321,7 +321,7 @@ export class DensityManager {
             }
             
             // Charger la carte de densité
-            const densityResponse = await fetch('/api/density-map', {
+            const densityResponse = await fetch(`/api/density-map${this.app.graph.contextQuery()}`, {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
                 body: JSON.stringify(graphDataWithPositions)
@@ -334,7 +334,7 @@ export class DensityManager {
             }
             
             // Charger les territoires
-            const territoriesResponse = await fetch('/api/conceptual-territories', {
+            const territoriesResponse = await fetch(`/api/conceptual-territories${this.app.graph.contextQuery()}`, {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
                 body: JSON.stringify(graphDataWithPositions)
@@ -346,7 +346,7 @@ export class DensityManager {
             }
             
             // Charger les métriques
-            const metricsResponse = await fetch('/api/density-metrics', {
+            const metricsResponse = await fetch(`/api/density-metrics${this.app.graph.contextQuery()}`, {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
                 body: JSON.stringify(graphDataWithPositions)
@@ -975,7 +975,7 @@ createDensityGrid() {
                 };
             }
 
-            const response = await fetch('/api/exploration-suggestions', {
+            const response = await fetch(`/api/exploration-suggestions${this.app.graph.contextQuery()}`, {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
                 body: JSON.stringify(graphDataWithPositions)
@@ -1206,7 +1206,7 @@ createDensityGrid() {
                 };
             }
 
-            const response = await fetch('/api/exploration-suggestions', {
+            const response = await fetch(`/api/exploration-suggestions${this.app.graph.contextQuery()}`, {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
                 body: JSON.stringify(graphDataWithPositions)
@@ -6,6 +6,7 @@ export class EditorManager {
         this.n4lEditor = null;
         this.isUpdatingFromSync = false;
         this.syncTimeout = null;
+        this.diagnosticMarks = [];
     }
 
     init() {
@@ -48,11 +49,18 @@ export class EditorManager {
     async syncToState(n4lContent) {
         console.log("LOG: Syncing editor content to application state...");
         try {
-            const response = await fetch('/api/parse-n4l', {
-                method: 'POST',
-                headers: { 'Content-Type': 'text/plain' },
-                body: n4lContent
-            });
+            const { projectFiles, projectRoot } = this.app.state;
+            const response = projectFiles
+                ? await fetch('/api/parse-project', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ root: projectRoot, files: { ...projectFiles, [projectRoot]: n4lContent } })
+                })
+                : await fetch('/api/parse-n4l', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'text/plain' },
+                    body: n4lContent
+                });
             
             if (!response.ok) throw new Error(await response.text());
             
@@ -61,6 +69,9 @@ export class EditorManager {
             // Mettre à jour l'état de l'application
             this.app.state.subjects = data.subjects || [];
             this.app.state.n4lNotes = data.notes || {};
+            this.app.state.n4lSpans = data.spans || {};
+            this.app.state.diagnostics = data.diagnostics || [];
+            this.renderDiagnostics(this.app.state.diagnostics);
             
             // Rafraîchir les autres vues
             this.app.renderSubjects();
@@ -73,6 +84,34 @@ export class EditorManager {
         }
     }
 
+    /**
+     * Souligne dans l'éditeur les lignes signalées par le parser.
+     * @param {Array} diagnostics - Diagnostics renvoyés par /api/parse-n4l.
+     */
+    renderDiagnostics(diagnostics) {
+        this.diagnosticMarks.forEach(mark => mark.clear());
+        this.diagnosticMarks = [];
+
+        const doc = this.n4lEditor.getDoc();
+        diagnostics
+            .filter(d => d.severity === 'error' || d.severity === 'warning')
+            .filter(d => !d.file || d.file === this.app.state.projectRoot)
+            .forEach(d => {
+                const line = d.pos.line - 1;
+                if (line < 0 || line >= doc.lineCount()) return;
+
+                const from = { line, ch: Math.max(d.pos.column - 1, 0) };
+                const to = d.length > 0
+                    ? { line, ch: from.ch + d.length }
+                    : { line, ch: doc.getLine(line).length };
+
+                this.diagnosticMarks.push(doc.markText(from, to, {
+                    className: `n4l-diagnostic-${d.severity}`,
+                    title: `${d.code} : ${d.message}`
+                }));
+            });
+    }
+
     renderStateToEditor() {
         let output = '';
         const sortedContexts = Object.keys(this.app.state.n4lNotes).sort();
@@ -135,8 +174,24 @@ export class EditorManager {
         this.updateContent(newContent + '\n');
     }
 
+    /**
+     * Chemins de contexte présents dans le graphe (sous-contextes inclus).
+     * @returns {Object} Nombre de nœuds par chemin de contexte.
+     */
+    countNodesByContext() {
+        const counts = {};
+        if (this.app.state.allGraphData && this.app.state.allGraphData.nodes) {
+            this.app.state.allGraphData.nodes.forEach(node => {
+                (node.contexts || [node.context || 'general']).forEach(ctx => {
+                    counts[ctx] = (counts[ctx] || 0) + 1;
+                });
+            });
+        }
+        return counts;
+    }
+
     updateContextFilter() {
-        const contexts = new Set(Object.keys(this.app.state.n4lNotes));
+        const contexts = new Set(Object.keys(this.countNodesByContext()).sort());
         const contextFilter = document.getElementById('context-filter');
         
         if (contextFilter) {
@@ -156,21 +211,14 @@ export class EditorManager {
         
         panel.innerHTML = '';
         
-        // Calculer le nombre de nœuds par contexte
-        const nodeCountByContext = {};
-        let totalNodes = 0;
-        
-        if (this.app.state.allGraphData && this.app.state.allGraphData.nodes) {
-            this.app.state.allGraphData.nodes.forEach(node => {
-                const ctx = node.context || 'general';
-                nodeCountByContext[ctx] = (nodeCountByContext[ctx] || 0) + 1;
-                totalNodes++;
-            });
-        }
+        // Calculer le nombre de nœuds par contexte (un nœud compte dans chacun de ses contextes)
+        const nodeCountByContext = this.countNodesByContext();
+        const totalNodes = this.app.state.allGraphData && this.app.state.allGraphData.nodes
+            ? this.app.state.allGraphData.nodes.length
+            : 0;
         
         // Filtrer les contextes qui ont des nœuds
-        const contextsWithNodes = Object.keys(this.app.state.n4lNotes)
-            .filter(ctx => nodeCountByContext[ctx] > 0);
+        const contextsWithNodes = Object.keys(nodeCountByContext).sort();
         
         // Ajouter le bouton "Tous" seulement s'il y a des nœuds
         if (totalNodes > 0) {
@@ -252,20 +300,33 @@ export class EditorManager {
         }
     }
 
+    /**
+     * Place le curseur sur une ligne du fichier N4L (à partir de 1).
+     * @param {number} line - Numéro de la ligne.
+     */
+    goToLine(line) {
+        this.app.switchTab('n4l');
+        const target = { line: line - 1, ch: 0 };
+        this.n4lEditor.setCursor(target);
+        this.n4lEditor.scrollIntoView(target, 100);
+        this.n4lEditor.focus();
+    }
+
     /**
      * Fait défiler l'éditeur jusqu'à un contexte spécifique.
      * @param {string} contextName - Le nom du contexte à rechercher.
      */
     scrollToContext(contextName) {
-        // Crée une expression régulière pour trouver ":: contexte ::" ou "::: contexte :::"
-        // en début de ligne, insensible aux espaces.
-        const query = new RegExp(`^:::\\s*${contextName}\\s*:::$|^::\\s*${contextName}\\s*::`);
+        // Un chemin "parent > sous-contexte" désigne l'en-tête du dernier niveau,
+        // qui peut lister plusieurs contextes ("::: a | b :::").
+        const name = contextName.split('>').pop().trim();
+        const header = /^:{2,}\s*(.*?)\s*:{2,}$/;
         const doc = this.n4lEditor.getDoc();
 
         // Parcourt chaque ligne du document pour trouver une correspondance
         for (let i = 0; i < doc.lineCount(); i++) {
-            const line = doc.getLine(i);
-            if (query.test(line.trim())) {
+            const match = header.exec(doc.getLine(i).trim());
+            if (match && match[1].split('|').some(n => n.trim() === name || n.trim() === contextName)) {
                 // Positionne le curseur au début de la ligne trouvée
                 this.n4lEditor.setCursor({ line: i, ch: 0 });
                 // Fait défiler la vue pour que la ligne soit visible
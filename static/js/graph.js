@@ -5,6 +5,7 @@ export class GraphManager {
         this.app = app;
         this.graph = null;
         this.currentViewMode = 'standard';
+        this.mergeAliases = false;
     }
 
     init() {
@@ -48,6 +49,10 @@ export class GraphManager {
                     </button>
                     <span class="tooltip-text">Laisse l'IA analyser le graphe pour suggérer des questions d'investigation pertinentes.</span>
                 </div>
+                <div class="tooltip-container">
+                    <button id="merge-aliases-btn" class="bg-gray-500 text-white px-2 py-1 rounded-md text-xs">Alias</button>
+                    <span class="tooltip-text">Fusionne les éléments déclarés équivalents (<->, (=), (alias)) en un seul nœud.</span>
+                </div>
             </div>
                     <div class="mt-2">
                         <label class="font-semibold text-xs">Visualisation</label>
@@ -173,6 +178,7 @@ export class GraphManager {
             'analyze-reset': () => this.resetHighlight(),
             'ai-analyze-btn': () => this.analyzeWithAI(),
             'generate-questions-btn': () => this.generateQuestions(),
+            'merge-aliases-btn': () => this.toggleMergeAliases(),
             'discover-paths-btn': () => this.discoverAllPaths(),
             'cluster-search-btn': () => this.findAndHighlightClusters(),
             'cluster-analyze-btn': () => this.analyzeClustersWithAI(),
@@ -211,10 +217,11 @@ export class GraphManager {
     async update() {
         console.log("LOG: Updating graph...");
         try {
-            const response = await fetch('/api/graph-data', {
+            const query = this.mergeAliases ? '?merge=aliases' : '';
+            const response = await fetch(`/api/graph-data${query}`, {
                 method: 'POST',
                 headers: {'Content-Type': 'application/json'},
-                body: JSON.stringify(this.app.state.n4lNotes)
+                body: JSON.stringify({ notes: this.app.state.n4lNotes, spans: this.app.state.n4lSpans })
             });
             
             if (!response.ok) throw new Error(await response.text());
@@ -227,14 +234,20 @@ export class GraphManager {
 
             const nodes = new vis.DataSet(validNodes.map(n => ({
                 ...n,
+                ...(n.kind === 'event' ? { shape: 'ellipse' } : {}),
+                title: this.nodeTitle(n),
                 color: this.getNodeColor(n)
             })));
             
             const edges = new vis.DataSet(validEdges.map((e, index) => ({
                 ...e,
-                id: `edge-${index}`, // ID unique basé sur l'index
+                id: this.edgeId(e, index),
                 color: this.getEdgeColor(e.type),
-                arrows: e.type === 'equivalence' ? 'to, from' : 'to'
+                arrows: e.type === 'equivalence' || e.type === 'non_equivalence' ? 'to, from' : 'to',
+                // Les hypothèses (certitude < 0.5) sont en pointillés, les liens lourds plus épais
+                dashes: e.type === 'negation' || e.type === 'non_equivalence' || (e.confidence > 0 && e.confidence < 0.5),
+                width: e.weight > 0 ? Math.min(1 + e.weight, 6) : 1,
+                title: this.edgeTitle(e)
             })));
 
             const options = this.getGraphOptions();
@@ -253,6 +266,8 @@ export class GraphManager {
                 
                 // Gestion du clic droit
                 this.graph.on("oncontext", (params) => this.handleGraphRightClick(params));
+                // Double-clic : aller à la ligne source de l'élément
+                this.graph.on("doubleClick", (params) => this.goToSource(params));
             }
         } catch (error) {
             console.error('LOG: Erreur graphe:', error);
@@ -305,6 +320,13 @@ export class GraphManager {
     }
 
     getNodeColor(node) {
+        if (node && node.kind === 'event') {
+            return {
+                border: '#0ea5e9',
+                background: '#e0f2fe',
+                highlight: { border: '#ef4444', background: '#fecaca' }
+            };
+        }
         return {
             border: '#4f46e5',
             background: 'white',
@@ -323,6 +345,13 @@ export class GraphManager {
                 return { color: '#22c55e', highlight: '#15803d' };
             case 'group':
                 return { color: '#a855f7', highlight: '#7e22ce' };
+            case 'sequence':
+                return { color: '#f59e0b', highlight: '#b45309' };
+            case 'role':
+                return { color: '#0ea5e9', highlight: '#0369a1' };
+            case 'negation':
+            case 'non_equivalence':
+                return { color: '#ef4444', highlight: '#b91c1c' };
             default:
                 return { color: '#6b7280', highlight: '#374151' };
         }
@@ -339,26 +368,32 @@ export class GraphManager {
         const menu = document.getElementById('node-context-menu');
         menu.innerHTML = ''; // Vider le menu
     
-        // --- Option 1: Cône d'expansion ---
-        const coneOption = document.createElement('a');
-        coneOption.textContent = "Explorer le cône d'expansion";
-        coneOption.onclick = async () => {
-            menu.classList.add('hidden');
-            const result = await this.app.utils.showModal({
-                title: "Profondeur d'exploration",
-                text: "Entrez la profondeur du cône d'expansion :",
-                prompt: true,
-                inputType: 'number',
-                inputValue: 2
-            });
-            if (result && result.text) {
-                const depth = parseInt(result.text, 10);
-                if (depth > 0) {
-                    this.showExpansionCone(nodeId, depth);
+        // --- Option 1: Cônes d'expansion (tous sens, conséquences, causes) ---
+        [
+            ['both', "Explorer le cône d'expansion"],
+            ['outgoing', "Ce que ce nœud entraîne"],
+            ['incoming', "Ce qui mène à ce nœud"]
+        ].forEach(([direction, label]) => {
+            const coneOption = document.createElement('a');
+            coneOption.textContent = label;
+            coneOption.onclick = async () => {
+                menu.classList.add('hidden');
+                const result = await this.app.utils.showModal({
+                    title: "Profondeur d'exploration",
+                    text: "Entrez la profondeur du cône d'expansion :",
+                    prompt: true,
+                    inputType: 'number',
+                    inputValue: 2
+                });
+                if (result && result.text) {
+                    const depth = parseInt(result.text, 10);
+                    if (depth > 0) {
+                        this.showExpansionCone(nodeId, depth, direction);
+                    }
                 }
-            }
-        };
-        menu.appendChild(coneOption);
+            };
+            menu.appendChild(coneOption);
+        });
 
         // Dans handleGraphRightClick, modifiez l'option d'analyse :
         const analyzeOption = document.createElement('a');
@@ -417,6 +452,65 @@ export class GraphManager {
     }
     
 
+    async toggleMergeAliases() {
+        this.mergeAliases = !this.mergeAliases;
+        const btn = document.getElementById('merge-aliases-btn');
+        if (btn) {
+            btn.classList.toggle('bg-gray-500', !this.mergeAliases);
+            btn.classList.toggle('bg-indigo-500', this.mergeAliases);
+        }
+        await this.update();
+    }
+
+    /**
+     * Infobulle d'un nœud : alias fusionnés et attributs typés.
+     * @param {Object} node - Nœud renvoyé par /api/graph-data.
+     * @returns {string|undefined}
+     */
+    nodeTitle(node) {
+        const lines = [];
+        if (node.aliases) lines.push(`Alias : ${node.aliases.join(', ')}`);
+        (node.properties || []).forEach(p => lines.push(`${p.name} : ${p.value}`));
+        return lines.length > 0 ? lines.join('\n') : undefined;
+    }
+
+    /**
+     * Identifiant d'une arête : celui, stable, attribué par le parser, ou à
+     * défaut sa position dans la liste.
+     */
+    edgeId(edge, index) {
+        return edge.id || `edge-${index}`;
+    }
+
+    /**
+     * Infobulle d'une arête : certitude et source de l'information.
+     * @param {Object} edge - Arête renvoyée par /api/graph-data.
+     * @returns {string|undefined}
+     */
+    edgeTitle(edge) {
+        const lines = [];
+        if (edge.confidence > 0) lines.push(`Certitude : ${Math.round(edge.confidence * 100)} %`);
+        if (edge.source) lines.push(`Source : ${edge.source}`);
+        if (edge.weight > 0) lines.push(`Poids : ${edge.weight}`);
+        return lines.length > 0 ? lines.join('\n') : undefined;
+    }
+
+    goToSource(params) {
+        let spans = null;
+        if (params.nodes.length > 0) {
+            const node = this.app.state.allGraphData.nodes.find(n => n.id === params.nodes[0]);
+            spans = node && node.spans;
+        } else if (params.edges.length > 0) {
+            const edge = this.graph.body.data.edges.get(params.edges[0]);
+            spans = edge && edge.spans;
+        }
+        const root = this.app.state.projectRoot;
+        const span = spans && spans.find(s => !s.file || s.file === root);
+        if (span) {
+            this.app.editor.goToLine(span.startLine);
+        }
+    }
+
     reclassifyNode(nodeLabel, newContext) {
         let noteFoundAndMoved = false;
         
@@ -468,6 +562,16 @@ export class GraphManager {
         }
     }
 
+    /**
+     * Paramètre de requête restreignant les vues calculées côté serveur
+     * au contexte sélectionné dans le filtre.
+     */
+    contextQuery() {
+        const contextFilter = document.getElementById('context-filter');
+        const context = contextFilter ? contextFilter.value : '';
+        return context ? `?context=${encodeURIComponent(context)}` : '';
+    }
+
     filterByContext(context) {
         if (!this.graph) return;
         
@@ -483,17 +587,18 @@ export class GraphManager {
             return;
         }
 
-        const filteredNodes = this.app.state.allGraphData.nodes.filter(n => n.context === context);
+        const filteredNodes = this.app.state.allGraphData.nodes.filter(n => (n.contexts || [n.context]).includes(context));
         const filteredNodeIds = new Set(filteredNodes.map(n => n.id));
         const filteredEdges = this.app.state.allGraphData.edges.filter(e => 
-            filteredNodeIds.has(e.from) && filteredNodeIds.has(e.to)
+            filteredNodeIds.has(e.from) && filteredNodeIds.has(e.to) &&
+            (e.contexts || [e.context]).includes(context)
         );
 
         this.graph.setData({
             nodes: new vis.DataSet(filteredNodes.map(n => ({...n, color: this.getNodeColor(n)}))),
             edges: new vis.DataSet(filteredEdges.map((e, index) => ({
                 ...e,
-                id: `edge-filtered-${index}`,
+                id: this.edgeId(e, index),
                 color: this.getEdgeColor(e.type),
                 arrows: e.type === 'equivalence' ? 'to, from' : 'to'
             })))
@@ -545,7 +650,7 @@ export class GraphManager {
         
         const edges = new vis.DataSet(this.app.state.allGraphData.edges.map((e, index) => ({
             ...e,
-            id: `edge-${index}`,
+            id: this.edgeId(e, index),
             color: this.getEdgeColor(e.type),
             arrows: e.type === 'equivalence' ? 'to, from' : 'to'
         })));
@@ -737,6 +842,7 @@ export class GraphManager {
             if (q.hint) {
                 html += `<div class="text-xs text-gray-500 italic mb-2">💡 ${q.hint}</div>`;
             }
+            html += this.app.utils.spanLinks(q.spans);
             
             if (q.nodes && q.nodes.length > 0) {
                 html += '<div class="flex flex-wrap gap-1 mb-2">';
@@ -773,15 +879,7 @@ export class GraphManager {
         btn.disabled = true;
         
         try {
-            const response = await fetch('/api/find-all-paths', {
-                method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify(this.app.state.n4lNotes)
-            });
-            
-            if (!response.ok) throw new Error(await response.text());
-            
-            const paths = await response.json();
+            const paths = await this.findStoryPaths();
             window.discoveredPaths = paths;
             this.renderDiscoveredPaths(paths);
             
@@ -797,6 +895,60 @@ export class GraphManager {
         }
     }
 
+    // Cherche les histoires indirectes entre les deux nœuds sélectionnés ou,
+    // à défaut, entre les nœuds les plus connectés : quelques paires et
+    // quelques chemins par paire, pour que la recherche reste bornée
+    async findStoryPaths() {
+        const { nodes, edges } = this.app.state.allGraphData;
+        let endpoints = this.graph?.getSelectedNodes() || [];
+        if (endpoints.length < 2) {
+            const degree = new Map(nodes.map(n => [n.id, 0]));
+            edges.forEach(e => {
+                degree.set(e.from, (degree.get(e.from) || 0) + 1);
+                degree.set(e.to, (degree.get(e.to) || 0) + 1);
+            });
+            endpoints = [...degree.entries()]
+                .filter(([, d]) => d > 0)
+                .sort((a, b) => b[1] - a[1])
+                .slice(0, 4)
+                .map(([id]) => id);
+        } else {
+            endpoints = endpoints.slice(0, 2);
+        }
+
+        const pairs = [];
+        for (let i = 0; i < endpoints.length; i++) {
+            for (let j = i + 1; j < endpoints.length; j++) {
+                pairs.push([endpoints[i], endpoints[j]]);
+            }
+        }
+
+        const seen = new Set();
+        const paths = [];
+        for (const [from, to] of pairs) {
+            // Une paire en échec (nœud inconnu, réseau...) n'empêche pas les autres
+            try {
+                const response = await fetch('/api/find-paths', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ from, to, k: 3, maxLength: 6, graphData: this.app.state.allGraphData })
+                });
+                if (!response.ok) throw new Error(await response.text());
+
+                (await response.json() || []).forEach(path => {
+                    const key = path.nodes.join('\u0000');
+                    if (path.nodes.length > 2 && !seen.has(key)) {
+                        seen.add(key);
+                        paths.push(path.nodes);
+                    }
+                });
+            } catch (error) {
+                console.warn(`Chemins ${from} → ${to} ignorés :`, error);
+            }
+        }
+        return paths;
+    }
+
     renderDiscoveredPaths(paths) {
         const container = document.getElementById('paths-list-container');
         if (!container) return;
@@ -901,7 +1053,7 @@ export class GraphManager {
             );
             return {
                 ...e,
-                id: `edge-${index}`,
+                id: this.edgeId(e, index),
                 color: isHighlighted ? '#dc2626' : this.getEdgeColor(e.type).color,
                 width: isHighlighted ? 3 : 1,
                 arrows: e.type === 'equivalence' ? 'to, from' : 'to'
@@ -963,7 +1115,7 @@ export class GraphManager {
         
         const edges = new vis.DataSet(this.app.state.allGraphData.edges.map((e, index) => ({
             ...e,
-            id: `edge-${index}`,
+            id: this.edgeId(e, index),
             color: this.getEdgeColor(e.type),
             arrows: e.type === 'equivalence' ? 'to, from' : 'to'
         })));
@@ -980,7 +1132,7 @@ export class GraphManager {
         }
         
         try {
-            const response = await fetch('/api/layered-graph', {
+            const response = await fetch(`/api/layered-graph${this.contextQuery()}`, {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
                 body: JSON.stringify(this.app.state.allGraphData)
@@ -1021,7 +1173,7 @@ export class GraphManager {
         
         const edges = new vis.DataSet(layeredData.edges.map((e, index) => ({
             ...e,
-            id: `edge-${index}`,
+            id: this.edgeId(e, index),
             color: { color: '#999', highlight: '#333' },
             smooth: { type: 'continuous', roundness: 0.5 },
             arrows: e.type === 'equivalence' ? 'to, from' : 'to'
@@ -1059,7 +1211,7 @@ export class GraphManager {
         
         const edges = new vis.DataSet(this.app.state.allGraphData.edges.map((e, index) => ({
             ...e,
-            id: `edge-${index}`,
+            id: this.edgeId(e, index),
             color: this.getEdgeColor(e.type),
             smooth: { type: 'curvedCW', roundness: 0.2 },
             arrows: e.type === 'equivalence' ? 'to, from' : 'to'
@@ -1088,7 +1240,7 @@ export class GraphManager {
         
         const edges = new vis.DataSet(this.app.state.allGraphData.edges.map((e, index) => ({
             ...e,
-            id: `edge-${index}`,
+            id: this.edgeId(e, index),
             color: this.getEdgeColor(e.type),
             arrows: e.type === 'equivalence' ? 'to, from' : 'to'
         })));
@@ -1152,7 +1304,7 @@ export class GraphManager {
         return levels;
     }
 
-    async showExpansionCone(nodeId, depth) {
+    async showExpansionCone(nodeId, depth, direction = 'both') {
         try {
             const response = await fetch('/api/graph/expansion-cone', {
                 method: 'POST',
@@ -1160,6 +1312,7 @@ export class GraphManager {
                 body: JSON.stringify({
                     nodeId: nodeId,
                     depth: depth,
+                    direction: direction,
                     graphData: this.app.state.allGraphData
                 })
             });
@@ -1172,18 +1325,8 @@ export class GraphManager {
             const coneNodeIds = new Set(cone.nodeIds);
             
             const allEdges = this.app.state.allGraphData.edges;
-            const coneEdgeIdSet = new Set();
-
-            // Create a simple key for each edge in the cone for easy lookup
-            const coneEdgeKeys = new Set(cone.edges.map(e => `${e.from}|${e.to}|${e.label}`));
-
-            // Find the unique ID for each edge that is part of the cone
-            allEdges.forEach((edge, index) => {
-                const edgeKey = `${edge.from}|${edge.to}|${edge.label}`;
-                if (coneEdgeKeys.has(edgeKey)) {
-                    coneEdgeIdSet.add(`edge-${index}`);
-                }
-            });
+            // Les arêtes du cône gardent l'identifiant attribué par le parser
+            const coneEdgeIdSet = new Set(cone.edges.map(e => e.id));
     
             const nodes = new vis.DataSet(this.app.state.allGraphData.nodes.map(n => ({
                 ...n,
@@ -1195,7 +1338,7 @@ export class GraphManager {
             })));
     
             const edges = new vis.DataSet(allEdges.map((e, index) => {
-                const edgeId = `edge-${index}`;
+                const edgeId = this.edgeId(e, index);
                 const originalColor = this.getEdgeColor(e.type);
                 return {
                     ...e,
@@ -1425,7 +1568,7 @@ export class GraphManager {
                     width = 2.5;
                 }
 
-                return { ...e, id: `edge-${index}`, color: edgeColor, width };
+                return { ...e, id: this.edgeId(e, index), color: edgeColor, width };
             }));
 
             this.graph.setData({ nodes, edges });
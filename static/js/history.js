@@ -458,7 +458,7 @@ export class HistoryManager {
                 await this.app.graph.update();
                 
                 // Reconstruire l'éditeur N4L
-                this.reconstructN4LFromGraph(restoredVersion.graphData);
+                await this.reconstructN4LFromGraph(restoredVersion.graphData);
                 
                 await this.app.utils.showModal({
                     title: 'Version Restaurée',
@@ -678,50 +678,25 @@ export class HistoryManager {
         return nodesDiff >= 3 || edgesDiff >= 5;
     }
 
-    reconstructN4LFromGraph(graphData) {
-        // Reconstruire le contenu N4L à partir du graphe
-        let n4lContent = '';
-        const contextGroups = {};
-        
-        // Grouper par contexte
-        graphData.edges.forEach(edge => {
-            const context = edge.context || 'general';
-            if (!contextGroups[context]) {
-                contextGroups[context] = [];
-            }
-            
-            let note = '';
-            switch(edge.type) {
-                case 'relation':
-                    note = `${edge.from} -> ${edge.label} -> ${edge.to}`;
-                    break;
-                case 'equivalence':
-                    note = `${edge.from} <-> ${edge.to}`;
-                    break;
-                case 'group':
-                    // Gérer les groupes (simplification)
-                    note = `${edge.from} -> contient -> ${edge.to}`;
-                    break;
-            }
-            
-            if (note && !contextGroups[context].includes(note)) {
-                contextGroups[context].push(note);
-            }
-        });
-        
-        // Construire le contenu N4L
-        for (const context in contextGroups) {
-            if (contextGroups[context].length > 0) {
-                n4lContent += `:: ${context} ::\n\n`;
-                contextGroups[context].forEach(note => {
-                    n4lContent += `    ${note}\n`;
-                });
-                n4lContent += '\n';
-            }
+    async reconstructN4LFromGraph(graphData) {
+        // Reconstruire le contenu N4L canonique à partir du graphe
+        try {
+            const response = await fetch('/api/graph-to-n4l', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(graphData)
+            });
+
+            if (!response.ok) throw new Error(await response.text());
+
+            const n4lContent = await response.text();
+
+            // Mettre à jour l'éditeur puis l'état à partir du texte reconstruit
+            this.app.editor.updateContent(n4lContent);
+            await this.app.editor.syncToState(n4lContent);
+        } catch (error) {
+            console.error('Erreur reconstruction N4L:', error);
         }
-        
-        // Mettre à jour l'éditeur
-        this.app.editor.updateContent(n4lContent);
     }
 
     showHistoryPanel() {
@@ -992,17 +967,7 @@ export class HistoryManager {
 
     async discoverPaths() {
         try {
-            const response = await fetch('/api/find-all-paths', {  // Route correcte
-                method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify(this.app.state.n4lNotes)  // Utiliser n4lNotes comme dans graph.js
-            });
-
-            if (!response.ok) {
-                throw new Error(`HTTP error! status: ${response.status}`);
-            }
-
-            const paths = await response.json();
+            const paths = await this.app.graph.findStoryPaths();
             this.discoveredPaths = paths;
             this.displayPaths(paths);
         } catch (error) {
@@ -1282,7 +1247,7 @@ export class HistoryManager {
                 );
                 return {
                     ...e,
-                    id: `edge-${index}`,
+                    id: e.id || `edge-${index}`,
                     color: isHighlighted ? '#dc2626' : (e.color || '#94a3b8'),
                     width: isHighlighted ? 3 : 1,
                     arrows: e.type === 'equivalence' ? 'to, from' : 'to'
@@ -5,6 +5,8 @@ export class State {
         this.concepts = [];
         this.subjects = [];
         this.n4lNotes = {};
+        this.n4lSpans = {}; // lignes source de chaque note, en parallèle de n4lNotes
+        this.diagnostics = [];
         this.allGraphData = { nodes: [], edges: [] };
         this.currentContext = 'general';
         this.currentAction = null;
@@ -12,6 +14,8 @@ export class State {
         this.selectedDest = null;
         this.selectedChildren = [];
         this.fileName = 'notes';
+        this.projectFiles = null; // autres fichiers d'un projet multi-fichiers, par chemin
+        this.projectRoot = null;  // fichier principal du projet, édité dans l'éditeur
         this.investigationMode = false;
         this.currentViewMode = 'standard';
     }
@@ -119,6 +123,24 @@ export class Utils {
         });
     }
 
+    /**
+     * Liens "ligne N" vers les lignes source d'un résultat d'analyse.
+     * @param {Array} spans - Spans renvoyés par le serveur.
+     */
+    spanLinks(spans) {
+        if (!spans || spans.length === 0) return '';
+        const root = window.app && window.app.state.projectRoot;
+        const links = spans.map(span => {
+            const label = span.endLine > span.startLine ? `lignes ${span.startLine}-${span.endLine}` : `ligne ${span.startLine}`;
+            if (span.file && span.file !== root) {
+                // Ligne d'un fichier inclus : non éditable ici
+                return `${span.file} ${label}`;
+            }
+            return `<span class="text-blue-600 underline cursor-pointer" onclick="window.app.utils.closeModal(null); window.app.editor.goToLine(${span.startLine})">${label}</span>`;
+        });
+        return `<div class="text-xs text-gray-500 mb-1">📍 ${links.join(', ')}</div>`;
+    }
+
     closeModal(value) {
         const dom = new DOM();
         dom.modal.classList.add('hidden');